@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -22,3 +23,19 @@ func loadDotEnv() error {
 
 	return fmt.Errorf("load .env: %w", err)
 }
+
+// drainDelayFromEnv returns SHUTDOWN_DRAIN_DELAY: how long the server keeps
+// serving with /readyz failing before it stops accepting connections, so
+// load balancers can take it out of rotation first. It defaults to 5s.
+func drainDelayFromEnv() (time.Duration, error) {
+	v := os.Getenv("SHUTDOWN_DRAIN_DELAY")
+	if v == "" {
+		return 5 * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse SHUTDOWN_DRAIN_DELAY: %w", err)
+	}
+	return d, nil
+}
@@ -5,19 +5,36 @@ import (
 
 	"go-chi-observability/internal/calculator"
 	"go-chi-observability/internal/observability"
+	"go-chi-observability/internal/server"
 )
 
-// initMetrics initialises all metric providers and application-specific
-// metric instruments. Add new domain InitMetrics calls here as the project grows.
+// modules lists the API domains. Add new domains here: the server mounts each
+// under /<Name> and runs its metrics, health check and shutdown hooks.
+var modules = []server.Module{
+	calculator.Module{},
+}
+
+// initMetrics initialises all metric providers and every module's metric
+// instruments.
 func initMetrics(ctx context.Context) (func(context.Context) error, error) {
 	shutdown, err := observability.InitMetrics(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := calculator.InitMetrics(); err != nil {
+	if err := server.InitModuleMetrics(modules); err != nil {
 		return nil, err
 	}
 
 	return shutdown, nil
 }
+
+// registerHealthChecks adds the checks behind /livez and /readyz: shared
+// infrastructure first, then each module's own.
+func registerHealthChecks() {
+	// A collector outage degrades the service but must not take it out of
+	// rotation, so telemetry is non-critical.
+	observability.HealthChecks.Register(observability.TelemetryHealthCheck())
+
+	server.RegisterModuleHealthChecks(observability.HealthChecks, modules)
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,6 +13,8 @@ import (
 
 	"go-chi-observability/internal/observability"
 	"go-chi-observability/internal/server"
+
+	"go.uber.org/zap"
 )
 
 func main() {
@@ -17,65 +22,103 @@ func main() {
 		panic(err)
 	}
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Logger
-	err := observability.InitLogger()
+	ln, err := net.Listen("tcp", ":8080")
 	if err != nil {
 		panic(err)
 	}
+
+	if err := run(ctx, ln); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run initialises observability, serves the API on ln until ctx is done,
+// then shuts down gracefully and flushes telemetry before returning.
+func run(ctx context.Context, ln net.Listener) error {
+	// Exporters must still flush after ctx is cancelled.
+	initCtx := context.WithoutCancel(ctx)
+
+	drainDelay, err := drainDelayFromEnv()
+	if err != nil {
+		return err
+	}
+
+	// Logger
+	err = observability.InitLogger()
+	if err != nil {
+		return err
+	}
 	defer observability.SyncLogger()
 
+	// Export buffer: deferred here so it closes after every exporter below
+	defer observability.CloseWAL()
+
 	// Tracing
-	traceShutdown, err := observability.InitTracing(ctx)
+	traceShutdown, err := observability.InitTracing(initCtx)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer traceShutdown(ctx)
+	defer traceShutdown(initCtx)
 
 	// Logging (OTel bridge — must be after Logger and Tracing init)
-	logShutdown, err := observability.InitLogging(ctx)
+	logShutdown, err := observability.InitLogging(initCtx)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer logShutdown(ctx)
+	defer logShutdown(initCtx)
 
 	// Metrics
-	metricShutdown, err := initMetrics(ctx)
+	metricShutdown, err := initMetrics(initCtx)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer metricShutdown(ctx)
+	defer metricShutdown(initCtx)
+
+	// Health checks
+	registerHealthChecks()
 
 	// Router
-	router := server.NewRouter()
+	router := server.NewRouter(modules...)
 
 	srv := &http.Server{
-		Addr:    ":8080",
 		Handler: router,
 	}
 
+	serveErr := make(chan error, 1)
 	go func() {
-		observability.Logger.Info("server started")
+		observability.LoggerWithTrace(ctx).Info("server started", zap.String("addr", ln.Addr().String()))
 
-		if err := srv.ListenAndServe(); err != nil {
-			panic(err)
-		}
+		serveErr <- srv.Serve(ln)
 	}()
 
-	waitForShutdown(srv)
-}
-
-func waitForShutdown(srv *http.Server) {
-
-	stop := make(chan os.Signal, 1)
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
 
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	shutdown(srv, drainDelay)
+	return nil
+}
 
-	<-stop
+func shutdown(srv *http.Server, drainDelay time.Duration) {
+	// Fail /readyz first, and keep serving until load balancers have seen
+	// it and stopped sending new requests.
+	observability.HealthChecks.SetShuttingDown()
+	time.Sleep(drainDelay)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	srv.Shutdown(ctx)
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		observability.Logger.Error("server shutdown failed", zap.Error(err))
+	}
+
+	if err := server.ShutdownModules(ctx, modules); err != nil {
+		observability.Logger.Error("module shutdown failed", zap.Error(err))
+	}
 }
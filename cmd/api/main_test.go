@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/observability"
+	"go-chi-observability/internal/testutil"
+	"go-chi-observability/internal/testutil/otlptest"
+)
+
+// apiStarted records whether run has been called in this process. Tracers
+// and meters obtained from the otel globals (e.g. calculator's package-level
+// tracer) bind to the first provider installed, so a second run would export
+// nothing for them.
+var apiStarted bool
+
+// debugSecret signs the X-Debug-Log tokens of the test requests.
+const debugSecret = "e2e-secret"
+
+// startAPI runs the service against collector and returns its base URL and a
+// function that shuts it down, flushing all telemetry.
+func startAPI(t *testing.T, collector *otlptest.Collector) (string, func()) {
+	t.Helper()
+
+	if apiStarted {
+		t.Skip("run can only be exercised once per test process")
+	}
+	apiStarted = true
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+	t.Setenv("OTEL_SERVICE_NAME", "e2e-api")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=test")
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "0s")
+	t.Setenv("DEBUG_LOG_SECRET", debugSecret)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	baseURL := "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, ln) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(baseURL + "/livez")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("API did not become live: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	stop := func() {
+		t.Helper()
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("API did not shut down")
+		}
+	}
+	return baseURL, stop
+}
+
+func TestAPIExportsCorrelatedTelemetry(t *testing.T) {
+	collector := otlptest.NewCollector(t)
+	baseURL, stop := startAPI(t, collector)
+
+	resp, err := http.Post(baseURL+"/calculator/add", "application/json", bytes.NewReader([]byte(`{"a":2,"b":3}`)))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	testutil.CheckResponseCode(t, http.StatusOK, resp.StatusCode)
+
+	// Only the request with a valid debug token logs at debug level.
+	for path, token := range map[string]string{
+		"/calculator/multiply": observability.SignDebugToken([]byte(debugSecret), time.Now().Add(time.Minute)),
+		"/calculator/subtract": observability.SignDebugToken([]byte("wrong"), time.Now().Add(time.Minute)),
+		"/calculator/divide":   "",
+	} {
+		req, _ := http.NewRequest(http.MethodPost, baseURL+path, bytes.NewReader([]byte(`{"a":6,"b":3}`)))
+		if token != "" {
+			req.Header.Set(observability.DebugLogHeader, token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.CheckResponseCode(t, http.StatusOK, resp.StatusCode)
+	}
+
+	stop()
+
+	multiply := collector.SpansNamed("calculator.multiply")
+	if len(multiply) != 1 {
+		t.Fatalf("expected 1 calculator.multiply span, got %d", len(multiply))
+	}
+	for _, body := range []string{"calculator request decoded", "calculator operation computed"} {
+		logs := collector.LogsWithBody(body)
+		if len(logs) != 1 || !bytes.Equal(logs[0].TraceId, multiply[0].TraceId) {
+			t.Errorf("expected one %q debug log, on the multiply trace, got %d", body, len(logs))
+		}
+	}
+
+	spans := collector.SpansNamed("calculator.add")
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 calculator.add span, got %d", len(spans))
+	}
+	span := spans[0]
+	for key, want := range map[string]string{
+		"service.name":           "e2e-api",
+		"deployment.environment": "test",
+	} {
+		if got, _ := otlptest.ResourceAttribute(span.Resource, key); got != want {
+			t.Errorf("resource %s: expected %q, got %q", key, want, got)
+		}
+	}
+	if got, _ := otlptest.Attribute(span.Attributes, "calculator.result"); got != "5" {
+		t.Errorf("expected calculator.result 5, got %q", got)
+	}
+
+	var logs []otlptest.LogRecord
+	for _, l := range collector.LogsWithBody("calculator operation completed") {
+		if got, _ := otlptest.Attribute(l.Attributes, "operation"); got == "add" {
+			logs = append(logs, l)
+		}
+	}
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 completion log record for add, got %d", len(logs))
+	}
+	if !bytes.Equal(logs[0].TraceId, span.TraceId) || !bytes.Equal(logs[0].SpanId, span.ParentSpanId) {
+		t.Fatalf("log record not correlated: trace=%x span=%x, want trace=%x span=%x",
+			logs[0].TraceId, logs[0].SpanId, span.TraceId, span.ParentSpanId)
+	}
+	if got, _ := otlptest.ResourceAttribute(logs[0].Resource, "service.name"); got != "e2e-api" {
+		t.Errorf("log resource service.name: expected %q, got %q", "e2e-api", got)
+	}
+
+	metrics := collector.MetricsNamed("calculator.operations.total")
+	if len(metrics) == 0 {
+		t.Fatal("expected calculator.operations.total to be exported")
+	}
+	points := metrics[len(metrics)-1].GetSum().GetDataPoints()
+	if len(points) != 4 {
+		t.Fatalf("expected a data point per operation, got %v", points)
+	}
+	for _, p := range points {
+		if got, _ := otlptest.Attribute(p.Attributes, "operation"); got == "add" && p.GetAsInt() != 1 {
+			t.Fatalf("expected operation=add to count 1, got %v", p)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// identPattern restricts domain and operation names to what can be used
+// unchanged as a package name, route segment and metric attribute value.
+var identPattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// reservedNames would shadow packages imported by cmd/api/init.go or the
+// generated files.
+var reservedNames = []string{
+	"context", "errors", "fmt", "http", "json", "time", "testing",
+	"chi", "otel", "zap", "metric", "trace", "codes", "attribute",
+	"observability", "server", "handlers", "testutil",
+}
+
+// reservedOps would collide with the identifiers every domain declares.
+var reservedOps = []string{"module", "initmetrics", "registerroutes"}
+
+type domain struct {
+	Name   string
+	Module string // Go module path, filled in by generate
+	Ops    []operation
+}
+
+type operation struct {
+	Name string // lower case: route segment, span suffix, metric attribute
+	Go   string // exported Go identifier
+}
+
+func newDomain(name string, ops []string) (domain, error) {
+	if !identPattern.MatchString(name) {
+		return domain{}, fmt.Errorf("domain %q: must be lower case letters and digits, starting with a letter", name)
+	}
+	if token.IsKeyword(name) || slices.Contains(reservedNames, name) {
+		return domain{}, fmt.Errorf("domain %q: reserved name", name)
+	}
+
+	d := domain{Name: name}
+	seen := make(map[string]bool)
+	for _, op := range ops {
+		op = strings.TrimSpace(op)
+		if !identPattern.MatchString(op) {
+			return domain{}, fmt.Errorf("operation %q: must be lower case letters and digits, starting with a letter", op)
+		}
+		if slices.Contains(reservedOps, op) {
+			return domain{}, fmt.Errorf("operation %q: reserved name", op)
+		}
+		if seen[op] {
+			return domain{}, fmt.Errorf("operation %q: listed twice", op)
+		}
+		seen[op] = true
+		d.Ops = append(d.Ops, operation{Name: op, Go: strings.ToUpper(op[:1]) + op[1:]})
+	}
+	if len(d.Ops) == 0 {
+		return domain{}, errors.New("at least one operation is required")
+	}
+	return d, nil
+}
+
+// domainFiles maps each generated file name to its template.
+var domainFiles = []struct {
+	name string
+	tmpl *template.Template
+}{
+	{"types.go", typesTemplate},
+	{"metrics.go", metricsTemplate},
+	{"handlers.go", handlersTemplate},
+	{"routes.go", routesTemplate},
+	{"module.go", moduleTemplate},
+	{"handlers_test.go", handlersTestTemplate},
+}
+
+// generate writes the domain package under root/internal and registers it in
+// root/cmd/api/init.go. It returns the paths written.
+func generate(root string, d domain) ([]string, error) {
+	module, err := modulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	d.Module = module
+
+	dir := filepath.Join(root, "internal", d.Name)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("%s already exists", dir)
+	}
+
+	rendered, err := render(d)
+	if err != nil {
+		return nil, err
+	}
+
+	initPath := filepath.Join(root, "cmd", "api", "init.go")
+	initSrc, err := os.ReadFile(initPath)
+	if err != nil {
+		return nil, err
+	}
+	initSrc, err = registerModule(initSrc, d)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", initPath, err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, f := range domainFiles {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, rendered[f.name], 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+
+	if err := os.WriteFile(initPath, initSrc, 0o644); err != nil {
+		return written, err
+	}
+	return append(written, initPath), nil
+}
+
+// render executes every template and gofmts the result.
+func render(d domain) (map[string][]byte, error) {
+	out := make(map[string][]byte, len(domainFiles))
+	for _, f := range domainFiles {
+		var buf bytes.Buffer
+		if err := f.tmpl.Execute(&buf, d); err != nil {
+			return nil, fmt.Errorf("render %s: %w", f.name, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("format %s: %w", f.name, err)
+		}
+		out[f.name] = src
+	}
+	return out, nil
+}
+
+// registerModule adds the domain's import and Module value to init.go.
+func registerModule(src []byte, d domain) ([]byte, error) {
+	s := string(src)
+	importLine := fmt.Sprintf("\t%q\n", d.Module+"/internal/"+d.Name)
+	entry := fmt.Sprintf("\t%s.Module{},\n", d.Name)
+
+	if strings.Contains(s, entry) {
+		return nil, fmt.Errorf("%s is already registered", d.Name)
+	}
+
+	const modulesStart = "var modules = []server.Module{\n"
+	start := strings.Index(s, modulesStart)
+	if start < 0 {
+		return nil, errors.New("modules list not found")
+	}
+	end := strings.Index(s[start:], "\n}\n")
+	if end < 0 {
+		return nil, errors.New("end of modules list not found")
+	}
+	end += start + 1
+	s = s[:end] + entry + s[end:]
+
+	// Insert next to the other internal imports; gofmt sorts the group.
+	internal := fmt.Sprintf("\t%q", d.Module+"/internal/")
+	at := strings.Index(s, internal[:len(internal)-1])
+	if at < 0 {
+		return nil, errors.New("internal import group not found")
+	}
+	s = s[:at] + importLine + s[at:]
+
+	return format.Source([]byte(s))
+}
+
+// modulePath reads the module path from go.mod.
+func modulePath(gomod string) (string, error) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(path), `"`), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: module directive not found", gomod)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testInitGo = `package main
+
+import (
+	"context"
+
+	"example.com/app/internal/calculator"
+	"example.com/app/internal/server"
+)
+
+var modules = []server.Module{
+	calculator.Module{},
+}
+
+func initMetrics(ctx context.Context) {}
+`
+
+func TestNewDomainValidatesNames(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []string
+	}{
+		{"Inventory", []string{"create"}},
+		{"inv_items", []string{"create"}},
+		{"func", []string{"create"}},
+		{"observability", []string{"create"}},
+		{"inventory", nil},
+		{"inventory", []string{"create", "create"}},
+		{"inventory", []string{"module"}},
+		{"inventory", []string{"bulk-create"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := newDomain(tt.name, tt.ops); err == nil {
+			t.Errorf("newDomain(%q, %q): expected error", tt.name, tt.ops)
+		}
+	}
+
+	d, err := newDomain("inventory", []string{"create", " list"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Ops[1].Name != "list" || d.Ops[1].Go != "List" {
+		t.Fatalf("unexpected operation %+v", d.Ops[1])
+	}
+}
+
+func TestGenerateWritesPackageAndRegistersModule(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go.mod"), "module example.com/app\n\ngo 1.25\n")
+	mustWrite(t, filepath.Join(root, "cmd", "api", "init.go"), testInitGo)
+
+	d, err := newDomain("inventory", []string{"create", "list"})
+	if err != nil {
+		t.Fatalf("newDomain: %v", err)
+	}
+
+	files, err := generate(root, d)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(files) != len(domainFiles)+1 {
+		t.Fatalf("expected %d files, got %v", len(domainFiles)+1, files)
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, filepath.Join(root, "internal", "inventory"), nil, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	if _, ok := pkgs["inventory"]; !ok {
+		t.Fatalf("expected package inventory, got %v", pkgs)
+	}
+
+	handlers := mustRead(t, filepath.Join(root, "internal", "inventory", "handlers.go"))
+	for _, want := range []string{
+		`"example.com/app/internal/observability"`,
+		`tracer.Start(ctx, "inventory.create"`,
+		`func List(w http.ResponseWriter, r *http.Request)`,
+	} {
+		if !strings.Contains(handlers, want) {
+			t.Errorf("handlers.go: expected %s", want)
+		}
+	}
+
+	initSrc := mustRead(t, filepath.Join(root, "cmd", "api", "init.go"))
+	if !strings.Contains(initSrc, "\t\"example.com/app/internal/inventory\"\n") ||
+		!strings.Contains(initSrc, "\tcalculator.Module{},\n\tinventory.Module{},\n") {
+		t.Fatalf("init.go not updated:\n%s", initSrc)
+	}
+
+	if _, err := generate(root, d); err == nil {
+		t.Fatal("expected error when the domain already exists")
+	}
+}
+
+func TestRegisterModuleRejectsDuplicates(t *testing.T) {
+	d, _ := newDomain("calculator", []string{"add"})
+	d.Module = "example.com/app"
+
+	if _, err := registerModule([]byte(testInitGo), d); err == nil {
+		t.Fatal("expected error for an already registered module")
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustRead(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
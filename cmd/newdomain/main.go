@@ -0,0 +1,45 @@
+// Command newdomain scaffolds a domain package following docs/api-structure.md
+// and registers it in cmd/api/init.go.
+//
+// Usage:
+//
+//	go run ./cmd/newdomain -ops create,get,list inventory
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	ops := flag.String("ops", "", "comma-separated operation names, each served at POST /<domain>/<op>")
+	root := flag.String("root", ".", "repository root (the directory containing go.mod)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: newdomain -ops op1,op2 [-root dir] <domain>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *ops == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	d, err := newDomain(flag.Arg(0), strings.Split(*ops, ","))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "newdomain:", err)
+		os.Exit(2)
+	}
+
+	files, err := generate(*root, d)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "newdomain:", err)
+		os.Exit(1)
+	}
+
+	for _, f := range files {
+		fmt.Println(f)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import "text/template"
+
+// The templates mirror internal/calculator, the reference domain. Output is
+// passed through gofmt, so spacing here need not be exact.
+
+var typesTemplate = template.Must(template.New("types.go").Parse(`package {{.Name}}
+{{range .Ops}}
+// {{.Go}}Request is the JSON body for POST /{{$.Name}}/{{.Name}}.
+type {{.Go}}Request struct {
+	// TODO: add request fields.
+}
+
+// {{.Go}}Response is the JSON response for POST /{{$.Name}}/{{.Name}}.
+type {{.Go}}Response struct {
+	Operation string ` + "`json:\"operation\"`" + `
+}
+{{end}}`))
+
+var metricsTemplate = template.Must(template.New("metrics.go").Parse(`package {{.Name}}
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metric instruments — initialized once via InitMetrics().
+var (
+	opsCounter   metric.Int64Counter
+	opsHistogram metric.Float64Histogram
+	errorCounter metric.Int64Counter
+)
+
+// InitMetrics registers custom OTel metric instruments for the {{.Name}} domain.
+// Call this once at startup (after observability.InitMetrics).
+func InitMetrics() error {
+	meter := otel.Meter("{{.Name}}")
+
+	var err error
+
+	opsCounter, err = meter.Int64Counter("{{.Name}}.operations.total",
+		metric.WithDescription("Total number of {{.Name}} operations performed"),
+		metric.WithUnit("{operation}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating ops counter: %w", err)
+	}
+
+	opsHistogram, err = meter.Float64Histogram("{{.Name}}.operation.duration",
+		metric.WithDescription("Duration of {{.Name}} operations in milliseconds"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating ops histogram: %w", err)
+	}
+
+	errorCounter, err = meter.Int64Counter("{{.Name}}.errors.total",
+		metric.WithDescription("Total number of {{.Name}} errors"),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating error counter: %w", err)
+	}
+
+	return nil
+}
+`))
+
+var handlersTemplate = template.Must(template.New("handlers.go").Parse(`package {{.Name}}
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"{{.Module}}/internal/observability"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracer is the {{.Name}} domain's dedicated OpenTelemetry tracer.
+var tracer = otel.Tracer("{{.Name}}")
+{{range .Ops}}
+// {{.Go}} handles POST /{{$.Name}}/{{.Name}}
+func {{.Go}}(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := observability.LoggerWithTrace(ctx)
+	requestID := observability.RequestIDFromContext(ctx)
+
+	ctx, span := tracer.Start(ctx, "{{$.Name}}.{{.Name}}",
+		trace.WithAttributes(
+			attribute.String("{{$.Name}}.operation", "{{.Name}}"),
+			attribute.String("request.id", requestID),
+		),
+	)
+	defer span.End()
+
+	var req {{.Go}}Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "{{.Name}}", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+
+	start := time.Now()
+	resp, err := perform{{.Go}}(ctx, req)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0 // ms
+
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "{{.Name}}", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	attrs := metric.WithAttributes(attribute.String("operation", "{{.Name}}"))
+	opsCounter.Add(ctx, 1, attrs)
+	opsHistogram.Record(ctx, elapsed, attrs)
+
+	span.SetStatus(codes.Ok, "")
+
+	logger.Info("{{$.Name}} operation completed",
+		zap.String("operation", "{{.Name}}"),
+		zap.String("request_id", requestID),
+		zap.Float64("duration_ms", elapsed),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// perform{{.Go}} holds the business logic for {{.Name}}. Start child spans
+// from ctx for any significant sub-steps.
+func perform{{.Go}}(ctx context.Context, req {{.Go}}Request) ({{.Go}}Response, error) {
+	// TODO: implement {{.Name}}.
+	return {{.Go}}Response{Operation: "{{.Name}}"}, nil
+}
+{{end}}`))
+
+var routesTemplate = template.Must(template.New("routes.go").Parse(`package {{.Name}}
+
+import "github.com/go-chi/chi/v5"
+
+// RegisterRoutes mounts all {{.Name}} endpoints onto the given router. The
+// server mounts it under the /{{.Name}} prefix (see Module).
+func RegisterRoutes(r chi.Router) {
+{{- range .Ops}}
+	r.Post("/{{.Name}}", {{.Go}})
+{{- end}}
+}
+`))
+
+var moduleTemplate = template.Must(template.New("module.go").Parse(`package {{.Name}}
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+
+	"{{.Module}}/internal/observability"
+)
+
+// Module plugs the {{.Name}} domain into the server (see server.Module).
+type Module struct{}
+
+func (Module) Name() string { return "{{.Name}}" }
+
+func (Module) InitMetrics() error { return InitMetrics() }
+
+func (Module) RegisterRoutes(r chi.Router) { RegisterRoutes(r) }
+
+// HealthChecks returns the checks for the domain's dependencies.
+func (Module) HealthChecks() []observability.HealthCheck { return nil }
+
+func (Module) Shutdown(context.Context) error { return nil }
+`))
+
+var handlersTestTemplate = template.Must(template.New("handlers_test.go").Parse(`package {{.Name}}
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"{{.Module}}/internal/observability"
+	"{{.Module}}/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var setupTestsOnce sync.Once
+
+func setupTests(t *testing.T) {
+	t.Helper()
+
+	setupTestsOnce.Do(func() {
+		observability.Logger = zap.NewNop()
+		if err := InitMetrics(); err != nil {
+			t.Fatalf("initializing {{.Name}} metrics: %v", err)
+		}
+	})
+}
+
+func newTestRouter() http.Handler {
+	r := chi.NewRouter()
+	RegisterRoutes(r)
+	return r
+}
+{{range .Ops}}
+func Test{{.Go}}ReturnsOperation(t *testing.T) {
+	setupTests(t)
+	tel := testutil.NewTelemetry(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/{{.Name}}", strings.NewReader(` + "`{}`" + `))
+	w := testutil.ExecuteRequest(req, newTestRouter())
+
+	testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+	var resp {{.Go}}Response
+	testutil.DecodeJSONBody(t, w.Body, &resp)
+	if resp.Operation != "{{.Name}}" {
+		t.Fatalf("expected operation %q, got %q", "{{.Name}}", resp.Operation)
+	}
+
+	tel.AssertSpan("{{$.Name}}.{{.Name}}", nil, codes.Ok)
+	tel.AssertCounter("{{$.Name}}.operations.total", []attribute.KeyValue{attribute.String("operation", "{{.Name}}")}, 1)
+}
+
+func Test{{.Go}}RejectsInvalidBody(t *testing.T) {
+	setupTests(t)
+	tel := testutil.NewTelemetry(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/{{.Name}}", strings.NewReader(` + "`{`" + `))
+	w := testutil.ExecuteRequest(req, newTestRouter())
+
+	testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+	tel.AssertSpan("{{$.Name}}.{{.Name}}", nil, codes.Error)
+	tel.AssertCounter("{{$.Name}}.errors.total", []attribute.KeyValue{attribute.String("operation", "{{.Name}}")}, 1)
+}
+{{end}}`))
@@ -0,0 +1,24 @@
+// Command obslint runs the project's telemetry and dependency analyzers.
+//
+// Usage:
+//
+//	go run ./cmd/obslint ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+
+	"go-chi-observability/internal/lint/importrules"
+	"go-chi-observability/internal/lint/spanend"
+	"go-chi-observability/internal/lint/telemetrynames"
+	"go-chi-observability/internal/lint/tracelogger"
+)
+
+func main() {
+	multichecker.Main(
+		spanend.Analyzer,
+		tracelogger.Analyzer,
+		telemetrynames.Analyzer,
+		importrules.Analyzer,
+	)
+}
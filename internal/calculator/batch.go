@@ -0,0 +1,203 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go-chi-observability/internal/observability"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxBatchItems bounds the items in one batch request.
+	maxBatchItems = 1000
+	// maxBatchBodyBytes bounds the size of a batch request body.
+	maxBatchBodyBytes = 1 << 20
+	// maxBatchChainSteps bounds the steps of a chain item.
+	maxBatchChainSteps = 100
+	// batchWorkers is the number of items of one batch that run at once.
+	batchWorkers = 8
+)
+
+// Batch item outcomes, the "outcome" attribute of calculator.batch.items.total.
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+	outcomeCanceled  = "canceled"
+)
+
+// validate checks the batch as a whole; problems with single items are
+// reported in their results.
+func (req BatchRequest) validate() error {
+	switch {
+	case len(req.Items) == 0:
+		return errors.New("no items provided")
+	case len(req.Items) > maxBatchItems:
+		return fmt.Errorf("batch has %d items, the limit is %d", len(req.Items), maxBatchItems)
+	}
+	return errors.Join(req.Angle.validate(), req.Policy.validate())
+}
+
+// runBatch runs every item on a pool of batchWorkers goroutines and returns
+// the results in item order. Items still waiting when ctx is canceled are
+// not started; their results carry the cancellation error.
+func runBatch(ctx context.Context, n int, run func(ctx context.Context, i int) BatchResult) []BatchResult {
+	results := make([]BatchResult, n)
+
+	jobs := make(chan batchJob)
+	var wg sync.WaitGroup
+	for range min(batchWorkers, n) {
+		wg.Go(func() {
+			for job := range jobs {
+				i := job.index
+				batchQueueWait.Record(ctx, float64(time.Since(job.queued).Microseconds())/1000.0)
+				if err := ctx.Err(); err != nil {
+					results[i] = canceledResult(ctx, i, err)
+					continue
+				}
+				results[i] = run(ctx, i)
+			}
+		})
+	}
+
+enqueue:
+	for i := range n {
+		select {
+		case jobs <- batchJob{index: i, queued: time.Now()}:
+		case <-ctx.Done():
+			for j := i; j < n; j++ {
+				results[j] = canceledResult(ctx, j, ctx.Err())
+			}
+			break enqueue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// batchJob is an item handed to a worker, with the time it was queued.
+type batchJob struct {
+	index  int
+	queued time.Time
+}
+
+func canceledResult(ctx context.Context, i int, err error) BatchResult {
+	batchItems.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcomeCanceled)))
+	return BatchResult{Index: i, Error: fmt.Sprintf("item %d not run: %v", i, err)}
+}
+
+// runBatchItem runs item i of req on a calculator.batch.item span, a child of
+// the batch span in ctx that also links to it.
+func runBatchItem(ctx context.Context, req BatchRequest, i int) BatchResult {
+	item := req.Items[i]
+	operation := item.Op
+	if item.Chain != nil {
+		operation = "chain"
+	}
+
+	ctx, span := tracer.Start(ctx, "calculator.batch.item",
+		trace.WithLinks(trace.LinkFromContext(ctx, attribute.String("link.type", "batch"))),
+		trace.WithAttributes(
+			attribute.Int("batch.item.index", i),
+			attribute.String("calculator.operation", operation),
+			attribute.String("request.id", observability.RequestIDFromContext(ctx)),
+		),
+	)
+	defer span.End()
+	logger := observability.LoggerWithTrace(ctx)
+
+	res := BatchResult{Index: i}
+	var result float64
+	var err error
+	switch {
+	case (item.Op == "") == (item.Chain == nil):
+		err = errors.New("item needs exactly one of op and chain")
+	case item.Chain != nil:
+		chain := *item.Chain
+		if chain.Angle == "" {
+			chain.Angle = req.Angle
+		}
+		if chain.Policy == "" {
+			chain.Policy = req.Policy
+		}
+		if len(chain.Steps) > maxBatchChainSteps {
+			err = fmt.Errorf("chain has %d steps, the limit is %d", len(chain.Steps), maxBatchChainSteps)
+			break
+		}
+		if err = chain.validate(); err != nil {
+			break
+		}
+		// runChain records, counts and logs its own failures.
+		resp, err := runChain(ctx, logger, chain, nil)
+		if err != nil {
+			res.Error, res.ErrorKind = err.Error(), errorKind(err)
+			batchItems.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcomeFailed)))
+			return res
+		}
+		result, res.Steps = float64(resp.Result), resp.Steps
+	default:
+		result, err = runBatchOperation(ctx, item, req.Angle, req.Policy)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		setErrorKind(span, err)
+		errorCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", operation)))
+		batchItems.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcomeFailed)))
+		logger.Warn("batch item failed",
+			zap.Int("index", i),
+			zap.String("operation", operation),
+			zap.String("error_kind", string(errorKind(err))),
+			zap.Error(err),
+		)
+		res.Error, res.ErrorKind = err.Error(), errorKind(err)
+		return res
+	}
+
+	span.SetAttributes(attribute.Float64("calculator.result", result))
+	span.SetStatus(codes.Ok, "")
+	batchItems.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcomeSucceeded)))
+	n := Number(result)
+	res.Result = &n
+	return res
+}
+
+// runBatchOperation computes a single-operation batch item like
+// handleOperation does, on the item span in ctx.
+func runBatchOperation(ctx context.Context, item BatchItem, angle AngleUnit, policy ResultPolicy) (float64, error) {
+	op, ok := LookupOperation(item.Op)
+	if !ok {
+		return 0, fmt.Errorf("unknown operation %q", item.Op)
+	}
+	if math.IsNaN(item.A) || math.IsInf(item.A, 0) || math.IsNaN(item.B) || math.IsInf(item.B, 0) {
+		return 0, fmt.Errorf("invalid numeric input: a=%g b=%g", item.A, item.B)
+	}
+
+	start := time.Now()
+	result, err := calculate(ctx, op, angle, policy, []float64{item.A, item.B}[:op.Arity]...)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0 // ms
+
+	attrs := metric.WithAttributes(attribute.String("operation", op.Name))
+	opsCounter.Add(ctx, 1, attrs)
+	opsHistogram.Record(ctx, elapsed, attrs)
+	saveHistory(ctx, Calculation{
+		Operation: op.Name,
+		Operands:  []Number{Number(item.A), Number(item.B)}[:op.Arity],
+		Result:    Number(result),
+	})
+	return result, nil
+}
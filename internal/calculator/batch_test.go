@@ -0,0 +1,66 @@
+package calculator
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// initBatchMetrics creates the instruments runBatch records to (no-ops
+// unless a test installed a MeterProvider).
+func initBatchMetrics(t *testing.T) {
+	t.Helper()
+	if err := InitMetrics(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunBatchBoundsConcurrencyAndKeepsOrder(t *testing.T) {
+	initBatchMetrics(t)
+
+	var running, peak atomic.Int32
+	results := runBatch(context.Background(), 50, func(_ context.Context, i int) BatchResult {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return BatchResult{Index: i}
+	})
+
+	if got := peak.Load(); got > batchWorkers {
+		t.Fatalf("expected at most %d items at once, got %d", batchWorkers, got)
+	}
+	for i, res := range results {
+		if res.Index != i {
+			t.Fatalf("result %d has index %d", i, res.Index)
+		}
+	}
+}
+
+func TestRunBatchStopsOnCancellation(t *testing.T) {
+	initBatchMetrics(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var started atomic.Int32
+	results := runBatch(ctx, 100, func(_ context.Context, i int) BatchResult {
+		if started.Add(1) == 1 {
+			cancel()
+		}
+		return BatchResult{Index: i}
+	})
+
+	if got := started.Load(); got > batchWorkers {
+		t.Fatalf("expected no items to start after cancellation, %d started", got)
+	}
+	last := results[len(results)-1]
+	if last.Index != len(results)-1 || !strings.Contains(last.Error, "not run: context canceled") {
+		t.Fatalf("expected the last item to be canceled, got %+v", last)
+	}
+}
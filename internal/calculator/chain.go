@@ -0,0 +1,194 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// validate checks the mode; errors mean the request is rejected as a whole.
+func (m ChainMode) validate() error {
+	switch m {
+	case "", ChainAbort, ChainContinue:
+		return nil
+	}
+	return fmt.Errorf("unknown chain mode %q, expected %q or %q", m, ChainAbort, ChainContinue)
+}
+
+// validate checks the parts of step i that do not depend on earlier results.
+func (step ChainStep) validate(i int, session string) error {
+	if step.ValueFrom != nil {
+		switch from := *step.ValueFrom; {
+		case from < 0 || from >= i:
+			return fmt.Errorf("value_from %d at step %d must name an earlier step", from, i)
+		case step.register != "":
+			return fmt.Errorf("step %d has both value_from and a register value", i)
+		}
+		if op, ok := LookupOperation(step.Op); ok && op.Arity != 2 {
+			return fmt.Errorf("value_from at step %d, but %s takes no value", i, step.Op)
+		}
+	}
+	if step.If != "" {
+		if _, err := parseCondition(step.If); err != nil {
+			return fmt.Errorf("%w at step %d", err, i)
+		}
+	}
+	if err := validateStore(step.Store, session); err != nil {
+		return fmt.Errorf("%w at step %d", err, i)
+	}
+	return nil
+}
+
+// condition is a parsed step condition: two operands compared.
+type condition struct {
+	left, right conditionOperand
+	op          string
+}
+
+// conditionOperand is "result" (the running total), "$name" (a session
+// register) or a number.
+type conditionOperand struct {
+	result   bool
+	register string
+	value    float64
+}
+
+var conditionPattern = regexp.MustCompile(`^\s*(\S+?)\s*(<=|>=|==|!=|<|>)\s*(\S+)\s*$`)
+
+// parseCondition parses conditions like "result > 100" or "$x <= result".
+func parseCondition(s string) (condition, error) {
+	m := conditionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return condition{}, fmt.Errorf("invalid condition %q, expected e.g. \"result > 100\"", s)
+	}
+	left, err := parseConditionOperand(m[1])
+	if err != nil {
+		return condition{}, fmt.Errorf("invalid condition %q: %w", s, err)
+	}
+	right, err := parseConditionOperand(m[3])
+	if err != nil {
+		return condition{}, fmt.Errorf("invalid condition %q: %w", s, err)
+	}
+	return condition{left: left, op: m[2], right: right}, nil
+}
+
+func parseConditionOperand(s string) (conditionOperand, error) {
+	if s == "result" {
+		return conditionOperand{result: true}, nil
+	}
+	if name, ok := strings.CutPrefix(s, "$"); ok && validRegister(name) {
+		return conditionOperand{register: name}, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return conditionOperand{}, fmt.Errorf("%q is not result, a $register or a finite number", s)
+	}
+	return conditionOperand{value: v}, nil
+}
+
+func (o conditionOperand) eval(running float64, s *session) (float64, error) {
+	if o.result {
+		return running, nil
+	}
+	return s.resolve(o.value, o.register)
+}
+
+// holds evaluates the condition against the running total.
+func (c condition) holds(running float64, s *session) (bool, error) {
+	l, err := c.left.eval(running, s)
+	if err != nil {
+		return false, err
+	}
+	r, err := c.right.eval(running, s)
+	if err != nil {
+		return false, err
+	}
+	switch c.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "==":
+		return l == r, nil
+	default: // "!="
+		return l != r, nil
+	}
+}
+
+// applyStep runs step i of req on the running total, on the step span in
+// ctx. It returns the operand used and the new total; a step whose condition
+// does not hold is skipped and leaves the total unchanged. results holds the
+// earlier steps, for value_from; naming a failed or skipped step is an error.
+func applyStep(ctx context.Context, req ChainRequest, sess *session, results []ChainResult, i int, running float64) (value, total float64, skipped bool, err error) {
+	span := trace.SpanFromContext(ctx)
+	step := req.Steps[i]
+	value = step.Value
+
+	if step.If != "" {
+		cond, err := parseCondition(step.If)
+		if err != nil {
+			return value, running, false, err
+		}
+		holds, err := cond.holds(running, sess)
+		if err != nil {
+			return value, running, false, err
+		}
+		span.SetAttributes(
+			attribute.String("chain.step.condition", step.If),
+			attribute.Bool("chain.step.condition_met", holds),
+		)
+		if !holds {
+			return value, running, true, nil
+		}
+	}
+
+	op, ok := LookupOperation(step.Op)
+	if !ok {
+		return value, running, false, fmt.Errorf("unknown operation %q", step.Op)
+	}
+	if op.Angle {
+		span.SetAttributes(angleUnitAttr(req.Angle))
+	}
+	if op.Arity == 2 {
+		switch {
+		case step.ValueFrom != nil:
+			from := results[*step.ValueFrom]
+			span.SetAttributes(attribute.Int("chain.step.value_from", *step.ValueFrom))
+			switch from.Status {
+			case StepFailed:
+				return value, running, false, fmt.Errorf("value_from step %d, which failed", *step.ValueFrom)
+			case StepSkipped:
+				return value, running, false, fmt.Errorf("value_from step %d, which was skipped", *step.ValueFrom)
+			}
+			value = float64(from.Result)
+		case step.register != "":
+			span.SetAttributes(attribute.String("chain.step.value_register", step.register))
+			if value, err = sess.resolve(step.Value, step.register); err != nil {
+				return value, running, false, err
+			}
+		}
+		span.SetAttributes(attribute.Float64("chain.step.value", value))
+	}
+
+	total, err = calculate(ctx, op, req.Angle, req.Policy, []float64{running, value}[:op.Arity]...)
+	if err != nil {
+		return value, running, false, err
+	}
+	if step.Store != "" {
+		if err := sess.store(step.Store, total); err != nil {
+			return value, running, false, err
+		}
+		span.SetAttributes(attribute.String("chain.step.stored", step.Store))
+	}
+	return value, total, false, nil
+}
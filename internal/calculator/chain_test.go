@@ -0,0 +1,94 @@
+package calculator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		cond    string
+		running float64
+		want    bool
+	}{
+		{"result > 100", 101, true},
+		{"result > 100", 100, false},
+		{"result>=100", 100, true},
+		{"result < -5", -6, true},
+		{"result <= 0", 1, false},
+		{"result == 3", 3, true},
+		{"result != 3", 3, false},
+		{"10 > result", 5, true},
+		{"  result   <   1e3 ", 999, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			c, err := parseCondition(tt.cond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.holds(tt.running, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%q with result=%g: got %v, want %v", tt.cond, tt.running, got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "result", "result > ", "result => 1", "result > x", "result > NaN", "result > 1 > 2"} {
+		if _, err := parseCondition(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestConditionReadsRegisters(t *testing.T) {
+	initBatchMetrics(t)
+
+	s, _ := newSessionStore(time.Now).create(context.Background(), time.Minute)
+	s.store("limit", 10)
+
+	c, err := parseCondition("result >= $limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.holds(10, s); err != nil || !ok {
+		t.Errorf("expected 10 >= $limit, got %v, %v", ok, err)
+	}
+	if _, err := c.holds(10, nil); err == nil || !strings.Contains(err.Error(), "needs a session") {
+		t.Errorf("expected a missing session error, got %v", err)
+	}
+}
+
+func TestChainRequestValidateSteps(t *testing.T) {
+	from := func(i int) *int { return &i }
+
+	tests := []struct {
+		name string
+		req  ChainRequest
+		want string
+	}{
+		{"forward reference", ChainRequest{Steps: []ChainStep{{Op: "add", ValueFrom: from(0)}}}, "must name an earlier step"},
+		{"negative reference", ChainRequest{Steps: []ChainStep{{Op: "add"}, {Op: "add", ValueFrom: from(-1)}}}, "must name an earlier step"},
+		{"reference and register", ChainRequest{Steps: []ChainStep{{Op: "add"}, {Op: "add", ValueFrom: from(0), register: "x"}}}, "both value_from and a register"},
+		{"reference on a unary operation", ChainRequest{Steps: []ChainStep{{Op: "add"}, {Op: "sqrt", ValueFrom: from(0)}}}, "sqrt takes no value"},
+		{"bad condition", ChainRequest{Steps: []ChainStep{{Op: "add", If: "result ~ 1"}}}, "invalid condition"},
+		{"unknown mode", ChainRequest{Mode: "retry", Steps: []ChainStep{{Op: "add"}}}, "unknown chain mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	ok := ChainRequest{Mode: ChainContinue, Steps: []ChainStep{{Op: "add"}, {Op: "add", ValueFrom: from(0), If: "result > 1"}}}
+	if err := ok.validate(); err != nil {
+		t.Errorf("expected a valid chain, got %v", err)
+	}
+}
@@ -0,0 +1,736 @@
+package calculator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultDecimalScale is the scale of non-terminating results (1/3,
+	// sqrt(2)) when the request sets none — the precision of IEEE decimal128.
+	DefaultDecimalScale = 34
+	// MaxDecimalScale bounds the requested scale.
+	MaxDecimalScale = 1000
+
+	// maxDecimalExponent bounds the exponent of operands like "1e500", and
+	// maxDecimalBits the size of any value, so no request can make the
+	// service allocate without limit.
+	maxDecimalExponent = 1000
+	maxDecimalBits     = 1 << 16
+)
+
+// ErrUnsupported is the ErrorKind of operands decimal precision cannot
+// compute exactly (2 ^ 0.5, root 1.5 of 8, ...).
+const ErrUnsupported ErrorKind = "unsupported"
+
+// precisionOf returns the precision requested by the ?precision= query
+// parameter or, failing that, the body's "precision" field.
+func precisionOf(r *http.Request, body []byte) (Precision, error) {
+	p := Precision(r.URL.Query().Get("precision"))
+	if p == "" {
+		// A malformed body is reported by the handler's full decode.
+		var opts DecimalOptions
+		_ = json.Unmarshal(body, &opts)
+		p = opts.Precision
+	}
+	switch p {
+	case "", PrecisionFloat:
+		return PrecisionFloat, nil
+	case PrecisionDecimal:
+		return PrecisionDecimal, nil
+	}
+	return "", fmt.Errorf("unknown precision %q, expected %q or %q", p, PrecisionFloat, PrecisionDecimal)
+}
+
+// validate checks the scale and rounding mode and fills in the default
+// rounding mode.
+func (o *DecimalOptions) validate() error {
+	if o.Scale != nil && (*o.Scale < 0 || *o.Scale > MaxDecimalScale) {
+		return fmt.Errorf("scale %d out of range 0-%d", *o.Scale, MaxDecimalScale)
+	}
+	switch o.Rounding {
+	case "":
+		o.Rounding = RoundHalfEven
+	case RoundHalfEven, RoundHalfUp, RoundFloor, RoundCeiling:
+	default:
+		return fmt.Errorf("unknown rounding mode %q, expected %q, %q, %q or %q", o.Rounding, RoundHalfEven, RoundHalfUp, RoundFloor, RoundCeiling)
+	}
+	return nil
+}
+
+// errSessionDecimal rejects session and store in decimal precision.
+var errSessionDecimal = errors.New("sessions are not supported in decimal precision")
+
+// validate checks the angle unit and options and rejects sessions, which
+// decimal precision does not support.
+func (req *DecimalCalcRequest) validate() error {
+	if req.Session != "" || req.Store != "" {
+		return errSessionDecimal
+	}
+	return errors.Join(req.Angle.validate(), req.DecimalOptions.validate())
+}
+
+// validateSteps rejects the chain features decimal precision does not
+// support: step references, conditions, sessions and continue mode.
+func (req DecimalChainRequest) validateSteps() error {
+	switch {
+	case req.Mode == ChainContinue:
+		return errors.New("mode \"continue\" is not supported in decimal precision")
+	case req.Session != "":
+		return errSessionDecimal
+	}
+	for i, step := range req.Steps {
+		switch {
+		case step.ValueFrom != nil:
+			return fmt.Errorf("value_from is not supported in decimal precision (step %d)", i)
+		case step.If != "":
+			return fmt.Errorf("conditional steps are not supported in decimal precision (step %d)", i)
+		case step.Store != "":
+			return fmt.Errorf("%w (step %d)", errSessionDecimal, i)
+		}
+	}
+	return nil
+}
+
+// scaleFor returns the scale x is rounded to: the requested one or, if none,
+// the digits x needs to be exact, or DefaultDecimalScale if it never ends.
+func (o DecimalOptions) scaleFor(x *big.Rat) int {
+	if o.Scale != nil {
+		return *o.Scale
+	}
+	if digits, ok := terminatingDigits(x); ok {
+		return digits
+	}
+	return DefaultDecimalScale
+}
+
+// computeScale is the scale passed to Operation.Decimal.
+func (o DecimalOptions) computeScale() int {
+	if o.Scale != nil {
+		return *o.Scale
+	}
+	return DefaultDecimalScale
+}
+
+// decimalPattern is JSON's number syntax, which decimal strings must follow
+// too.
+var decimalPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// parseDecimal reads a decimal operand. Missing operands are zero, like
+// their float64 counterparts.
+func parseDecimal(n json.Number) (*big.Rat, error) {
+	s := string(n)
+	if s == "" {
+		return new(big.Rat), nil
+	}
+	if !decimalPattern.MatchString(s) {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp, err := strconv.Atoi(s[i+1:])
+		if err != nil || exp > maxDecimalExponent || exp < -maxDecimalExponent {
+			return nil, fmt.Errorf("invalid decimal %q: exponent out of range ±%d", s, maxDecimalExponent)
+		}
+	}
+	x, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return x, nil
+}
+
+// terminatingDigits returns the number of decimal places x needs to be
+// written exactly, and false if its expansion never ends.
+func terminatingDigits(x *big.Rat) (int, bool) {
+	den := new(big.Int).Set(x.Denom())
+	var twos, fives int
+	for ; den.Bit(0) == 0; twos++ {
+		den.Rsh(den, 1)
+	}
+	five, rem := big.NewInt(5), new(big.Int)
+	for {
+		q, r := new(big.Int).QuoRem(den, five, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		den, fives = q, fives+1
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	return max(twos, fives), true
+}
+
+// formatExact writes x in decimal notation if it terminates, else as a
+// fraction. It is for messages; responses use roundDecimal and FloatString.
+func formatExact(x *big.Rat) string {
+	if digits, ok := terminatingDigits(x); ok {
+		return x.FloatString(digits)
+	}
+	return x.RatString()
+}
+
+// roundDecimal rounds x to scale decimal places.
+func roundDecimal(x *big.Rat, scale int, mode RoundingMode) *big.Rat {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	scaled := new(big.Rat).Mul(x, new(big.Rat).SetInt(unit))
+
+	// q is truncated towards zero; |rem| / den is the discarded fraction.
+	q, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		away := false // whether to move q one unit away from zero
+		switch mode {
+		case RoundFloor:
+			away = x.Sign() < 0
+		case RoundCeiling:
+			away = x.Sign() > 0
+		default:
+			half := new(big.Int).Abs(rem)
+			switch half.Lsh(half, 1).Cmp(scaled.Denom()) {
+			case 1:
+				away = true
+			case 0:
+				away = mode == RoundHalfUp || q.Bit(0) == 1
+			}
+		}
+		if away {
+			q.Add(q, big.NewInt(int64(x.Sign())))
+		}
+	}
+	return new(big.Rat).SetFrac(q, unit)
+}
+
+// applyDecimal computes op exactly. scale is the number of decimal places
+// the result will be rounded to; operations without an exact result compute
+// just beyond it. Angles in degrees with a rational result are exact too.
+func (op Operation) applyDecimal(unit AngleUnit, scale int, args ...*big.Rat) (*big.Rat, error) {
+	if len(args) != op.Arity {
+		return nil, fmt.Errorf("%s takes %d operand(s), got %d", op.Name, op.Arity, len(args))
+	}
+	if op.Decimal == nil {
+		return nil, operandErrorf(op.Name, ErrUnsupported, "%s is not available in decimal precision", op.Name)
+	}
+	if op.Angle && unit == Degrees {
+		if r, ok, err := exactDegrees(op.Name, args[0]); ok {
+			return r, err
+		}
+		args = []*big.Rat{degreesToRadians(args[0], scale)}
+	}
+	result, err := op.Decimal(args, scale)
+	if err != nil {
+		return nil, err
+	}
+	if result.Num().BitLen() > maxDecimalBits || result.Denom().BitLen() > maxDecimalBits {
+		return nil, operandErrorf(op.Name, ErrTooLarge, "%s result exceeds the decimal size limit", op.Name)
+	}
+	return result, nil
+}
+
+// ---------------------------------------------------------------------------
+// Decimal implementations of the built-in operations
+// ---------------------------------------------------------------------------
+
+func decimalAdd(a []*big.Rat, _ int) (*big.Rat, error) {
+	return new(big.Rat).Add(a[0], a[1]), nil
+}
+
+func decimalSubtract(a []*big.Rat, _ int) (*big.Rat, error) {
+	return new(big.Rat).Sub(a[0], a[1]), nil
+}
+
+func decimalMultiply(a []*big.Rat, _ int) (*big.Rat, error) {
+	return new(big.Rat).Mul(a[0], a[1]), nil
+}
+
+func decimalDivisor(op string) func(a []*big.Rat) error {
+	return func(a []*big.Rat) error {
+		if a[1].Sign() == 0 {
+			return operandErrorf(op, ErrDivisionByZero, "division by zero: %s / %s", formatExact(a[0]), formatExact(a[1]))
+		}
+		return nil
+	}
+}
+
+func decimalDivide(a []*big.Rat, _ int) (*big.Rat, error) {
+	if err := decimalDivisor("divide")(a); err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Quo(a[0], a[1]), nil
+}
+
+func decimalModulo(a []*big.Rat, _ int) (*big.Rat, error) {
+	if err := decimalDivisor("modulo")(a); err != nil {
+		return nil, err
+	}
+	// a - b*trunc(a/b): the sign of a, like math.Mod.
+	q := new(big.Rat).Quo(a[0], a[1])
+	trunc := new(big.Int).Quo(q.Num(), q.Denom())
+	return new(big.Rat).Sub(a[0], new(big.Rat).Mul(a[1], new(big.Rat).SetInt(trunc))), nil
+}
+
+func decimalPower(a []*big.Rat, _ int) (*big.Rat, error) {
+	x, n := a[0], a[1]
+	if !n.IsInt() {
+		return nil, operandErrorf("power", ErrUnsupported, "decimal power requires an integer exponent, got %s", formatExact(n))
+	}
+	if !n.Num().IsInt64() || n.Num().Int64() > maxDecimalExponent || n.Num().Int64() < -maxDecimalExponent {
+		return nil, operandErrorf("power", ErrTooLarge, "decimal exponent %s out of range ±%d", formatExact(n), maxDecimalExponent)
+	}
+	e := n.Num().Int64()
+	if x.Sign() == 0 && e < 0 {
+		return nil, operandErrorf("power", ErrDivisionByZero, "division by zero: 0 ^ %d", e)
+	}
+	size := max(x.Num().BitLen(), x.Denom().BitLen())
+	if int64(size)*max(e, -e) > maxDecimalBits {
+		return nil, operandErrorf("power", ErrTooLarge, "power result exceeds the decimal size limit")
+	}
+	abs := big.NewInt(max(e, -e))
+	num := new(big.Int).Exp(x.Num(), abs, nil)
+	den := new(big.Int).Exp(x.Denom(), abs, nil)
+	if e < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+func decimalAbs(a []*big.Rat, _ int) (*big.Rat, error) {
+	return new(big.Rat).Abs(a[0]), nil
+}
+
+func decimalSqrt(a []*big.Rat, scale int) (*big.Rat, error) {
+	if a[0].Sign() < 0 {
+		return nil, operandErrorf("sqrt", ErrOutOfDomain, "square root of negative number %s", formatExact(a[0]))
+	}
+	return decimalNthRoot(a[0], 2, scale), nil
+}
+
+func decimalRoot(a []*big.Rat, scale int) (*big.Rat, error) {
+	x, n := a[0], a[1]
+	if !n.IsInt() || n.Sign() <= 0 || !n.Num().IsInt64() || n.Num().Int64() > maxDecimalExponent {
+		return nil, operandErrorf("root", ErrUnsupported, "decimal root requires a positive integer degree up to %d, got %s", maxDecimalExponent, formatExact(n))
+	}
+	k := int(n.Num().Int64())
+	if x.Sign() < 0 {
+		if k%2 == 0 {
+			return nil, operandErrorf("root", ErrOutOfDomain, "root %d of negative number %s requires an odd integer degree", k, formatExact(x))
+		}
+		r := decimalNthRoot(new(big.Rat).Neg(x), k, scale)
+		return r.Neg(r), nil
+	}
+	return decimalNthRoot(x, k, scale), nil
+}
+
+// decimalNthRoot returns the k-th root of x >= 0: exact when it has at most
+// scale+1 decimal places, and otherwise a non-terminating value strictly
+// between the two (scale+1)-place neighbours of the true root — which rounds
+// to scale places exactly as the root itself would, and is never mistaken
+// for an exact result.
+func decimalNthRoot(x *big.Rat, k, scale int) *big.Rat {
+	places := scale + 1
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+
+	// r = floor(k-th root of floor(x * 10^(places*k)))
+	scaled := new(big.Rat).Mul(x, new(big.Rat).SetInt(new(big.Int).Exp(unit, big.NewInt(int64(k)), nil)))
+	n := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+	r := intNthRoot(n, k)
+
+	root := new(big.Rat).SetFrac(r, unit)
+	if scaled.IsInt() && new(big.Int).Exp(r, big.NewInt(int64(k)), nil).Cmp(n) == 0 {
+		return root
+	}
+	nudge := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Mul(unit, big.NewInt(30)))
+	return root.Add(root, nudge)
+}
+
+// intNthRoot returns floor(n^(1/k)) for n >= 0 by Newton's method.
+func intNthRoot(n *big.Int, k int) *big.Int {
+	if n.Sign() == 0 {
+		return new(big.Int)
+	}
+	bk, bk1 := big.NewInt(int64(k)), big.NewInt(int64(k-1))
+	// Start above the root and descend.
+	x := new(big.Int).Lsh(big.NewInt(1), uint(n.BitLen()/k+1))
+	for {
+		// y = ((k-1)x + n / x^(k-1)) / k
+		y := new(big.Int).Exp(x, bk1, nil)
+		y.Quo(n, y)
+		y.Add(y, new(big.Int).Mul(bk1, x))
+		y.Quo(y, bk)
+		if y.Cmp(x) >= 0 {
+			return x
+		}
+		x = y
+	}
+}
+
+func decimalIntegers(op string, a []*big.Rat) error {
+	for _, x := range a {
+		if !x.IsInt() {
+			return operandErrorf(op, ErrNotInteger, "%s requires integers, got %s", op, formatExact(x))
+		}
+	}
+	return nil
+}
+
+func decimalGCD(a []*big.Rat, _ int) (*big.Rat, error) {
+	if err := decimalIntegers("gcd", a); err != nil {
+		return nil, err
+	}
+	x, y := new(big.Int).Abs(a[0].Num()), new(big.Int).Abs(a[1].Num())
+	return new(big.Rat).SetInt(new(big.Int).GCD(nil, nil, x, y)), nil
+}
+
+func decimalLCM(a []*big.Rat, _ int) (*big.Rat, error) {
+	if err := decimalIntegers("lcm", a); err != nil {
+		return nil, err
+	}
+	x, y := new(big.Int).Abs(a[0].Num()), new(big.Int).Abs(a[1].Num())
+	if x.Sign() == 0 || y.Sign() == 0 {
+		return new(big.Rat), nil
+	}
+	g := new(big.Int).GCD(nil, nil, x, y)
+	return new(big.Rat).SetInt(new(big.Int).Mul(new(big.Int).Quo(x, g), y)), nil
+}
+
+func decimalFactorial(a []*big.Rat, _ int) (*big.Rat, error) {
+	n := a[0]
+	if !n.IsInt() || n.Sign() < 0 {
+		return nil, operandErrorf("factorial", ErrNotInteger, "factorial requires a non-negative integer, got %s", formatExact(n))
+	}
+	if !n.Num().IsInt64() || n.Num().Int64() > maxDecimalExponent {
+		return nil, operandErrorf("factorial", ErrTooLarge, "decimal factorial of %s exceeds the limit of %d", formatExact(n), maxDecimalExponent)
+	}
+	return new(big.Rat).SetInt(new(big.Int).MulRange(1, n.Num().Int64())), nil
+}
+
+// ---------------------------------------------------------------------------
+// Decimal transcendental operations, by series on big.Float
+// ---------------------------------------------------------------------------
+
+// decimalGuardDigits are the places computed beyond the scale for results
+// that are approximated.
+const decimalGuardDigits = 10
+
+// decimalSeries turns the approximation f computes at a given binary
+// precision into a result for scale places. f is called again with more
+// bits when the result's integer part takes up the precision. Like
+// decimalNthRoot, the result lies strictly between the two
+// (scale+decimalGuardDigits)-place neighbours of f's value and never
+// terminates.
+func decimalSeries(op string, scale int, f func(prec uint) (*big.Float, error)) (*big.Rat, error) {
+	places := scale + decimalGuardDigits
+	prec := uint(math.Ceil(float64(places)*math.Log2(10))) + 32
+	y, err := f(prec)
+	if err != nil {
+		return nil, err
+	}
+	if e := y.MantExp(nil); e > maxDecimalBits {
+		return nil, operandErrorf(op, ErrTooLarge, "%s result exceeds the decimal size limit", op)
+	} else if e > 0 {
+		if y, err = f(prec + uint(e)); err != nil {
+			return nil, err
+		}
+	}
+
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Float).SetPrec(y.Prec()+uint(unit.BitLen())).Mul(y, new(big.Float).SetInt(unit))
+	n, _ := scaled.Int(nil) // truncated towards zero
+	r := new(big.Rat).SetFrac(n, unit)
+	nudge := new(big.Rat).SetFrac(big.NewInt(int64(y.Sign())), new(big.Int).Mul(unit, big.NewInt(30)))
+	return r.Add(r, nudge), nil
+}
+
+func decimalLn(a []*big.Rat, scale int) (*big.Rat, error) {
+	x := a[0]
+	if x.Sign() <= 0 {
+		return nil, operandErrorf("ln", ErrOutOfDomain, "logarithm of non-positive number %s", formatExact(x))
+	}
+	if x.Cmp(big.NewRat(1, 1)) == 0 {
+		return new(big.Rat), nil
+	}
+	return decimalSeries("ln", scale, func(prec uint) (*big.Float, error) {
+		return floatLn(new(big.Float).SetPrec(prec).SetRat(x), prec), nil
+	})
+}
+
+func decimalLog10(a []*big.Rat, scale int) (*big.Rat, error) {
+	x := a[0]
+	if x.Sign() <= 0 {
+		return nil, operandErrorf("log10", ErrOutOfDomain, "logarithm of non-positive number %s", formatExact(x))
+	}
+	if k, ok := powerOfTen(x); ok {
+		return big.NewRat(int64(k), 1), nil
+	}
+	return decimalSeries("log10", scale, func(prec uint) (*big.Float, error) {
+		ln := floatLn(new(big.Float).SetPrec(prec+8).SetRat(x), prec+8)
+		return ln.Quo(ln, floatLn(big.NewFloat(10).SetPrec(prec+8), prec+8)), nil
+	})
+}
+
+// powerOfTen returns k if x is 10^k.
+func powerOfTen(x *big.Rat) (int, bool) {
+	n, sign := x.Num().String(), 1
+	if n == "1" {
+		n, sign = x.Denom().String(), -1
+	} else if !x.IsInt() {
+		return 0, false
+	}
+	if n[0] != '1' || strings.Trim(n[1:], "0") != "" {
+		return 0, false
+	}
+	return sign * (len(n) - 1), true
+}
+
+func decimalExp(a []*big.Rat, scale int) (*big.Rat, error) {
+	x := a[0]
+	if x.Sign() == 0 {
+		return big.NewRat(1, 1), nil
+	}
+	// The result's integer bits plus the places would exceed maxDecimalBits.
+	if xf, _ := x.Float64(); xf/math.Ln2+float64(scale+decimalGuardDigits)*math.Log2(10) > maxDecimalBits {
+		return nil, operandErrorf("exp", ErrTooLarge, "exp of %s exceeds the decimal size limit", formatExact(x))
+	}
+	return decimalSeries("exp", scale, func(prec uint) (*big.Float, error) {
+		return floatExp(new(big.Float).SetPrec(prec).SetRat(x), prec), nil
+	})
+}
+
+func decimalSin(a []*big.Rat, scale int) (*big.Rat, error) {
+	if a[0].Sign() == 0 {
+		return new(big.Rat), nil
+	}
+	return decimalSeries("sin", scale, func(prec uint) (*big.Float, error) {
+		sin, _ := floatSinCos(a[0], prec)
+		return sin, nil
+	})
+}
+
+func decimalCos(a []*big.Rat, scale int) (*big.Rat, error) {
+	if a[0].Sign() == 0 {
+		return big.NewRat(1, 1), nil
+	}
+	return decimalSeries("cos", scale, func(prec uint) (*big.Float, error) {
+		_, cos := floatSinCos(a[0], prec)
+		return cos, nil
+	})
+}
+
+func decimalTan(a []*big.Rat, scale int) (*big.Rat, error) {
+	if a[0].Sign() == 0 {
+		return new(big.Rat), nil
+	}
+	return decimalSeries("tan", scale, func(prec uint) (*big.Float, error) {
+		sin, cos := floatSinCos(a[0], prec)
+		// Near a pole, cos loses relative precision; make up for it.
+		if e := cos.MantExp(nil); e < 0 {
+			sin, cos = floatSinCos(a[0], prec+uint(-e))
+		}
+		return sin.Quo(sin, cos), nil
+	})
+}
+
+// exactDegrees returns sin, cos or tan of x degrees when the result is
+// rational, which by Niven's theorem happens only at multiples of 30° for
+// sin and cos and of 45° for tan.
+func exactDegrees(op string, x *big.Rat) (*big.Rat, bool, error) {
+	step := int64(30)
+	if op == "tan" {
+		step = 45
+	}
+	q := new(big.Rat).Quo(x, big.NewRat(step, 1))
+	if !q.IsInt() {
+		return nil, false, nil
+	}
+	k := new(big.Int).Mod(q.Num(), big.NewInt(360/step)).Int64()
+
+	sines := [12]string{"0", "1/2", "", "1", "", "1/2", "0", "-1/2", "", "-1", "", "-1/2"}
+	switch op {
+	case "sin":
+		if s := sines[k]; s != "" {
+			r, _ := new(big.Rat).SetString(s)
+			return r, true, nil
+		}
+	case "cos":
+		if s := sines[(k+3)%12]; s != "" {
+			r, _ := new(big.Rat).SetString(s)
+			return r, true, nil
+		}
+	case "tan":
+		switch k % 4 {
+		case 0:
+			return new(big.Rat), true, nil
+		case 1:
+			return big.NewRat(1, 1), true, nil
+		case 2:
+			return nil, true, operandErrorf("tan", ErrOutOfDomain, "tangent of %s degrees is undefined", formatExact(x))
+		case 3:
+			return big.NewRat(-1, 1), true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// degreesToRadians converts x to radians, accurate enough for a result of
+// scale places.
+func degreesToRadians(x *big.Rat, scale int) *big.Rat {
+	prec := uint(math.Ceil(float64(scale+decimalGuardDigits)*math.Log2(10))) + uint(max(x.Num().BitLen()-x.Denom().BitLen(), 0)) + 64
+	r := new(big.Float).SetPrec(prec).SetRat(x)
+	r.Mul(r, floatPi(prec))
+	r.Quo(r, big.NewFloat(180))
+	rad, _ := r.Rat(nil)
+	return rad
+}
+
+// floatPi returns π to prec bits by Machin's formula,
+// π = 16 atan(1/5) - 4 atan(1/239).
+func floatPi(prec uint) *big.Float {
+	pi := new(big.Int).Mul(inverseSeries(5, true, prec), big.NewInt(16))
+	pi.Sub(pi, new(big.Int).Mul(inverseSeries(239, true, prec), big.NewInt(4)))
+	return fixedToFloat(pi, prec)
+}
+
+// floatLn2 returns ln 2 to prec bits by
+// ln 2 = 18 atanh(1/26) - 2 atanh(1/4801) + 8 atanh(1/8749).
+func floatLn2(prec uint) *big.Float {
+	ln2 := new(big.Int).Mul(inverseSeries(26, false, prec), big.NewInt(18))
+	ln2.Sub(ln2, new(big.Int).Mul(inverseSeries(4801, false, prec), big.NewInt(2)))
+	ln2.Add(ln2, new(big.Int).Mul(inverseSeries(8749, false, prec), big.NewInt(8)))
+	return fixedToFloat(ln2, prec)
+}
+
+// seriesGuardBits absorb the truncation of every term of inverseSeries.
+const seriesGuardBits = 32
+
+// inverseSeries returns Σ s^k / ((2k+1) n^(2k+1)), which is atan(1/n) for
+// s = -1 (alternate) and atanh(1/n) for s = 1, as an integer scaled by
+// 2^(prec+seriesGuardBits). Fixed point on big.Int divides by small
+// integers in place, which is far cheaper than big.Float.
+func inverseSeries(n int64, alternate bool, prec uint) *big.Int {
+	pow := new(big.Int).Lsh(big.NewInt(1), prec+seriesGuardBits)
+	pow.Quo(pow, big.NewInt(n))
+	n2 := big.NewInt(n * n)
+	sum, term, div := new(big.Int), new(big.Int), new(big.Int)
+	for k := int64(0); pow.Sign() != 0; k++ {
+		term.Quo(pow, div.SetInt64(2*k+1))
+		if alternate && k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+		pow.Quo(pow, n2)
+	}
+	return sum
+}
+
+// fixedToFloat converts a result of inverseSeries to a prec-bit big.Float.
+func fixedToFloat(x *big.Int, prec uint) *big.Float {
+	f := new(big.Float).SetPrec(prec).SetInt(x)
+	return f.SetMantExp(f, -int(prec+seriesGuardBits))
+}
+
+// atanh returns Σ z^(2k+1) / (2k+1) to prec bits, for |z| <= 1/3.
+func atanh(z *big.Float, prec uint) *big.Float {
+	sum := new(big.Float).SetPrec(prec)
+	pow := new(big.Float).SetPrec(prec).Set(z)
+	z2 := new(big.Float).SetPrec(prec).Mul(z, z)
+	term := new(big.Float).SetPrec(prec)
+	for k := int64(0); pow.Sign() != 0 && pow.MantExp(nil) > -int(prec); k++ {
+		sum.Add(sum, term.Quo(pow, big.NewFloat(float64(2*k+1))))
+		pow.Mul(pow, z2)
+	}
+	return sum
+}
+
+// floatLn returns ln x for x > 0 to prec bits after the point: with
+// x = m·2^e and m in [0.5, 1), ln x = e·ln 2 + 2 atanh((m-1)/(m+1)).
+func floatLn(x *big.Float, prec uint) *big.Float {
+	m := new(big.Float)
+	e := x.MantExp(m)
+	p := prec + uint(bits.Len(uint(max(e, -e)))) + 8
+	m.SetPrec(p)
+
+	one := big.NewFloat(1)
+	z := new(big.Float).SetPrec(p).Sub(m, one)
+	z.Quo(z, new(big.Float).SetPrec(p).Add(m, one))
+	ln := atanh(z, p)
+	ln.Mul(ln, big.NewFloat(2))
+
+	ln2 := floatLn2(p)
+	return ln.Add(ln, ln2.Mul(ln2, big.NewFloat(float64(e))))
+}
+
+// floatExp returns e^x to prec bits after the point: with x = k·ln 2 + r
+// and |r| <= ln 2 / 2, e^x = 2^k · (Σ (r/2^s)^n / n!)^(2^s). Halving r s
+// times, with s about the square root of the precision, cuts the terms of
+// the series far more than the squarings cost.
+func floatExp(x *big.Float, prec uint) *big.Float {
+	if x.Cmp(new(big.Float).SetInt64(-int64(prec))) < 0 {
+		// Below 2^-prec: any value that small rounds the same.
+		return new(big.Float).SetMantExp(big.NewFloat(1), -int(prec)-1)
+	}
+	xf, _ := x.Float64()
+	s := int(math.Sqrt(float64(prec)))
+	p := prec + uint(bits.Len(uint(math.Abs(xf)))) + uint(s) + 16
+
+	ln2 := floatLn2(p)
+	k := int(math.Round(xf / math.Ln2))
+	r := new(big.Float).SetPrec(p).Set(x)
+	r.Sub(r, new(big.Float).SetPrec(p).Mul(ln2, big.NewFloat(float64(k))))
+	r.SetMantExp(r, -s)
+
+	sum := new(big.Float).SetPrec(p).SetInt64(1)
+	term := new(big.Float).SetPrec(p).SetInt64(1)
+	for n := int64(1); term.Sign() != 0 && term.MantExp(nil) > -int(p); n++ {
+		term.Mul(term, r)
+		term.Quo(term, big.NewFloat(float64(n)))
+		sum.Add(sum, term)
+	}
+	for range s {
+		sum.Mul(sum, sum)
+	}
+	return sum.SetMantExp(sum, k)
+}
+
+// floatSinCos returns sin x and cos x to prec bits after the point. x is
+// first reduced by a multiple of 2π to [-π, π], with enough bits of π for
+// the size of x.
+func floatSinCos(x *big.Rat, prec uint) (sin, cos *big.Float) {
+	p := prec + uint(max(x.Num().BitLen()-x.Denom().BitLen(), 0)) + 16
+
+	r := new(big.Float).SetPrec(p).SetRat(x)
+	twoPi := floatPi(p)
+	twoPi.Mul(twoPi, big.NewFloat(2))
+	turns := new(big.Float).SetPrec(p).Quo(r, twoPi)
+	half := big.NewFloat(0.5)
+	if turns.Sign() < 0 {
+		half.Neg(half)
+	}
+	k, _ := turns.Add(turns, half).Int(nil) // rounded to the nearest
+	r.Sub(r, new(big.Float).SetPrec(p).Mul(twoPi, new(big.Float).SetInt(k)))
+
+	r2 := new(big.Float).SetPrec(p).Mul(r, r)
+	r2.Neg(r2)
+	sin = new(big.Float).SetPrec(p).Set(r)
+	cos = new(big.Float).SetPrec(p).SetInt64(1)
+	sinTerm := new(big.Float).SetPrec(p).Set(r)
+	cosTerm := new(big.Float).SetPrec(p).SetInt64(1)
+	for n := int64(1); ; n++ {
+		cosTerm.Mul(cosTerm, r2)
+		cosTerm.Quo(cosTerm, big.NewFloat(float64((2*n-1)*(2*n))))
+		cos.Add(cos, cosTerm)
+		sinTerm.Mul(sinTerm, r2)
+		sinTerm.Quo(sinTerm, big.NewFloat(float64((2*n)*(2*n+1))))
+		sin.Add(sin, sinTerm)
+		if n > 2 && (sinTerm.Sign() == 0 || sinTerm.MantExp(nil) < -int(p)) && (cosTerm.Sign() == 0 || cosTerm.MantExp(nil) < -int(p)) {
+			return sin, cos
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package calculator
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func rat(s string) *big.Rat {
+	x, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic(s)
+	}
+	return x
+}
+
+func TestRoundDecimal(t *testing.T) {
+	tests := []struct {
+		x     string
+		scale int
+		mode  RoundingMode
+		want  string
+	}{
+		{"2.345", 2, RoundHalfEven, "2.34"},
+		{"2.355", 2, RoundHalfEven, "2.36"},
+		{"2.345", 2, RoundHalfUp, "2.35"},
+		{"-2.345", 2, RoundHalfUp, "-2.35"},
+		{"-2.345", 2, RoundHalfEven, "-2.34"},
+		{"2.3451", 2, RoundHalfEven, "2.35"},
+		{"2.341", 2, RoundCeiling, "2.35"},
+		{"-2.349", 2, RoundCeiling, "-2.34"},
+		{"2.349", 2, RoundFloor, "2.34"},
+		{"-2.341", 2, RoundFloor, "-2.35"},
+		{"1/3", 4, RoundHalfEven, "0.3333"},
+		{"2/3", 0, RoundHalfEven, "1"},
+		{"7", 3, RoundFloor, "7.000"},
+	}
+
+	for _, tt := range tests {
+		if got := roundDecimal(rat(tt.x), tt.scale, tt.mode).FloatString(tt.scale); got != tt.want {
+			t.Errorf("roundDecimal(%s, %d, %s) = %s, want %s", tt.x, tt.scale, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestTerminatingDigits(t *testing.T) {
+	tests := []struct {
+		x      string
+		digits int
+		ok     bool
+	}{
+		{"3", 0, true},
+		{"0.3", 1, true},
+		{"1/8", 3, true},
+		{"1/3", 0, false},
+		{"1/30", 0, false},
+	}
+
+	for _, tt := range tests {
+		if digits, ok := terminatingDigits(rat(tt.x)); digits != tt.digits || ok != tt.ok {
+			t.Errorf("terminatingDigits(%s) = %d, %v, want %d, %v", tt.x, digits, ok, tt.digits, tt.ok)
+		}
+	}
+}
+
+func TestApplyDecimal(t *testing.T) {
+	tests := []struct {
+		op    string
+		args  []string
+		scale int
+		mode  RoundingMode
+		want  string
+	}{
+		{"add", []string{"0.1", "0.2"}, 1, RoundHalfEven, "0.3"},
+		{"divide", []string{"1", "3"}, 10, RoundHalfEven, "0.3333333333"},
+		{"divide", []string{"2", "3"}, 2, RoundFloor, "0.66"},
+		{"power", []string{"1.1", "3"}, 3, RoundHalfEven, "1.331"},
+		{"power", []string{"2", "-2"}, 2, RoundHalfEven, "0.25"},
+		{"modulo", []string{"-7.5", "2"}, 1, RoundHalfEven, "-1.5"},
+		{"sqrt", []string{"2"}, 20, RoundHalfEven, "1.41421356237309504880"},
+		{"sqrt", []string{"2.25"}, 1, RoundHalfEven, "1.5"},
+		{"sqrt", []string{"0.0625"}, 1, RoundHalfEven, "0.2"}, // 0.25 is a tie
+		{"root", []string{"-27", "3"}, 0, RoundHalfEven, "-3"},
+		{"gcd", []string{"123456789012345678901234567890", "9876543210"}, 0, RoundHalfEven, "90"},
+		{"factorial", []string{"25"}, 0, RoundHalfEven, "15511210043330985984000000"},
+	}
+
+	for _, tt := range tests {
+		op, _ := LookupOperation(tt.op)
+		args := make([]*big.Rat, len(tt.args))
+		for i, a := range tt.args {
+			args[i] = rat(a)
+		}
+		got, err := op.applyDecimal(Radians, tt.scale, args...)
+		if err != nil {
+			t.Errorf("%s%v: %v", tt.op, tt.args, err)
+			continue
+		}
+		if text := roundDecimal(got, tt.scale, tt.mode).FloatString(tt.scale); text != tt.want {
+			t.Errorf("%s%v at scale %d = %s, want %s", tt.op, tt.args, tt.scale, text, tt.want)
+		}
+	}
+}
+
+func TestApplyDecimalTranscendental(t *testing.T) {
+	tests := []struct {
+		op    string
+		x     string
+		unit  AngleUnit
+		scale int
+		want  string
+	}{
+		{"ln", "2", Radians, 30, "0.693147180559945309417232121458"},
+		{"ln", "1", Radians, 2, "0.00"},
+		{"ln", "1e-300", Radians, 10, "-690.7755278982"},
+		{"log10", "2", Radians, 20, "0.30102999566398119521"},
+		{"log10", "0.001", Radians, 0, "-3"},
+		{"exp", "1", Radians, 30, "2.718281828459045235360287471353"},
+		{"exp", "-1", Radians, 20, "0.36787944117144232160"},
+		{"exp", "-100000", Radians, 5, "0.00000"},
+		{"sin", "1", Radians, 25, "0.8414709848078965066525023"},
+		{"cos", "1", Radians, 25, "0.5403023058681397174009366"},
+		{"tan", "1", Radians, 20, "1.55740772465490223051"},
+		{"sin", "30", Degrees, 3, "0.500"},
+		{"sin", "-30", Degrees, 1, "-0.5"},
+		{"cos", "90", Degrees, 1, "0.0"},
+		{"tan", "135", Degrees, 0, "-1"},
+		{"sin", "45", Degrees, 20, "0.70710678118654752440"},
+	}
+
+	for _, tt := range tests {
+		op, _ := LookupOperation(tt.op)
+		got, err := op.applyDecimal(tt.unit, tt.scale, rat(tt.x))
+		if err != nil {
+			t.Errorf("%s(%s %s): %v", tt.op, tt.x, tt.unit, err)
+			continue
+		}
+		if text := roundDecimal(got, tt.scale, RoundHalfEven).FloatString(tt.scale); text != tt.want {
+			t.Errorf("%s(%s %s) at scale %d = %s, want %s", tt.op, tt.x, tt.unit, tt.scale, text, tt.want)
+		}
+	}
+
+	// Exact results terminate; approximations never do, so that without a
+	// scale they are rounded to DefaultDecimalScale.
+	for _, tt := range []struct {
+		op, x string
+		unit  AngleUnit
+		exact bool
+	}{
+		{"log10", "100", Radians, true},
+		{"sin", "150", Degrees, true},
+		{"ln", "10", Radians, false},
+		{"sin", "0.5", Radians, false},
+	} {
+		op, _ := LookupOperation(tt.op)
+		got, err := op.applyDecimal(tt.unit, DefaultDecimalScale, rat(tt.x))
+		if err != nil {
+			t.Fatalf("%s(%s): %v", tt.op, tt.x, err)
+		}
+		if _, ok := terminatingDigits(got); ok != tt.exact {
+			t.Errorf("%s(%s): expected exact %t, got %s", tt.op, tt.x, tt.exact, got.RatString())
+		}
+	}
+}
+
+func TestApplyDecimalErrors(t *testing.T) {
+	tests := []struct {
+		op   string
+		args []string
+		kind ErrorKind
+		msg  string
+	}{
+		{"divide", []string{"0.5", "0"}, ErrDivisionByZero, "division by zero: 0.5 / 0"},
+		{"ln", []string{"0"}, ErrOutOfDomain, "non-positive number 0"},
+		{"exp", []string{"100000"}, ErrTooLarge, "decimal size limit"},
+		{"power", []string{"2", "0.5"}, ErrUnsupported, "integer exponent"},
+		{"power", []string{"10", "100000"}, ErrTooLarge, "out of range"},
+		{"sqrt", []string{"-1"}, ErrOutOfDomain, "negative number -1"},
+		{"factorial", []string{"1.5"}, ErrNotInteger, "non-negative integer"},
+	}
+
+	for _, tt := range tests {
+		op, _ := LookupOperation(tt.op)
+		args := make([]*big.Rat, len(tt.args))
+		for i, a := range tt.args {
+			args[i] = rat(a)
+		}
+		_, err := op.applyDecimal(Radians, DefaultDecimalScale, args...)
+
+		var opErr *OperandError
+		if !errors.As(err, &opErr) || opErr.Kind != tt.kind || !strings.Contains(opErr.Msg, tt.msg) {
+			t.Errorf("%s%v: expected %s error containing %q, got %v", tt.op, tt.args, tt.kind, tt.msg, err)
+		}
+	}
+
+	tan, _ := LookupOperation("tan")
+	_, err := tan.applyDecimal(Degrees, DefaultDecimalScale, rat("270"))
+	var opErr *OperandError
+	if !errors.As(err, &opErr) || opErr.Kind != ErrOutOfDomain || !strings.Contains(opErr.Msg, "tangent of 270 degrees is undefined") {
+		t.Errorf("tan(270 degrees): expected an out_of_domain error, got %v", err)
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	for _, s := range []string{"1e1001", "1/3", "Inf", "0x10"} {
+		if _, err := parseDecimal(json.Number(s)); err == nil {
+			t.Errorf("parseDecimal(%q): expected an error", s)
+		}
+	}
+	if x, err := parseDecimal("1.5e2"); err != nil || x.Cmp(rat("150")) != 0 {
+		t.Errorf("parseDecimal(1.5e2) = %v, %v", x, err)
+	}
+}
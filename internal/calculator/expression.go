@@ -0,0 +1,475 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Limits keep a single request from producing an unbounded number of spans
+// or exhausting the stack while parsing.
+const (
+	maxExpressionLength = 1024
+	maxExpressionNodes  = 256
+	maxExpressionDepth  = 64
+)
+
+// SyntaxError is a parse error at a 1-based character position.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// EvalError is an evaluation error in the subexpression starting at Pos.
+// Err is the underlying operation error, if any.
+type EvalError struct {
+	Pos int
+	Msg string
+	Err error
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("evaluation error at position %d: %s", e.Pos, e.Msg)
+}
+
+func (e *EvalError) Unwrap() error { return e.Err }
+
+// ---------------------------------------------------------------------------
+// AST
+// ---------------------------------------------------------------------------
+
+// exprNode is a node of a parsed expression. pos is the 1-based position of
+// the token that introduced it.
+type exprNode interface {
+	pos() int
+	String() string
+}
+
+type numberNode struct {
+	at    int
+	value float64
+}
+
+type variableNode struct {
+	at   int
+	name string
+}
+
+type unaryNode struct {
+	at int
+	op byte
+	x  exprNode
+}
+
+type binaryNode struct {
+	at   int
+	op   byte
+	x, y exprNode
+}
+
+type callNode struct {
+	at   int
+	name string
+	args []exprNode
+}
+
+func (n *numberNode) pos() int   { return n.at }
+func (n *variableNode) pos() int { return n.at }
+func (n *unaryNode) pos() int    { return n.at }
+func (n *binaryNode) pos() int   { return n.at }
+func (n *callNode) pos() int     { return n.at }
+
+func (n *numberNode) String() string   { return strconv.FormatFloat(n.value, 'g', -1, 64) }
+func (n *variableNode) String() string { return n.name }
+func (n *unaryNode) String() string    { return string(n.op) + wrap(n.x) }
+func (n *binaryNode) String() string   { return wrap(n.x) + " " + string(n.op) + " " + wrap(n.y) }
+
+func (n *callNode) String() string {
+	args := make([]string, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.String()
+	}
+	return n.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// wrap parenthesises operator subexpressions so String round-trips.
+func wrap(n exprNode) string {
+	switch n.(type) {
+	case *binaryNode, *unaryNode:
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+// binaryOperators maps each operator to the registered operation it applies.
+var binaryOperators = map[byte]string{
+	'+': "add",
+	'-': "subtract",
+	'*': "multiply",
+	'/': "divide",
+	'^': "power",
+}
+
+// Every registered operation can be called as a function, e.g. sqrt(x) or
+// root(x, 3). functionAliases adds short names for some of them.
+var functionAliases = map[string]string{
+	"pow": "power",
+	"mod": "modulo",
+}
+
+// function is a variadic built-in callable from expressions. It takes at
+// least minArgs arguments.
+type function struct {
+	minArgs int
+	compute func(args []float64) float64
+}
+
+var functions = map[string]function{
+	"min": {1, minOf},
+	"max": {1, maxOf},
+}
+
+// lookupCall resolves a function name to a registered operation, or failing
+// that to a variadic function.
+func lookupCall(name string) (Operation, function, bool) {
+	if alias, ok := functionAliases[name]; ok {
+		name = alias
+	}
+	if op, ok := LookupOperation(name); ok {
+		return op, function{}, true
+	}
+	fn, ok := functions[name]
+	return Operation{}, fn, ok
+}
+
+func minOf(a []float64) float64 {
+	m := a[0]
+	for _, v := range a[1:] {
+		m = math.Min(m, v)
+	}
+	return m
+}
+
+func maxOf(a []float64) float64 {
+	m := a[0]
+	for _, v := range a[1:] {
+		m = math.Max(m, v)
+	}
+	return m
+}
+
+// ---------------------------------------------------------------------------
+// Lexer
+// ---------------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp // + - * / ^
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 1-based
+}
+
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case isDigit(c) || c == '.':
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				j := i + 1
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				if j < len(src) && isDigit(src[j]) {
+					for i = j; i < len(src) && isDigit(src[i]); i++ {
+					}
+				}
+			}
+			toks = append(toks, token{tokNumber, src[start:i], start + 1})
+		case isLetter(c) || c == '$':
+			// "$name" reads a register of the request's session.
+			if c == '$' {
+				if i++; i == len(src) || !isLetter(src[i]) {
+					return nil, &SyntaxError{Pos: start + 1, Msg: "expected a register name after \"$\""}
+				}
+			}
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			toks = append(toks, token{tokIdent, src[start:i], start + 1})
+		case strings.IndexByte("+-*/^", c) >= 0:
+			i++
+			toks = append(toks, token{tokOp, string(c), start + 1})
+		case c == '(':
+			i++
+			toks = append(toks, token{tokLParen, "(", start + 1})
+		case c == ')':
+			i++
+			toks = append(toks, token{tokRParen, ")", start + 1})
+		case c == ',':
+			i++
+			toks = append(toks, token{tokComma, ",", start + 1})
+		default:
+			return nil, &SyntaxError{Pos: start + 1, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src) + 1}), nil
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+// ---------------------------------------------------------------------------
+// Parser
+// ---------------------------------------------------------------------------
+
+// parseExpression parses src with conventional precedence, loosest first:
+// + and -, then * and /, then unary minus, then ^. ^ is right-associative,
+// so 2^3^2 is 2^9 and -2^2 is -4.
+func parseExpression(src string) (exprNode, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, &SyntaxError{Pos: 1, Msg: "empty expression"}
+	}
+	if len(src) > maxExpressionLength {
+		return nil, &SyntaxError{Pos: maxExpressionLength + 1, Msg: fmt.Sprintf("expression longer than %d characters", maxExpressionLength)}
+	}
+
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	n, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t.describe())}
+	}
+	return n, nil
+}
+
+type parser struct {
+	toks  []token
+	i     int
+	nodes int
+	depth int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// node counts every node created so oversized expressions fail early.
+func (p *parser) node(n exprNode) (exprNode, error) {
+	p.nodes++
+	if p.nodes > maxExpressionNodes {
+		return nil, &SyntaxError{Pos: n.pos(), Msg: fmt.Sprintf("expression has more than %d terms", maxExpressionNodes)}
+	}
+	return n, nil
+}
+
+func (p *parser) isOp(ops string) bool {
+	t := p.peek()
+	return t.kind == tokOp && strings.Contains(ops, t.text)
+}
+
+func (p *parser) additive() (exprNode, error) {
+	x, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.next()
+		y, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		if x, err = p.node(&binaryNode{at: op.pos, op: op.text[0], x: x, y: y}); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+func (p *parser) multiplicative() (exprNode, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/") {
+		op := p.next()
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if x, err = p.node(&binaryNode{at: op.pos, op: op.text[0], x: x, y: y}); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+func (p *parser) unary() (exprNode, error) {
+	if !p.isOp("+-") {
+		return p.power()
+	}
+	op := p.next()
+	if err := p.enter(op.pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if op.text == "+" {
+		return x, nil
+	}
+	return p.node(&unaryNode{at: op.pos, op: '-', x: x})
+}
+
+func (p *parser) power() (exprNode, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("^") {
+		return x, nil
+	}
+	op := p.next()
+	if err := p.enter(op.pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	y, err := p.unary() // right-associative; allows 2^-1
+	if err != nil {
+		return nil, err
+	}
+	return p.node(&binaryNode{at: op.pos, op: '^', x: x, y: y})
+}
+
+func (p *parser) primary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil || math.IsInf(v, 0) {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("invalid number %q", t.text)}
+		}
+		return p.node(&numberNode{at: t.pos, value: v})
+
+	case tokIdent:
+		if p.peek().kind == tokLParen && !strings.HasPrefix(t.text, "$") {
+			return p.call(t)
+		}
+		return p.node(&variableNode{at: t.pos, name: t.text})
+
+	case tokLParen:
+		if err := p.enter(t.pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		x, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, &SyntaxError{Pos: c.pos, Msg: fmt.Sprintf("expected \")\" to close \"(\" at position %d, found %s", t.pos, c.describe())}
+		}
+		return x, nil
+	}
+	return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected a number, variable, function or \"(\", found %s", t.describe())}
+}
+
+func (p *parser) call(name token) (exprNode, error) {
+	op, fn, ok := lookupCall(name.text)
+	if !ok {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	open := p.next()
+	if err := p.enter(open.pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	var args []exprNode
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if c := p.next(); c.kind != tokRParen {
+		return nil, &SyntaxError{Pos: c.pos, Msg: fmt.Sprintf("expected \",\" or \")\" in call to %s, found %s", name.text, c.describe())}
+	}
+
+	switch {
+	case op.Name != "":
+		if len(args) != op.Arity {
+			return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("%s takes %d argument(s), got %d", name.text, op.Arity, len(args))}
+		}
+		// Aliases are resolved here so spans and metrics use the
+		// registered name.
+		return p.node(&callNode{at: name.pos, name: op.Name, args: args})
+	case len(args) < fn.minArgs:
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("%s takes at least %d argument(s), got %d", name.text, fn.minArgs, len(args))}
+	}
+	return p.node(&callNode{at: name.pos, name: name.text, args: args})
+}
+
+func (p *parser) enter(pos int) error {
+	p.depth++
+	if p.depth > maxExpressionDepth {
+		return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("expression nested deeper than %d levels", maxExpressionDepth)}
+	}
+	return nil
+}
+
+func (p *parser) leave() { p.depth-- }
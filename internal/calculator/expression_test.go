@@ -0,0 +1,71 @@
+package calculator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 + 2 * 3", "1 + (2 * 3)"},
+		{"(10 + 5) * 3 / 2", "((10 + 5) * 3) / 2"},
+		{"10 - 4 - 3", "(10 - 4) - 3"},
+		{"2 ^ 3 ^ 2", "2 ^ (3 ^ 2)"},
+		{"-2 ^ 2", "-(2 ^ 2)"},
+		{"2 ^ -1", "2 ^ (-1)"},
+		{"--x", "-(-x)"},
+		{"+x", "x"},
+		{"max(a, b * 2, 1e3)", "max(a, b * 2, 1000)"},
+		{"sqrt((x))", "sqrt(x)"},
+		{"pow(2, x)", "power(2, x)"},
+		{"root(x, 3) + factorial(4)", "root(x, 3) + factorial(4)"},
+	}
+
+	for _, tt := range tests {
+		n, err := parseExpression(tt.src)
+		if err != nil {
+			t.Errorf("parseExpression(%q): %v", tt.src, err)
+			continue
+		}
+		if got := n.String(); got != tt.want {
+			t.Errorf("parseExpression(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseExpressionReportsPositions(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{"", 1, "empty expression"},
+		{"1 +", 4, "found end of expression"},
+		{"(1 + 2", 7, `to close "(" at position 1`},
+		{"1 + 2)", 6, `unexpected ")"`},
+		{"2 # 3", 3, "unexpected character"},
+		{"foo(1)", 1, `unknown function "foo"`},
+		{"1 + sqrt(1, 2)", 5, "sqrt takes 1 argument(s), got 2"},
+		{"max()", 1, "at least 1 argument(s)"},
+		{"mod(1)", 1, "mod takes 2 argument(s), got 1"},
+		{"1..2", 1, "invalid number"},
+		{strings.Repeat("(", maxExpressionDepth+1) + "1" + strings.Repeat(")", maxExpressionDepth+1), maxExpressionDepth + 1, "nested deeper"},
+		{strings.Repeat("1+", maxExpressionNodes) + "1", maxExpressionNodes, "more than"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseExpression(tt.src)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("parseExpression(%.20q): expected *SyntaxError, got %v", tt.src, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos || !strings.Contains(syntaxErr.Msg, tt.msg) {
+			t.Errorf("parseExpression(%.20q) = %v, want position %d containing %q", tt.src, err, tt.pos, tt.msg)
+		}
+	}
+}
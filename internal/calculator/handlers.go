@@ -1,12 +1,20 @@
 package calculator
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"go-chi-observability/internal/handlers"
 	"go-chi-observability/internal/observability"
 
@@ -22,44 +30,22 @@ import (
 var tracer = otel.Tracer("calculator")
 
 // ---------------------------------------------------------------------------
-// Handlers — binary operations
+// Handlers — registered operations
 // ---------------------------------------------------------------------------
 
-// Add handles POST /calculator/add
-func Add(w http.ResponseWriter, r *http.Request) {
-	handleBinaryOp(w, r, "add", func(a, b float64) (float64, error) {
-		return a + b, nil
-	})
-}
-
-// Subtract handles POST /calculator/subtract
-func Subtract(w http.ResponseWriter, r *http.Request) {
-	handleBinaryOp(w, r, "subtract", func(a, b float64) (float64, error) {
-		return a - b, nil
-	})
-}
-
-// Multiply handles POST /calculator/multiply
-func Multiply(w http.ResponseWriter, r *http.Request) {
-	handleBinaryOp(w, r, "multiply", func(a, b float64) (float64, error) {
-		return a * b, nil
-	})
-}
-
-// Divide handles POST /calculator/divide — demonstrates error recording on spans.
-func Divide(w http.ResponseWriter, r *http.Request) {
-	handleBinaryOp(w, r, "divide", func(a, b float64) (float64, error) {
-		if b == 0 {
-			return 0, fmt.Errorf("division by zero: %g / %g", a, b)
-		}
-		return a / b, nil
-	})
+// OperationHandler handles POST /calculator/<op.Name>. RegisterRoutes mounts
+// one for every registered operation.
+func OperationHandler(op Operation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleOperation(w, r, op)
+	}
 }
 
-// handleBinaryOp is the shared implementation for all binary calculator operations.
+// handleOperation is the shared implementation for all operation endpoints.
 // It demonstrates: custom child spans, span attributes & events, custom metrics,
 // trace-correlated structured logging, error recording, and request-ID propagation.
-func handleBinaryOp(w http.ResponseWriter, r *http.Request, opName string, compute func(float64, float64) (float64, error)) {
+func handleOperation(w http.ResponseWriter, r *http.Request, op Operation) {
+	opName := op.Name
 	ctx := r.Context()
 	logger := observability.LoggerWithTrace(ctx)
 	requestID := observability.RequestIDFromContext(ctx)
@@ -74,39 +60,92 @@ func handleBinaryOp(w http.ResponseWriter, r *http.Request, opName string, compu
 	defer span.End()
 
 	// --- 2. Decode request body ---
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, opName, "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	precision, err := precisionOf(r, body)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, opName, err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	if precision == PrecisionDecimal {
+		handleDecimalOperation(ctx, span, logger, w, op, body)
+		return
+	}
+
 	var req CalcRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		observability.RecordError(ctx, span, logger, errorCounter, opName, "invalid request body", err, http.StatusBadRequest, w)
 		return
 	}
 
 	// Validate inputs
+	if err := errors.Join(req.Angle.validate(), req.Policy.validate(), validateStore(req.Store, req.Session)); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, opName, err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	sess, err := sessions.get(ctx, req.Session)
+	if err == nil {
+		sessionAttrs(ctx, sess)
+		err = req.resolve(ctx, sess, op.Arity)
+	}
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, opName, err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
 	if math.IsNaN(req.A) || math.IsInf(req.A, 0) || math.IsNaN(req.B) || math.IsInf(req.B, 0) {
 		observability.RecordError(ctx, span, logger, errorCounter, opName, "invalid numeric input", fmt.Errorf("a=%g b=%g", req.A, req.B), http.StatusBadRequest, w)
 		return
 	}
 
 	// Record operands as span attributes
-	span.SetAttributes(
-		attribute.Float64("calculator.operand.a", req.A),
-		attribute.Float64("calculator.operand.b", req.B),
+	span.SetAttributes(attribute.Float64("calculator.operand.a", req.A))
+	if op.Arity == 2 {
+		span.SetAttributes(attribute.Float64("calculator.operand.b", req.B))
+	}
+	if op.Angle {
+		span.SetAttributes(angleUnitAttr(req.Angle))
+	}
+	logger.Debug("calculator request decoded",
+		zap.String("operation", opName),
+		zap.Float64("a", req.A),
+		zap.Float64("b", req.B),
+		zap.String("angle", string(req.Angle)),
+		zap.String("result_policy", string(req.Policy)),
+		zap.String("session", req.Session),
+		zap.String("request_id", requestID),
 	)
 
 	// --- 3. Perform computation (timed for histogram) ---
 	start := time.Now()
-	result, err := compute(req.A, req.B)
+	result, err := calculate(ctx, op, req.Angle, req.Policy, []float64{req.A, req.B}[:op.Arity]...)
 	elapsed := float64(time.Since(start).Microseconds()) / 1000.0 // ms
 
 	if err != nil {
+		setErrorKind(span, err)
 		observability.RecordError(ctx, span, logger, errorCounter, opName, err.Error(), err, http.StatusBadRequest, w)
 		return
 	}
+	if req.Store != "" {
+		if err := sess.store(req.Store, result); err != nil {
+			observability.RecordError(ctx, span, logger, errorCounter, opName, err.Error(), err, http.StatusBadRequest, w)
+			return
+		}
+		span.SetAttributes(attribute.String("calculator.session.stored", req.Store))
+	}
 
 	// --- 4. Record metrics ---
 	attrs := metric.WithAttributes(attribute.String("operation", opName))
 	opsCounter.Add(ctx, 1, attrs)
 	opsHistogram.Record(ctx, elapsed, attrs)
-	resultGauge.Record(ctx, result, attrs)
+	recordResult(ctx, result, attrs)
+	saveHistory(ctx, Calculation{
+		Operation: opName,
+		Operands:  []Number{Number(req.A), Number(req.B)}[:op.Arity],
+		Result:    Number(result),
+	})
 
 	// --- 5. Span event with the result ---
 	span.AddEvent("computation.complete", trace.WithAttributes(
@@ -131,8 +170,58 @@ func handleBinaryOp(w http.ResponseWriter, r *http.Request, opName string, compu
 		Operation: opName,
 		A:         req.A,
 		B:         req.B,
-		Result:    result,
+		Result:    Number(result),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// recordResult sets the last-result gauge. Non-finite results, which
+// PolicyString lets through, are left out.
+func recordResult(ctx context.Context, result float64, attrs metric.MeasurementOption) {
+	if !math.IsNaN(result) && !math.IsInf(result, 0) {
+		resultGauge.Record(ctx, result, attrs)
+	}
+}
+
+// angleUnitAttr records the unit trigonometric operands were given in.
+func angleUnitAttr(unit AngleUnit) attribute.KeyValue {
+	if unit == "" {
+		unit = Radians
+	}
+	return attribute.String("calculator.angle_unit", string(unit))
+}
+
+// setErrorKind tags span with the ErrorKind of an *OperandError, so domain
+// errors (division by zero, sqrt of a negative, ...) can be told apart
+// without parsing messages.
+func setErrorKind(span trace.Span, err error) {
+	if kind := errorKind(err); kind != "" {
+		span.SetAttributes(attribute.String("calculator.error.kind", string(kind)))
 	}
+}
+
+// ListOperations handles GET /calculator/operations — the registry as
+// self-describing documentation.
+func ListOperations(w http.ResponseWriter, r *http.Request) {
+	operators := make(map[string]string, len(binaryOperators))
+	for sym, name := range binaryOperators {
+		operators[name] = string(sym)
+	}
+
+	resp := OperationsResponse{Operations: []OperationInfo{}}
+	for _, op := range Operations() {
+		resp.Operations = append(resp.Operations, OperationInfo{
+			Name:        op.Name,
+			Description: op.Description,
+			Arity:       op.Arity,
+			Endpoint:    "/calculator/" + op.Name,
+			Operator:    operators[op.Name],
+			Decimal:     op.Decimal != nil,
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
@@ -159,22 +248,95 @@ func Chain(w http.ResponseWriter, r *http.Request) {
 	defer span.End()
 
 	// Decode
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "chain", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	precision, err := precisionOf(r, body)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "chain", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	if precision == PrecisionDecimal {
+		decimalChain(ctx, span, logger, w, body)
+		return
+	}
+
 	var req ChainRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		observability.RecordError(ctx, span, logger, errorCounter, "chain", "invalid request body", err, http.StatusBadRequest, w)
 		return
 	}
 
-	if len(req.Steps) == 0 {
-		observability.RecordError(ctx, span, logger, errorCounter, "chain", "no steps provided", fmt.Errorf("steps array is empty"), http.StatusBadRequest, w)
+	if err := req.validate(); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "chain", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	logger.Debug("calculator chain decoded",
+		zap.Float64("initial", req.Initial),
+		zap.Int("steps", len(req.Steps)),
+		zap.String("mode", string(req.Mode)),
+		zap.String("session", req.Session),
+		zap.String("request_id", requestID),
+	)
+
+	resp, err := runChain(ctx, logger, req, nil)
+	if err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validate checks everything about req that does not depend on the steps'
+// results.
+func (req ChainRequest) validate() error {
+	if len(req.Steps) == 0 {
+		return errors.New("no steps provided")
+	}
+	errs := []error{req.Angle.validate(), req.Policy.validate(), req.Mode.validate()}
+	for i, step := range req.Steps {
+		errs = append(errs, step.validate(i, req.Session))
+	}
+	return errors.Join(errs...)
+}
+
+// runChain runs a validated chain on the calculator.chain span in ctx,
+// creating a child span for every step. progress, if set, is called after
+// each step. A failed step is recorded on its span, counted and logged. In
+// ChainAbort mode it is also recorded on the chain span and returned as an
+// error naming the step; in ChainContinue mode the chain goes on and the
+// response reports it. Cancellation of ctx between steps is always an error.
+func runChain(ctx context.Context, logger *zap.Logger, req ChainRequest, progress func(ChainResult)) (ChainResponse, error) {
+	span := trace.SpanFromContext(ctx)
+	requestID := observability.RequestIDFromContext(ctx)
+
 	span.SetAttributes(
 		attribute.Float64("chain.initial", req.Initial),
 		attribute.Int("chain.steps_count", len(req.Steps)),
 	)
 
+	sess, err := sessions.get(ctx, req.Session)
+	if err == nil {
+		sessionAttrs(ctx, sess)
+		if req.register != "" {
+			span.SetAttributes(attribute.String("chain.initial_register", req.register))
+			req.Initial, err = sess.resolve(req.Initial, req.register)
+			span.SetAttributes(attribute.Float64("chain.initial", req.Initial))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		errorCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", "chain")))
+		logger.Error("chain rejected", zap.Error(err), zap.String("request_id", requestID))
+		return ChainResponse{}, err
+	}
+
 	logger.Info("starting chained calculation",
 		zap.Float64("initial", req.Initial),
 		zap.Int("steps", len(req.Steps)),
@@ -183,10 +345,18 @@ func Chain(w http.ResponseWriter, r *http.Request) {
 
 	running := req.Initial
 	results := make([]ChainResult, 0, len(req.Steps))
+	var failed, skipped int
 
 	for i, step := range req.Steps {
+		if err := ctx.Err(); err != nil {
+			err = fmt.Errorf("chain canceled at step %d: %w", i, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, fmt.Sprintf("canceled at step %d", i))
+			return ChainResponse{}, err
+		}
+
 		// --- Child span per step ---
-		_, stepSpan := tracer.Start(ctx, fmt.Sprintf("calculator.chain.step.%d.%s", i, step.Op),
+		stepCtx, stepSpan := tracer.Start(ctx, fmt.Sprintf("calculator.chain.step.%d.%s", i, step.Op),
 			trace.WithAttributes(
 				attribute.Int("chain.step.index", i),
 				attribute.String("chain.step.operation", step.Op),
@@ -196,104 +366,1027 @@ func Chain(w http.ResponseWriter, r *http.Request) {
 		)
 
 		stepStart := time.Now()
-		var err error
 		prev := running
-
-		switch step.Op {
-		case "add":
-			running += step.Value
-		case "subtract":
-			running -= step.Value
-		case "multiply":
-			running *= step.Value
-		case "divide":
-			if step.Value == 0 {
-				err = fmt.Errorf("division by zero at step %d", i)
-			} else {
-				running /= step.Value
-			}
-		default:
-			err = fmt.Errorf("unknown operation %q at step %d", step.Op, i)
-		}
-
+		value, total, skip, err := applyStep(stepCtx, req, sess, results, i, running)
 		stepElapsed := float64(time.Since(stepStart).Microseconds()) / 1000.0
 
-		if err != nil {
+		result := ChainResult{Op: step.Op, Value: value}
+		switch {
+		case err != nil:
+			err = fmt.Errorf("%w at step %d", err, i)
+
 			// Record error on the child step span
 			stepSpan.RecordError(err)
 			stepSpan.SetStatus(codes.Error, err.Error())
+			setErrorKind(stepSpan, err)
 			stepSpan.End()
 
-			// Record error on the parent chain span
-			span.RecordError(err)
-			span.SetStatus(codes.Error, fmt.Sprintf("failed at step %d", i))
-
-			// Metric + log + HTTP response
+			// Metric + log
 			errorCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", step.Op)))
 
 			logger.Error("chain step failed",
 				zap.Int("step", i),
 				zap.String("operation", step.Op),
+				zap.String("error_kind", string(errorKind(err))),
 				zap.Error(err),
 				zap.String("request_id", requestID),
 			)
 
-			handlers.WriteError(w, http.StatusBadRequest, err.Error())
-			return
-		}
+			if req.Mode != ChainContinue {
+				// Record error on the parent chain span
+				span.RecordError(err)
+				span.SetStatus(codes.Error, fmt.Sprintf("failed at step %d", i))
+				setErrorKind(span, err)
+				return ChainResponse{}, err
+			}
 
-		// Record step metrics
-		attrs := metric.WithAttributes(attribute.String("operation", step.Op))
-		opsCounter.Add(ctx, 1, attrs)
-		opsHistogram.Record(ctx, stepElapsed, attrs)
+			failed++
+			span.AddEvent("step.failed", trace.WithAttributes(
+				attribute.Int("index", i),
+				attribute.String("error", err.Error()),
+			))
+			result.Status, result.Result = StepFailed, Number(running)
+			result.Error, result.ErrorKind = err.Error(), errorKind(err)
 
-		stepSpan.AddEvent("step.complete", trace.WithAttributes(
-			attribute.Float64("input", prev),
-			attribute.Float64("result", running),
-		))
-		stepSpan.SetAttributes(attribute.Float64("chain.step.result", running))
-		stepSpan.SetStatus(codes.Ok, "")
-		stepSpan.End()
+		case skip:
+			// Neither Ok nor Error: the step did not run.
+			skipped++
+			stepSpan.SetAttributes(attribute.Bool("chain.step.skipped", true))
+			stepSpan.End()
 
-		logger.Info("chain step completed",
-			zap.Int("step", i),
-			zap.String("operation", step.Op),
-			zap.Float64("input", prev),
-			zap.Float64("value", step.Value),
-			zap.Float64("result", running),
-			zap.Float64("duration_ms", stepElapsed),
-		)
+			logger.Info("chain step skipped",
+				zap.Int("step", i),
+				zap.String("operation", step.Op),
+				zap.String("condition", step.If),
+				zap.Float64("input", prev),
+			)
+			result.Status, result.Result = StepSkipped, Number(running)
 
-		results = append(results, ChainResult{
-			Op:     step.Op,
-			Value:  step.Value,
-			Result: running,
-		})
+		default:
+			running = total
+
+			// Record step metrics
+			attrs := metric.WithAttributes(attribute.String("operation", step.Op))
+			opsCounter.Add(ctx, 1, attrs)
+			opsHistogram.Record(ctx, stepElapsed, attrs)
+
+			stepSpan.AddEvent("step.complete", trace.WithAttributes(
+				attribute.Float64("input", prev),
+				attribute.Float64("result", running),
+			))
+			stepSpan.SetAttributes(attribute.Float64("chain.step.result", running))
+			stepSpan.SetStatus(codes.Ok, "")
+			stepSpan.End()
+
+			logger.Info("chain step completed",
+				zap.Int("step", i),
+				zap.String("operation", step.Op),
+				zap.Float64("input", prev),
+				zap.Float64("value", value),
+				zap.Float64("result", running),
+				zap.Float64("duration_ms", stepElapsed),
+			)
+			result.Status, result.Result = StepSucceeded, Number(running)
+		}
+
+		results = append(results, result)
+		if progress != nil {
+			progress(result)
+		}
 	}
 
 	// Record final result
-	resultGauge.Record(ctx, running, metric.WithAttributes(attribute.String("operation", "chain")))
+	recordResult(ctx, running, metric.WithAttributes(attribute.String("operation", "chain")))
+	saveHistory(ctx, Calculation{Operation: "chain", Operands: []Number{Number(req.Initial)}, Result: Number(running)})
 
 	span.AddEvent("chain.complete", trace.WithAttributes(
 		attribute.Float64("final_result", running),
 		attribute.Int("total_steps", len(req.Steps)),
 	))
-	span.SetAttributes(attribute.Float64("chain.result", running))
-	span.SetStatus(codes.Ok, "")
+	span.SetAttributes(
+		attribute.Float64("chain.result", running),
+		attribute.Int("chain.failed_steps", failed),
+		attribute.Int("chain.skipped_steps", skipped),
+	)
+	status := ChainSucceeded
+	if failed > 0 {
+		status = ChainPartial
+		span.SetStatus(codes.Error, fmt.Sprintf("%d of %d steps failed", failed, len(req.Steps)))
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
 
 	logger.Info("chained calculation completed",
 		zap.Float64("initial", req.Initial),
 		zap.Float64("result", running),
 		zap.Int("steps", len(req.Steps)),
+		zap.Int("failed", failed),
+		zap.Int("skipped", skipped),
 		zap.String("request_id", requestID),
 	)
 
-	resp := ChainResponse{
+	return ChainResponse{
 		Initial: req.Initial,
 		Steps:   results,
-		Result:  running,
+		Result:  Number(running),
+		Status:  status,
+		Failed:  failed,
+		Skipped: skipped,
+	}, nil
+}
+
+// ---------------------------------------------------------------------------
+// Handler — batches (demonstrates span links and a bounded worker pool)
+// ---------------------------------------------------------------------------
+
+// Batch handles POST /calculator/batch — runs many independent operations and
+// chains on a bounded worker pool and returns their results in order. Every
+// item runs on a calculator.batch.item span, a child of the batch span in the
+// same trace, and its wait for a worker is recorded in
+// calculator.batch.queue_wait.
+func Batch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := observability.LoggerWithTrace(ctx)
+	requestID := observability.RequestIDFromContext(ctx)
+
+	ctx, span := tracer.Start(ctx, "calculator.batch",
+		trace.WithAttributes(
+			attribute.String("request.id", requestID),
+		),
+	)
+	defer span.End()
+
+	var req BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			observability.RecordError(ctx, span, logger, errorCounter, "batch", "request body too large", err, http.StatusRequestEntityTooLarge, w)
+			return
+		}
+		observability.RecordError(ctx, span, logger, errorCounter, "batch", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	if err := req.validate(); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "batch", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("batch.size", len(req.Items)))
+	batchSize.Record(ctx, int64(len(req.Items)))
+
+	start := time.Now()
+	results := runBatch(ctx, len(req.Items), func(ctx context.Context, i int) BatchResult {
+		return runBatchItem(ctx, req, i)
+	})
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0 // ms
+
+	resp := BatchResponse{Results: results}
+	for _, res := range results {
+		if res.Error == "" {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("batch.succeeded", resp.Succeeded),
+		attribute.Int("batch.failed", resp.Failed),
+	)
+	if resp.Failed > 0 {
+		span.SetStatus(codes.Error, fmt.Sprintf("%d of %d items failed", resp.Failed, len(results)))
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	opsHistogram.Record(ctx, elapsed, metric.WithAttributes(attribute.String("operation", "batch")))
+
+	logger.Info("batch completed",
+		zap.Int("items", len(results)),
+		zap.Int("succeeded", resp.Succeeded),
+		zap.Int("failed", resp.Failed),
+		zap.String("request_id", requestID),
+		zap.Float64("duration_ms", elapsed),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ---------------------------------------------------------------------------
+// Handlers — asynchronous jobs (demonstrates trace continuity across a queue)
+// ---------------------------------------------------------------------------
+
+// SubmitJob handles POST /calculator/jobs — queues a chain and returns 202
+// with the job's ID. The chain later runs on a worker in a trace linked to
+// this request's.
+func SubmitJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := observability.LoggerWithTrace(ctx)
+	requestID := observability.RequestIDFromContext(ctx)
+
+	ctx, span := tracer.Start(ctx, "calculator.jobs.submit",
+		trace.WithAttributes(
+			attribute.String("request.id", requestID),
+		),
+	)
+	defer span.End()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "jobs", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	if precision, err := precisionOf(r, body); err != nil || precision == PrecisionDecimal {
+		if err == nil {
+			err = errors.New("decimal precision is not supported by /calculator/jobs")
+		}
+		observability.RecordError(ctx, span, logger, errorCounter, "jobs", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	var req ChainRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "jobs", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	if err := req.validate(); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "jobs", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	resp, err := jobs.submit(ctx, req)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "jobs", err.Error(), err, http.StatusServiceUnavailable, w)
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("job.id", resp.ID),
+		attribute.Int("chain.steps_count", len(req.Steps)),
+	)
+	span.SetStatus(codes.Ok, "")
+
+	logger.Info("job queued",
+		zap.String("job_id", resp.ID),
+		zap.Int("steps", len(req.Steps)),
+		zap.String("request_id", requestID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/calculator/jobs/"+resp.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetJob handles GET /calculator/jobs/{id} — the job's status, progress and,
+// once finished, result or error.
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	resp, err := jobs.get(chi.URLParam(r, "id"))
+	if err != nil {
+		handlers.WriteError(w, http.StatusNotFound, err.Error())
+		return
 	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
+
+// CancelJob handles DELETE /calculator/jobs/{id}. A queued job is canceled at
+// once; a running one stops before its next step, so the response may still
+// show it running. Finished jobs give 409.
+func CancelJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := observability.LoggerWithTrace(ctx)
+	id := chi.URLParam(r, "id")
+
+	resp, err := jobs.cancel(ctx, id)
+	switch {
+	case errors.Is(err, errJobNotFound):
+		handlers.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, errJobFinished):
+		handlers.WriteError(w, http.StatusConflict, fmt.Sprintf("%v: %s", err, resp.Status))
+		return
+	}
+
+	logger.Info("job cancellation requested",
+		zap.String("job_id", id),
+		zap.String("status", string(resp.Status)),
+		zap.String("request_id", observability.RequestIDFromContext(ctx)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ---------------------------------------------------------------------------
+// Handlers — sessions (demonstrates state carried across requests)
+// ---------------------------------------------------------------------------
+
+// CreateSession handles POST /calculator/sessions — starts a session whose
+// registers later requests can store results in and read back as "$name".
+func CreateSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := observability.LoggerWithTrace(ctx)
+	requestID := observability.RequestIDFromContext(ctx)
+
+	ctx, span := tracer.Start(ctx, "calculator.sessions.create",
+		trace.WithAttributes(
+			attribute.String("request.id", requestID),
+		),
+	)
+	defer span.End()
+
+	var req SessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		observability.RecordError(ctx, span, logger, errorCounter, "sessions", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	switch {
+	case req.TTLSeconds == 0:
+		ttl = DefaultSessionTTL
+	case req.TTLSeconds < 0 || ttl > MaxSessionTTL:
+		err := fmt.Errorf("ttl_seconds must be between 1 and %d", int(MaxSessionTTL/time.Second))
+		observability.RecordError(ctx, span, logger, errorCounter, "sessions", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	sess, err := sessions.create(ctx, ttl)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "sessions", err.Error(), err, http.StatusServiceUnavailable, w)
+		return
+	}
+	resp := sess.view()
+
+	span.SetAttributes(
+		attribute.String("calculator.session.id", resp.ID),
+		attribute.Int("calculator.session.ttl_seconds", resp.TTLSeconds),
+	)
+	span.SetStatus(codes.Ok, "")
+
+	logger.Info("session created",
+		zap.String("session_id", resp.ID),
+		zap.Duration("ttl", ttl),
+		zap.String("request_id", requestID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/calculator/sessions/"+resp.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetSession handles GET /calculator/sessions/{id} — the session's registers
+// and expiry, which the lookup extends.
+func GetSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := sessions.get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		handlers.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sess.view())
+}
+
+// DeleteSession handles DELETE /calculator/sessions/{id}.
+func DeleteSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	if err := sessions.delete(ctx, id); err != nil {
+		handlers.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	observability.LoggerWithTrace(ctx).Info("session deleted",
+		zap.String("session_id", id),
+		zap.String("request_id", observability.RequestIDFromContext(ctx)),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---------------------------------------------------------------------------
+// Handler — calculation history (demonstrates database client spans)
+// ---------------------------------------------------------------------------
+
+// History handles GET /calculator/history — stored calculations, newest
+// first, filtered by ?operation=, ?from= and ?to= (RFC 3339) and paged with
+// ?limit= and ?cursor=.
+func History(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := observability.LoggerWithTrace(ctx)
+
+	ctx, span := tracer.Start(ctx, "calculator.history",
+		trace.WithAttributes(
+			attribute.String("request.id", observability.RequestIDFromContext(ctx)),
+		),
+	)
+	defer span.End()
+
+	q, err := historyQuery(r)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "history", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	repo, err := historyRepository()
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "history", "calculation history is unavailable", err, http.StatusServiceUnavailable, w)
+		return
+	}
+	page, err := repo.List(ctx, q)
+	switch {
+	case errors.Is(err, errInvalidCursor):
+		observability.RecordError(ctx, span, logger, errorCounter, "history", err.Error(), err, http.StatusBadRequest, w)
+		return
+	case err != nil:
+		observability.RecordError(ctx, span, logger, errorCounter, "history", "listing calculation history failed", err, http.StatusInternalServerError, w)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("calculator.history.rows", len(page.Calculations)))
+	span.SetStatus(codes.Ok, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// historyQuery reads the query parameters of GET /calculator/history.
+func historyQuery(r *http.Request) (HistoryQuery, error) {
+	params := r.URL.Query()
+	q := HistoryQuery{
+		Operation: params.Get("operation"),
+		Cursor:    params.Get("cursor"),
+		Limit:     DefaultHistoryLimit,
+	}
+
+	var err error
+	for name, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := params.Get(name); v != "" {
+			if *t, err = time.Parse(time.RFC3339Nano, v); err != nil {
+				return q, fmt.Errorf("invalid %s: expected an RFC 3339 time, got %q", name, v)
+			}
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, errors.New("from must be before to")
+	}
+
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 || q.Limit > MaxHistoryLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", MaxHistoryLimit)
+		}
+	}
+	return q, nil
+}
+
+// ---------------------------------------------------------------------------
+// Decimal precision — the same endpoints and telemetry on exact decimals
+// ---------------------------------------------------------------------------
+
+// handleDecimalOperation finishes handleOperation for ?precision=decimal.
+// Operands and the result are strings; spans and metrics carry float64
+// approximations, and calculator.decimal.result the exact text.
+func handleDecimalOperation(ctx context.Context, span trace.Span, logger *zap.Logger, w http.ResponseWriter, op Operation, body []byte) {
+	opName := op.Name
+	requestID := observability.RequestIDFromContext(ctx)
+
+	var req DecimalCalcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, opName, "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	if err := req.validate(); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, opName, err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	operands := []json.Number{req.A, req.B}[:op.Arity]
+	args := make([]*big.Rat, len(operands))
+	for i, n := range operands {
+		x, err := parseDecimal(n)
+		if err != nil {
+			observability.RecordError(ctx, span, logger, errorCounter, opName, "invalid numeric input", err, http.StatusBadRequest, w)
+			return
+		}
+		args[i] = x
+		f, _ := x.Float64()
+		span.SetAttributes(attribute.Float64("calculator.operand."+string(rune('a'+i)), f))
+	}
+	span.SetAttributes(decimalAttrs(req.DecimalOptions)...)
+	if op.Angle {
+		span.SetAttributes(angleUnitAttr(req.Angle))
+	}
+	logger.Debug("calculator request decoded",
+		zap.String("operation", opName),
+		zap.String("a", string(req.A)),
+		zap.String("b", string(req.B)),
+		zap.String("angle", string(req.Angle)),
+		zap.String("precision", string(PrecisionDecimal)),
+		zap.Int("scale", req.computeScale()),
+		zap.String("rounding", string(req.Rounding)),
+		zap.String("request_id", requestID),
+	)
+
+	start := time.Now()
+	exact, err := op.applyDecimal(req.Angle, req.computeScale(), args...)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0 // ms
+
+	if err != nil {
+		setErrorKind(span, err)
+		observability.RecordError(ctx, span, logger, errorCounter, opName, err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	scale := req.scaleFor(exact)
+	text := roundDecimal(exact, scale, req.Rounding).FloatString(scale)
+	approx, _ := exact.Float64()
+
+	attrs := metric.WithAttributes(attribute.String("operation", opName))
+	opsCounter.Add(ctx, 1, attrs)
+	opsHistogram.Record(ctx, elapsed, attrs)
+	recordResult(ctx, approx, attrs)
+	saved := Calculation{Operation: opName, Result: Number(approx), Decimal: text}
+	for _, x := range args {
+		f, _ := x.Float64()
+		saved.Operands = append(saved.Operands, Number(f))
+	}
+	saveHistory(ctx, saved)
+
+	span.AddEvent("computation.complete", trace.WithAttributes(
+		attribute.String("result", text),
+		attribute.Float64("duration_ms", elapsed),
+	))
+	span.SetAttributes(
+		attribute.Float64("calculator.result", approx),
+		attribute.String("calculator.decimal.result", text),
+	)
+	span.SetStatus(codes.Ok, "")
+
+	resp := DecimalCalcResponse{
+		Operation: opName,
+		A:         formatExact(args[0]),
+		Result:    text,
+		Precision: PrecisionDecimal,
+		Rounding:  req.Rounding,
+	}
+	if op.Arity == 2 {
+		resp.B = formatExact(args[1])
+	}
+
+	logger.Info("calculator operation completed",
+		zap.String("operation", opName),
+		zap.String("a", resp.A),
+		zap.String("b", resp.B),
+		zap.String("result", text),
+		zap.String("precision", string(PrecisionDecimal)),
+		zap.String("request_id", requestID),
+		zap.Float64("duration_ms", elapsed),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// decimalChain finishes Chain for ?precision=decimal. Each step's result is
+// rounded to the request's scale before the next step uses it, and is what
+// the response reports.
+func decimalChain(ctx context.Context, span trace.Span, logger *zap.Logger, w http.ResponseWriter, body []byte) {
+	requestID := observability.RequestIDFromContext(ctx)
+
+	var req DecimalChainRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "chain", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	if len(req.Steps) == 0 {
+		observability.RecordError(ctx, span, logger, errorCounter, "chain", "no steps provided", fmt.Errorf("steps array is empty"), http.StatusBadRequest, w)
+		return
+	}
+	if err := errors.Join(req.validate(), req.Angle.validate(), req.validateSteps()); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "chain", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	running, err := parseDecimal(req.Initial)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "chain", "invalid numeric input", err, http.StatusBadRequest, w)
+		return
+	}
+
+	initial := formatExact(running)
+	initialApprox, _ := running.Float64()
+	span.SetAttributes(
+		attribute.Float64("chain.initial", initialApprox),
+		attribute.Int("chain.steps_count", len(req.Steps)),
+	)
+	span.SetAttributes(decimalAttrs(req.DecimalOptions)...)
+
+	logger.Info("starting chained calculation",
+		zap.String("initial", initial),
+		zap.Int("steps", len(req.Steps)),
+		zap.String("precision", string(PrecisionDecimal)),
+		zap.String("request_id", requestID),
+	)
+
+	results := make([]DecimalChainResult, 0, len(req.Steps))
+	text := initial
+
+	for i, step := range req.Steps {
+		input, _ := running.Float64()
+		_, stepSpan := tracer.Start(ctx, fmt.Sprintf("calculator.chain.step.%d.%s", i, step.Op),
+			trace.WithAttributes(
+				attribute.Int("chain.step.index", i),
+				attribute.String("chain.step.operation", step.Op),
+				attribute.Float64("chain.step.input", input),
+			),
+		)
+
+		stepStart := time.Now()
+		var exact *big.Rat
+
+		op, ok := LookupOperation(step.Op)
+		value, err := parseDecimal(step.Value)
+		if err == nil {
+			v, _ := value.Float64()
+			stepSpan.SetAttributes(attribute.Float64("chain.step.value", v))
+		}
+		switch {
+		case !ok:
+			err = fmt.Errorf("unknown operation %q at step %d", step.Op, i)
+		case err != nil:
+			err = fmt.Errorf("%w at step %d", err, i)
+		default:
+			if op.Angle {
+				stepSpan.SetAttributes(angleUnitAttr(req.Angle))
+			}
+			if exact, err = op.applyDecimal(req.Angle, req.computeScale(), []*big.Rat{running, value}[:op.Arity]...); err != nil {
+				err = fmt.Errorf("%w at step %d", err, i)
+			}
+		}
+
+		stepElapsed := float64(time.Since(stepStart).Microseconds()) / 1000.0
+
+		if err != nil {
+			stepSpan.RecordError(err)
+			stepSpan.SetStatus(codes.Error, err.Error())
+			setErrorKind(stepSpan, err)
+			stepSpan.End()
+
+			span.RecordError(err)
+			span.SetStatus(codes.Error, fmt.Sprintf("failed at step %d", i))
+			setErrorKind(span, err)
+
+			errorCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", step.Op)))
+
+			logger.Error("chain step failed",
+				zap.Int("step", i),
+				zap.String("operation", step.Op),
+				zap.String("error_kind", string(errorKind(err))),
+				zap.Error(err),
+				zap.String("request_id", requestID),
+			)
+
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		prev := text
+		scale := req.scaleFor(exact)
+		running = roundDecimal(exact, scale, req.Rounding)
+		text = running.FloatString(scale)
+
+		attrs := metric.WithAttributes(attribute.String("operation", step.Op))
+		opsCounter.Add(ctx, 1, attrs)
+		opsHistogram.Record(ctx, stepElapsed, attrs)
+
+		stepSpan.AddEvent("step.complete", trace.WithAttributes(
+			attribute.String("input", prev),
+			attribute.String("result", text),
+		))
+		approx, _ := running.Float64()
+		stepSpan.SetAttributes(
+			attribute.Float64("chain.step.result", approx),
+			attribute.String("calculator.decimal.result", text),
+		)
+		stepSpan.SetStatus(codes.Ok, "")
+		stepSpan.End()
+
+		logger.Info("chain step completed",
+			zap.Int("step", i),
+			zap.String("operation", step.Op),
+			zap.String("input", prev),
+			zap.String("value", string(step.Value)),
+			zap.String("result", text),
+			zap.Float64("duration_ms", stepElapsed),
+		)
+
+		result := DecimalChainResult{Op: step.Op, Result: text}
+		if op.Arity == 2 {
+			result.Value = formatExact(value)
+		}
+		results = append(results, result)
+	}
+
+	approx, _ := running.Float64()
+	recordResult(ctx, approx, metric.WithAttributes(attribute.String("operation", "chain")))
+	saveHistory(ctx, Calculation{
+		Operation: "chain",
+		Operands:  []Number{Number(initialApprox)},
+		Result:    Number(approx),
+		Decimal:   text,
+	})
+
+	span.AddEvent("chain.complete", trace.WithAttributes(
+		attribute.String("final_result", text),
+		attribute.Int("total_steps", len(req.Steps)),
+	))
+	span.SetAttributes(
+		attribute.Float64("chain.result", approx),
+		attribute.String("calculator.decimal.result", text),
+	)
+	span.SetStatus(codes.Ok, "")
+
+	logger.Info("chained calculation completed",
+		zap.String("initial", initial),
+		zap.String("result", text),
+		zap.Int("steps", len(req.Steps)),
+		zap.String("precision", string(PrecisionDecimal)),
+		zap.String("request_id", requestID),
+	)
+
+	resp := DecimalChainResponse{
+		Initial:   initial,
+		Steps:     results,
+		Result:    text,
+		Precision: PrecisionDecimal,
+		Rounding:  req.Rounding,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// decimalAttrs describes decimal options as span attributes.
+func decimalAttrs(opts DecimalOptions) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("calculator.precision", string(PrecisionDecimal)),
+		attribute.String("calculator.decimal.rounding", string(opts.Rounding)),
+	}
+	if opts.Scale != nil {
+		attrs = append(attrs, attribute.Int("calculator.decimal.scale", *opts.Scale))
+	}
+	return attrs
+}
+
+// ---------------------------------------------------------------------------
+// Handler — expression evaluation (demonstrates a span per AST subtree)
+// ---------------------------------------------------------------------------
+
+// Evaluate handles POST /calculator/evaluate — parses an infix expression and
+// evaluates it with a child span for every operator and function call, so the
+// trace mirrors the shape of the expression.
+func Evaluate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := observability.LoggerWithTrace(ctx)
+	requestID := observability.RequestIDFromContext(ctx)
+
+	ctx, span := tracer.Start(ctx, "calculator.evaluate",
+		trace.WithAttributes(
+			attribute.String("request.id", requestID),
+		),
+	)
+	defer span.End()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "evaluate", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+	if precision, err := precisionOf(r, body); err != nil || precision == PrecisionDecimal {
+		if err == nil {
+			err = errors.New("decimal precision is not supported by /calculator/evaluate")
+		}
+		observability.RecordError(ctx, span, logger, errorCounter, "evaluate", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	var req EvaluateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "evaluate", "invalid request body", err, http.StatusBadRequest, w)
+		return
+	}
+
+	if err := errors.Join(req.Angle.validate(), req.Policy.validate(), validateStore(req.Store, req.Session)); err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "evaluate", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	sess, err := sessions.get(ctx, req.Session)
+	if err != nil {
+		observability.RecordError(ctx, span, logger, errorCounter, "evaluate", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	sessionAttrs(ctx, sess)
+
+	for name, v := range req.Variables {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			observability.RecordError(ctx, span, logger, errorCounter, "evaluate", "invalid numeric input", fmt.Errorf("variable %s=%g", name, v), http.StatusBadRequest, w)
+			return
+		}
+	}
+
+	tree, err := parseExpression(req.Expression)
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			span.SetAttributes(attribute.Int("calculator.expression.error_position", syntaxErr.Pos))
+		}
+		observability.RecordError(ctx, span, logger, errorCounter, "evaluate", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+
+	expression := tree.String()
+	span.SetAttributes(
+		attribute.String("calculator.expression", expression),
+		attribute.Int("calculator.expression.variables", len(req.Variables)),
+	)
+
+	start := time.Now()
+	ev := &evaluator{vars: req.Variables, session: sess, angle: req.Angle, policy: req.Policy, recordTrace: req.Trace}
+	result, err := ev.eval(ctx, tree)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0 // ms
+
+	if err != nil {
+		var evalErr *EvalError
+		if errors.As(err, &evalErr) {
+			span.SetAttributes(attribute.Int("calculator.expression.error_position", evalErr.Pos))
+		}
+		setErrorKind(span, err)
+		observability.RecordError(ctx, span, logger, errorCounter, "evaluate", err.Error(), err, http.StatusBadRequest, w)
+		return
+	}
+	if req.Store != "" {
+		if err := sess.store(req.Store, result); err != nil {
+			observability.RecordError(ctx, span, logger, errorCounter, "evaluate", err.Error(), err, http.StatusBadRequest, w)
+			return
+		}
+		span.SetAttributes(attribute.String("calculator.session.stored", req.Store))
+	}
+
+	attrs := metric.WithAttributes(attribute.String("operation", "evaluate"))
+	opsHistogram.Record(ctx, elapsed, attrs)
+	recordResult(ctx, result, attrs)
+	saveHistory(ctx, Calculation{Operation: "evaluate", Expression: expression, Result: Number(result)})
+
+	span.SetAttributes(attribute.Float64("calculator.result", result))
+	span.SetStatus(codes.Ok, "")
+
+	logger.Info("expression evaluated",
+		zap.String("expression", expression),
+		zap.Float64("result", result),
+		zap.Int("nodes", ev.nodes),
+		zap.String("request_id", requestID),
+		zap.Float64("duration_ms", elapsed),
+	)
+
+	resp := EvaluateResponse{
+		Expression: expression,
+		Result:     Number(result),
+		Trace:      ev.trace,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// evaluator walks a parsed expression. Operator and call nodes each get a
+// child span of their parent node's span and count as one operation.
+type evaluator struct {
+	vars        map[string]float64
+	session     *session // supplies $name registers; nil without a session
+	angle       AngleUnit
+	policy      ResultPolicy
+	recordTrace bool
+	trace       []EvaluationStep
+	nodes       int
+}
+
+func (ev *evaluator) eval(ctx context.Context, n exprNode) (float64, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return n.value, nil
+	case *variableNode:
+		if register, ok := strings.CutPrefix(n.name, "$"); ok {
+			v, err := ev.session.resolve(0, register)
+			if err != nil {
+				return 0, &EvalError{Pos: n.at, Msg: err.Error(), Err: err}
+			}
+			return v, nil
+		}
+		v, ok := ev.vars[n.name]
+		if !ok {
+			return 0, &EvalError{Pos: n.at, Msg: fmt.Sprintf("undefined variable %q", n.name)}
+		}
+		return v, nil
+	}
+
+	var opName string
+	switch n := n.(type) {
+	case *unaryNode:
+		opName = "negate"
+	case *binaryNode:
+		opName = binaryOperators[n.op]
+	case *callNode:
+		opName = n.name
+	}
+
+	ctx, span := tracer.Start(ctx, fmt.Sprintf("calculator.evaluate.%s", opName),
+		trace.WithAttributes(
+			attribute.String("calculator.operation", opName),
+			attribute.String("calculator.expression.node", n.String()),
+			attribute.Int("calculator.expression.position", n.pos()),
+		),
+	)
+	defer span.End()
+	if op, ok := LookupOperation(opName); ok && op.Angle {
+		span.SetAttributes(angleUnitAttr(ev.angle))
+	}
+
+	start := time.Now()
+	result, err := ev.apply(ctx, n)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0
+
+	if err != nil {
+		// Only the node where the error originated records it; ancestors
+		// are marked failed so the path to it stands out in the trace.
+		var evalErr *EvalError
+		if !errors.As(err, &evalErr) {
+			err = &EvalError{Pos: n.pos(), Msg: err.Error(), Err: err}
+			span.RecordError(err)
+			setErrorKind(span, err)
+		} else if evalErr.Pos == n.pos() {
+			span.RecordError(err)
+			setErrorKind(span, err)
+		}
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	ev.nodes++
+	attrs := metric.WithAttributes(attribute.String("operation", opName))
+	opsCounter.Add(ctx, 1, attrs)
+	opsHistogram.Record(ctx, elapsed, attrs)
+
+	span.SetAttributes(attribute.Float64("calculator.expression.result", result))
+	span.SetStatus(codes.Ok, "")
+
+	if ev.recordTrace {
+		ev.trace = append(ev.trace, EvaluationStep{Expression: n.String(), Position: n.pos(), Result: Number(result)})
+	}
+	return result, nil
+}
+
+// apply evaluates the operands of an operator or call node, then the node.
+func (ev *evaluator) apply(ctx context.Context, n exprNode) (float64, error) {
+	switch n := n.(type) {
+	case *unaryNode:
+		x, err := ev.eval(ctx, n.x)
+		if err != nil {
+			return 0, err
+		}
+		return -x, nil
+
+	case *binaryNode:
+		x, err := ev.eval(ctx, n.x)
+		if err != nil {
+			return 0, err
+		}
+		y, err := ev.eval(ctx, n.y)
+		if err != nil {
+			return 0, err
+		}
+		op, _ := LookupOperation(binaryOperators[n.op])
+		return calculate(ctx, op, ev.angle, ev.policy, x, y)
+
+	case *callNode:
+		args := make([]float64, len(n.args))
+		for i, a := range n.args {
+			v, err := ev.eval(ctx, a)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		if op, ok := LookupOperation(n.name); ok {
+			return calculate(ctx, op, ev.angle, ev.policy, args...)
+		}
+		return functions[n.name].compute(args), nil
+	}
+	return 0, fmt.Errorf("unexpected node %T", n)
+}
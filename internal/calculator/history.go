@@ -0,0 +1,321 @@
+package calculator
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-chi-observability/internal/observability"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultHistoryLimit and MaxHistoryLimit bound the page size of
+	// GET /calculator/history.
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 500
+
+	// maxMemoryHistory is the number of calculations the history keeps, in
+	// memory or in CALCULATOR_HISTORY_FILE; the oldest are dropped first.
+	maxMemoryHistory = 10_000
+
+	// historyCollection is the db.collection.name of repository spans.
+	historyCollection = "calculations"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Repository stores the calculation history.
+type Repository interface {
+	// Save stores c, which already has its ID and CreatedAt set.
+	Save(ctx context.Context, c Calculation) error
+	// List returns the calculations matching q, newest first.
+	List(ctx context.Context, q HistoryQuery) (HistoryPage, error)
+	Close() error
+}
+
+// HistoryQuery filters and pages the history. Zero fields do not filter.
+type HistoryQuery struct {
+	Operation string
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+	Cursor    string    // HistoryPage.NextCursor of the previous page
+	Limit     int       // DefaultHistoryLimit when zero
+}
+
+func (q HistoryQuery) matches(c Calculation) bool {
+	return (q.Operation == "" || c.Operation == q.Operation) &&
+		(q.From.IsZero() || !c.CreatedAt.Before(q.From)) &&
+		(q.To.IsZero() || c.CreatedAt.Before(q.To))
+}
+
+// queryText renders q as a parameterised query for the db.query.text span
+// attribute; the values themselves are separate attributes.
+func (q HistoryQuery) queryText() string {
+	var where []string
+	if q.Operation != "" {
+		where = append(where, "operation = ?")
+	}
+	if !q.From.IsZero() {
+		where = append(where, "created_at >= ?")
+	}
+	if !q.To.IsZero() {
+		where = append(where, "created_at < ?")
+	}
+	if q.Cursor != "" {
+		where = append(where, "seq < ?")
+	}
+	text := "SELECT * FROM " + historyCollection
+	if len(where) > 0 {
+		text += " WHERE " + strings.Join(where, " AND ")
+	}
+	return text + " ORDER BY seq DESC LIMIT ?"
+}
+
+// Cursors are opaque to clients; they encode the sequence number of the last
+// calculation on the previous page.
+func encodeCursor(seq uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(seq, 10)))
+}
+
+func decodeCursor(cursor string) (uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	seq, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	return seq, nil
+}
+
+// ---------------------------------------------------------------------------
+// In-memory repository
+// ---------------------------------------------------------------------------
+
+// MemoryRepository keeps the history in memory, so it is lost on restart.
+type MemoryRepository struct {
+	limit int // 0 keeps everything
+
+	mu    sync.RWMutex
+	seq   uint64
+	items []storedCalculation // oldest first
+}
+
+type storedCalculation struct {
+	seq uint64
+	Calculation
+}
+
+// NewMemoryRepository returns an empty repository that keeps the latest
+// limit calculations, or all of them when limit is 0.
+func NewMemoryRepository(limit int) *MemoryRepository {
+	return &MemoryRepository{limit: limit}
+}
+
+func (r *MemoryRepository) Save(_ context.Context, c Calculation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	r.items = append(r.items, storedCalculation{seq: r.seq, Calculation: c})
+	if r.limit > 0 && len(r.items) > r.limit {
+		r.items = append(r.items[:0], r.items[len(r.items)-r.limit:]...)
+	}
+	return nil
+}
+
+// all returns every stored calculation, oldest first.
+func (r *MemoryRepository) all() []Calculation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]Calculation, len(r.items))
+	for i, item := range r.items {
+		all[i] = item.Calculation
+	}
+	return all
+}
+
+func (r *MemoryRepository) List(_ context.Context, q HistoryQuery) (HistoryPage, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	end := len(r.items)
+	if q.Cursor != "" {
+		seq, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+		end = sort.Search(len(r.items), func(i int) bool { return r.items[i].seq >= seq })
+	}
+
+	page := HistoryPage{Calculations: []Calculation{}}
+	var last uint64
+	for i := end - 1; i >= 0; i-- {
+		item := r.items[i]
+		if !q.matches(item.Calculation) {
+			continue
+		}
+		if len(page.Calculations) == limit {
+			// There is at least one more match; the next page starts after
+			// the last calculation of this one.
+			page.NextCursor = encodeCursor(last)
+			break
+		}
+		page.Calculations = append(page.Calculations, item.Calculation)
+		last = item.seq
+	}
+	return page, nil
+}
+
+func (r *MemoryRepository) Close() error { return nil }
+
+// ---------------------------------------------------------------------------
+// Instrumentation
+// ---------------------------------------------------------------------------
+
+// instrumentedRepository wraps a Repository with client spans following the
+// OpenTelemetry database conventions, so history reads and writes show up in
+// traces like calls to any other store.
+type instrumentedRepository struct {
+	next   Repository
+	system string // db.system: "memory" or "file"
+}
+
+func (r instrumentedRepository) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation+" "+historyCollection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(r.system),
+			semconv.DBCollectionNameKey.String(historyCollection),
+			semconv.DBOperationNameKey.String(operation),
+		),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (r instrumentedRepository) Save(ctx context.Context, c Calculation) error {
+	ctx, span := r.start(ctx, "INSERT",
+		semconv.DBQueryTextKey.String("INSERT INTO "+historyCollection+" VALUES (?)"),
+		attribute.String("calculator.history.id", c.ID),
+		attribute.String("calculator.operation", c.Operation),
+	)
+	defer span.End()
+
+	if err := r.next.Save(ctx, c); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
+
+func (r instrumentedRepository) List(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	attrs := []attribute.KeyValue{
+		semconv.DBQueryTextKey.String(q.queryText()),
+		attribute.Int("calculator.history.limit", q.Limit),
+	}
+	if q.Operation != "" {
+		attrs = append(attrs, attribute.String("calculator.operation", q.Operation))
+	}
+	ctx, span := r.start(ctx, "SELECT", attrs...)
+	defer span.End()
+
+	page, err := r.next.List(ctx, q)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return page, err
+	}
+	span.SetAttributes(
+		attribute.Int("calculator.history.rows", len(page.Calculations)),
+		attribute.Bool("calculator.history.has_more", page.NextCursor != ""),
+	)
+	span.SetStatus(codes.Ok, "")
+	return page, nil
+}
+
+func (r instrumentedRepository) Close() error { return r.next.Close() }
+
+// ---------------------------------------------------------------------------
+// Process-wide history
+// ---------------------------------------------------------------------------
+
+var (
+	history     Repository
+	historyOnce sync.Once
+	historyErr  error
+)
+
+// historyRepository returns the process-wide history, opening it on first
+// use: the file named by CALCULATOR_HISTORY_FILE when set, else memory.
+func historyRepository() (Repository, error) {
+	historyOnce.Do(func() {
+		path := os.Getenv("CALCULATOR_HISTORY_FILE")
+		if path == "" {
+			history = instrumentedRepository{next: NewMemoryRepository(maxMemoryHistory), system: "memory"}
+			return
+		}
+
+		repo, err := OpenFileRepository(path, maxMemoryHistory)
+		if err != nil {
+			historyErr = fmt.Errorf("open calculation history: %w", err)
+			return
+		}
+		history = instrumentedRepository{next: repo, system: "file"}
+	})
+	return history, historyErr
+}
+
+// closeHistory closes the history if it was opened; it is not opened after.
+func closeHistory() error {
+	historyOnce.Do(func() { historyErr = errors.New("calculation history is closed") })
+	if history == nil {
+		return nil
+	}
+	return history.Close()
+}
+
+// saveHistory stores a successful calculation under the request and trace in
+// ctx; without a valid span context the trace ID is left empty. History is
+// best effort: a failure is logged, never returned to the client.
+func saveHistory(ctx context.Context, c Calculation) {
+	repo, err := historyRepository()
+	if err == nil {
+		c.ID = uuid.New().String()
+		c.RequestID = observability.RequestIDFromContext(ctx)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			c.TraceID = sc.TraceID().String()
+		}
+		c.CreatedAt = time.Now().UTC()
+		err = repo.Save(ctx, c)
+	}
+	if err != nil {
+		observability.LoggerWithTrace(ctx).Warn("calculation not saved to history",
+			zap.String("operation", c.Operation),
+			zap.Error(err),
+		)
+	}
+}
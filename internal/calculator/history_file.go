@@ -0,0 +1,163 @@
+package calculator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileRepository keeps the history in a JSON Lines file, one calculation per
+// line, so it survives restarts without an external database. The file is
+// read into memory when opened and appended to on every Save.
+//
+// Like MemoryRepository it keeps the latest limit calculations. The file is
+// compacted down to them when opened and whenever it has grown to twice the
+// limit, so neither memory nor disk grows without bound.
+type FileRepository struct {
+	path  string
+	limit int // 0 keeps everything
+
+	mu    sync.Mutex // serialises appends and compaction
+	file  *os.File
+	lines int               // calculations in the file, including ones no longer indexed
+	mem   *MemoryRepository // index serving List
+}
+
+// OpenFileRepository opens or creates the history file at path, keeping the
+// latest limit calculations, or all of them when limit is 0. A last line cut
+// short by a crash is discarded.
+func OpenFileRepository(path string, limit int) (*FileRepository, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileRepository{path: path, limit: limit, file: f, mem: NewMemoryRepository(limit)}
+	if err := r.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if limit > 0 && r.lines > limit {
+		if err := r.compactLocked(); err != nil {
+			r.file.Close()
+			return nil, fmt.Errorf("compact %s: %w", path, err)
+		}
+	}
+	return r, nil
+}
+
+// load reads every complete line into the index and leaves the file offset
+// at its end, truncating an incomplete last line.
+func (r *FileRepository) load() error {
+	reader := bufio.NewReader(r.file)
+	var offset int64
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(b) > 0 {
+				if err := r.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(b))
+
+		if b = bytes.TrimSpace(b); len(b) == 0 {
+			continue
+		}
+		var c Calculation
+		if err := json.Unmarshal(b, &c); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		r.mem.Save(context.Background(), c)
+		r.lines++
+	}
+
+	_, err := r.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (r *FileRepository) Save(ctx context.Context, c Calculation) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	r.lines++
+	if err := r.mem.Save(ctx, c); err != nil {
+		return err
+	}
+
+	if r.limit > 0 && r.lines >= 2*r.limit {
+		if err := r.compactLocked(); err != nil {
+			return fmt.Errorf("compact %s: %w", r.path, err)
+		}
+	}
+	return nil
+}
+
+// compactLocked replaces the file with one holding only the indexed
+// calculations. The new file is written beside it and renamed over it, so a
+// crash leaves one or the other. The caller holds r.mu.
+func (r *FileRepository) compactLocked() error {
+	all := r.mem.all()
+
+	tmp := r.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, c := range all {
+		if err = enc.Encode(c); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, r.path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	r.file.Close()
+	r.file = f
+	r.lines = len(all)
+	return nil
+}
+
+func (r *FileRepository) List(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	return r.mem.List(ctx, q)
+}
+
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
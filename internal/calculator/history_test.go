@@ -0,0 +1,243 @@
+package calculator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// saveCalculations stores n calculations one second apart, alternating
+// between add and multiply, and returns their start time.
+func saveCalculations(t *testing.T, repo Repository, n int) time.Time {
+	t.Helper()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range n {
+		op := "add"
+		if i%2 == 1 {
+			op = "multiply"
+		}
+		c := Calculation{
+			ID:        string(rune('a' + i)),
+			Operation: op,
+			Result:    Number(i),
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+		}
+		if err := repo.Save(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return start
+}
+
+func ids(page HistoryPage) string {
+	var s string
+	for _, c := range page.Calculations {
+		s += c.ID
+	}
+	return s
+}
+
+func TestMemoryRepositoryListFiltersAndPages(t *testing.T) {
+	repo := NewMemoryRepository(0)
+	start := saveCalculations(t, repo, 10) // a..j
+
+	tests := []struct {
+		name  string
+		query HistoryQuery
+		want  string
+	}{
+		{"all, newest first", HistoryQuery{}, "jihgfedcba"},
+		{"operation", HistoryQuery{Operation: "multiply"}, "jhfdb"},
+		{"time range", HistoryQuery{From: start.Add(2 * time.Second), To: start.Add(5 * time.Second)}, "edc"},
+		{"operation and time range", HistoryQuery{Operation: "add", From: start.Add(2 * time.Second)}, "igec"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.List(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(page); got != tt.want || page.NextCursor != "" {
+				t.Errorf("got %q (cursor %q), want %q", got, page.NextCursor, tt.want)
+			}
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		q := HistoryQuery{Operation: "add", Limit: 2}
+		var got []string
+		for {
+			page, err := repo.List(context.Background(), q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, ids(page))
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+		if len(got) != 3 || got[0] != "ig" || got[1] != "ec" || got[2] != "a" {
+			t.Errorf("unexpected pages %q", got)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		if _, err := repo.List(context.Background(), HistoryQuery{Cursor: "!"}); !errors.Is(err, errInvalidCursor) {
+			t.Errorf("expected errInvalidCursor, got %v", err)
+		}
+	})
+}
+
+func TestMemoryRepositoryKeepsLatest(t *testing.T) {
+	repo := NewMemoryRepository(3)
+	saveCalculations(t, repo, 5)
+
+	page, err := repo.List(context.Background(), HistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(page); got != "edc" {
+		t.Errorf("expected the latest 3 calculations, got %q", got)
+	}
+}
+
+func TestSaveHistoryLeavesTraceIDEmptyWithoutSpan(t *testing.T) {
+	saveHistory(context.Background(), Calculation{Operation: "untraced", Result: 1})
+
+	repo, err := historyRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := repo.List(context.Background(), HistoryQuery{Operation: "untraced"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Calculations) != 1 {
+		t.Fatalf("expected 1 calculation, got %+v", page)
+	}
+	c := page.Calculations[0]
+	if c.TraceID != "" {
+		t.Errorf("expected no trace_id, got %q", c.TraceID)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("trace_id")) {
+		t.Errorf("expected trace_id to be omitted, got %s", data)
+	}
+}
+
+func TestFileRepositorySurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	repo, err := OpenFileRepository(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveCalculations(t, repo, 3)
+	if err := repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash in the middle of an append.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"partial","oper`)
+	f.Close()
+
+	repo, err = OpenFileRepository(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	if err := repo.Save(context.Background(), Calculation{ID: "d", Operation: "add", Result: 1e308}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := repo.List(context.Background(), HistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(page); got != "dcba" {
+		t.Fatalf("expected the saved calculations without the partial line, got %q", got)
+	}
+	if page.Calculations[1].Operation != "add" || page.Calculations[1].Result != 2 {
+		t.Errorf("unexpected calculation %+v", page.Calculations[1])
+	}
+}
+
+func TestFileRepositoryKeepsLatest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	lines := func() int {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.Count(data, []byte("\n"))
+	}
+
+	repo, err := OpenFileRepository(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveCalculations(t, repo, 5) // a..e
+	repo.Close()
+
+	// A file longer than the limit loads only its newest entries and is
+	// compacted to them.
+	repo, err = OpenFileRepository(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { repo.Close() }()
+	page, err := repo.List(context.Background(), HistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(page); got != "edc" {
+		t.Errorf("expected the latest 3 calculations, got %q", got)
+	}
+	if n := lines(); n != 3 {
+		t.Errorf("expected the file compacted to 3 lines, got %d", n)
+	}
+
+	// Saves compact the file again once it reaches twice the limit.
+	for _, id := range []string{"f", "g", "h", "i"} {
+		if err := repo.Save(context.Background(), Calculation{ID: id, Operation: "add"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := lines(); n != 4 {
+		t.Errorf("expected 3 lines after compaction plus 1 appended, got %d", n)
+	}
+	repo.Close()
+
+	repo, err = OpenFileRepository(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ = repo.List(context.Background(), HistoryQuery{})
+	if got := ids(page); got != "ihg" {
+		t.Errorf("expected the latest 3 calculations after reopening, got %q", got)
+	}
+}
+
+func TestOpenFileRepositoryRejectsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := os.WriteFile(path, []byte("not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenFileRepository(path, 0); err == nil {
+		t.Fatal("expected an error for a corrupt line")
+	}
+}
@@ -0,0 +1,324 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-chi-observability/internal/observability"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// jobWorkers is the number of jobs that run at once.
+	jobWorkers = 4
+	// maxQueuedJobs bounds the jobs waiting for a worker; submissions beyond
+	// it are refused.
+	maxQueuedJobs = 100
+	// jobRetention is how long finished jobs can still be fetched.
+	jobRetention = 15 * time.Minute
+	// maxRetainedJobs bounds the jobs kept, finished or not; the oldest
+	// finished jobs are forgotten first to stay within it.
+	maxRetainedJobs = 1000
+)
+
+var (
+	errJobNotFound  = errors.New("job not found")
+	errJobFinished  = errors.New("job already finished")
+	errQueueFull    = errors.New("job queue is full")
+	errJobsShutdown = errors.New("job queue is shutting down")
+)
+
+// jobs runs the asynchronous chains submitted to POST /calculator/jobs.
+var jobs = newJobManager(runJob)
+
+// job is an asynchronous chain. Fields below mu are guarded by the
+// manager's mutex.
+type job struct {
+	id        string
+	req       ChainRequest
+	submitter trace.SpanContext // span of the submitting request
+	requestID string
+
+	status    JobStatus
+	completed int
+	result    *ChainResponse
+	err       error
+	created   time.Time
+	started   time.Time
+	finished  time.Time
+	cancel    context.CancelFunc // set while running
+}
+
+// jobRunner runs a job's chain, calling progress after every step.
+type jobRunner func(ctx context.Context, j *job, progress func()) (ChainResponse, error)
+
+type jobManager struct {
+	run jobRunner
+
+	mu       sync.Mutex
+	jobs     map[string]*job
+	finished []*job // in the order they finished, for prune
+
+	queue   chan *job
+	start   sync.Once
+	ctx     context.Context // canceled by shutdown
+	stop    context.CancelFunc
+	workers sync.WaitGroup
+}
+
+func newJobManager(run jobRunner) *jobManager {
+	ctx, stop := context.WithCancel(context.Background())
+	return &jobManager{
+		run:   run,
+		jobs:  make(map[string]*job),
+		queue: make(chan *job, maxQueuedJobs),
+		ctx:   ctx,
+		stop:  stop,
+	}
+}
+
+// submit queues a validated chain. The submitter's span and request ID in
+// ctx are kept for the job's own trace and logs.
+func (m *jobManager) submit(ctx context.Context, req ChainRequest) (JobResponse, error) {
+	m.start.Do(func() {
+		for range jobWorkers {
+			m.workers.Go(m.work)
+		}
+	})
+
+	j := &job{
+		id:        uuid.New().String(),
+		req:       req,
+		submitter: trace.SpanContextFromContext(ctx),
+		requestID: observability.RequestIDFromContext(ctx),
+		status:    JobQueued,
+		created:   time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		return JobResponse{}, errJobsShutdown
+	}
+	m.prune(j.created)
+	select {
+	case m.queue <- j:
+	default:
+		return JobResponse{}, errQueueFull
+	}
+	m.jobs[j.id] = j
+
+	jobsTotal.Add(ctx, 1, statusAttr(JobQueued))
+	jobsActive.Add(ctx, 1, statusAttr(JobQueued))
+	return j.view(), nil
+}
+
+// get returns the current state of a job.
+func (m *jobManager) get(id string) (JobResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobResponse{}, errJobNotFound
+	}
+	return j.view(), nil
+}
+
+// cancel cancels a queued job at once and asks a running one to stop; it
+// does so before its next step.
+func (m *jobManager) cancel(ctx context.Context, id string) (JobResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobResponse{}, errJobNotFound
+	}
+	switch j.status {
+	case JobQueued:
+		m.finishLocked(j, JobCanceled, errors.New("canceled before it started"), time.Now())
+		jobsActive.Add(ctx, -1, statusAttr(JobQueued))
+		jobsTotal.Add(ctx, 1, statusAttr(JobCanceled))
+	case JobRunning:
+		j.cancel()
+	default:
+		return j.view(), errJobFinished
+	}
+	return j.view(), nil
+}
+
+// shutdown stops the workers, canceling running jobs and any still queued,
+// and waits for them until ctx is done.
+func (m *jobManager) shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.stop()
+	for _, j := range m.jobs {
+		if j.status == JobQueued {
+			m.finishLocked(j, JobCanceled, errJobsShutdown, time.Now())
+			jobsActive.Add(ctx, -1, statusAttr(JobQueued))
+			jobsTotal.Add(ctx, 1, statusAttr(JobCanceled))
+		}
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finishLocked marks j finished. The caller holds m.mu.
+func (m *jobManager) finishLocked(j *job, status JobStatus, err error, at time.Time) {
+	j.status, j.finished, j.cancel, j.err = status, at, nil, err
+	m.finished = append(m.finished, j)
+}
+
+// prune forgets jobs that finished more than jobRetention ago and, while
+// maxRetainedJobs are kept, the oldest finished ones, making room for one
+// more. The caller holds m.mu.
+func (m *jobManager) prune(now time.Time) {
+	for len(m.finished) > 0 {
+		j := m.finished[0]
+		if now.Sub(j.finished) <= jobRetention && len(m.jobs) < maxRetainedJobs {
+			return
+		}
+		delete(m.jobs, j.id)
+		m.finished = m.finished[1:]
+	}
+}
+
+func (m *jobManager) work() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case j := <-m.queue:
+			m.execute(j)
+		}
+	}
+}
+
+func (m *jobManager) execute(j *job) {
+	m.mu.Lock()
+	if j.status != JobQueued { // canceled while queued
+		m.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+	j.status, j.started, j.cancel = JobRunning, time.Now(), cancel
+	m.mu.Unlock()
+
+	jobsActive.Add(ctx, -1, statusAttr(JobQueued))
+	jobsActive.Add(ctx, 1, statusAttr(JobRunning))
+	jobsTotal.Add(ctx, 1, statusAttr(JobRunning))
+
+	resp, err := m.run(ctx, j, func() {
+		m.mu.Lock()
+		j.completed++
+		m.mu.Unlock()
+	})
+
+	status := JobSucceeded
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		status = JobCanceled
+	default:
+		status = JobFailed
+	}
+	finished := time.Now()
+
+	// Metrics are recorded before the status is published so that a client
+	// seeing the job finished also sees it counted. ctx may be canceled;
+	// metrics only need it for its values.
+	mctx := context.WithoutCancel(ctx)
+	jobsActive.Add(mctx, -1, statusAttr(JobRunning))
+	jobsTotal.Add(mctx, 1, statusAttr(status))
+	jobDuration.Record(mctx, float64(finished.Sub(j.started).Microseconds())/1000.0, statusAttr(status))
+
+	m.mu.Lock()
+	m.finishLocked(j, status, err, finished)
+	if err == nil {
+		j.result = &resp
+	}
+	m.mu.Unlock()
+}
+
+// view renders the job for the API. The caller holds the manager's mutex.
+func (j *job) view() JobResponse {
+	resp := JobResponse{
+		ID:        j.id,
+		Status:    j.status,
+		Progress:  JobProgress{CompletedSteps: j.completed, TotalSteps: len(j.req.Steps)},
+		Result:    j.result,
+		CreatedAt: j.created,
+	}
+	if j.err != nil {
+		resp.Error, resp.ErrorKind = j.err.Error(), errorKind(j.err)
+	}
+	if !j.started.IsZero() {
+		resp.StartedAt = &j.started
+	}
+	if !j.finished.IsZero() {
+		resp.FinishedAt = &j.finished
+	}
+	return resp
+}
+
+func statusAttr(status JobStatus) metric.MeasurementOption {
+	return metric.WithAttributes(attribute.String("status", string(status)))
+}
+
+// runJob runs a job's chain in a new trace whose calculator.job root span is
+// linked to the submitting request's span, so the request's trace and the
+// job's can be followed from one to the other.
+func runJob(ctx context.Context, j *job, progress func()) (ChainResponse, error) {
+	ctx = observability.ContextWithRequestID(ctx, j.requestID)
+
+	opts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("job.id", j.id),
+			attribute.String("request.id", j.requestID),
+		),
+	}
+	if j.submitter.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{
+			SpanContext: j.submitter,
+			Attributes:  []attribute.KeyValue{attribute.String("link.type", "submitter")},
+		}))
+	}
+	ctx, span := tracer.Start(ctx, "calculator.job", opts...)
+	defer span.End()
+
+	logger := observability.LoggerWithTrace(ctx)
+	logger.Info("job started", zap.String("job_id", j.id), zap.Int("steps", len(j.req.Steps)))
+
+	// runChain sets the span's status and records failures.
+	resp, err := runChain(ctx, logger, j.req, func(ChainResult) { progress() })
+	if err != nil {
+		logger.Warn("job stopped", zap.String("job_id", j.id), zap.Error(err))
+		return resp, err
+	}
+
+	logger.Info("job succeeded", zap.String("job_id", j.id), zap.Float64("result", float64(resp.Result)))
+	return resp, nil
+}
@@ -0,0 +1,199 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var chainOfTwo = ChainRequest{Initial: 1, Steps: []ChainStep{{Op: "add", Value: 1}, {Op: "add", Value: 1}}}
+
+// waitForJob polls the job until cond holds or a second has passed.
+func waitForJob(t *testing.T, m *jobManager, id string, cond func(JobResponse) bool) JobResponse {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := m.get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond(resp) {
+			return resp
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not reach the expected state, last %+v", id, resp)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func finished(resp JobResponse) bool { return resp.FinishedAt != nil }
+
+func TestJobManagerRunsJobsWithProgress(t *testing.T) {
+	initBatchMetrics(t)
+
+	m := newJobManager(func(_ context.Context, j *job, progress func()) (ChainResponse, error) {
+		for range j.req.Steps {
+			progress()
+		}
+		return ChainResponse{Result: 3}, nil
+	})
+	t.Cleanup(func() { m.shutdown(context.Background()) })
+
+	resp, err := m.submit(context.Background(), chainOfTwo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != JobQueued || resp.Progress.TotalSteps != 2 {
+		t.Fatalf("unexpected submit response %+v", resp)
+	}
+
+	resp = waitForJob(t, m, resp.ID, finished)
+	if resp.Status != JobSucceeded || resp.Result == nil || resp.Result.Result != 3 {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+	if resp.Progress.CompletedSteps != 2 || resp.StartedAt == nil {
+		t.Errorf("unexpected progress %+v", resp)
+	}
+}
+
+func TestJobManagerRecordsFailures(t *testing.T) {
+	initBatchMetrics(t)
+
+	m := newJobManager(func(context.Context, *job, func()) (ChainResponse, error) {
+		return ChainResponse{}, operandErrorf("divide", ErrDivisionByZero, "division by zero: 1 / 0")
+	})
+	t.Cleanup(func() { m.shutdown(context.Background()) })
+
+	resp, _ := m.submit(context.Background(), chainOfTwo)
+	resp = waitForJob(t, m, resp.ID, finished)
+	if resp.Status != JobFailed || resp.ErrorKind != ErrDivisionByZero || resp.Result != nil {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	initBatchMetrics(t)
+
+	started := make(chan struct{}, maxQueuedJobs)
+	release := make(chan struct{})
+	m := newJobManager(func(ctx context.Context, _ *job, _ func()) (ChainResponse, error) {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			return ChainResponse{}, ctx.Err()
+		case <-release:
+			return ChainResponse{}, nil
+		}
+	})
+	t.Cleanup(func() {
+		close(release)
+		m.shutdown(context.Background())
+	})
+
+	// Occupy every worker so the next job stays queued.
+	var running []string
+	for range jobWorkers {
+		resp, _ := m.submit(context.Background(), chainOfTwo)
+		running = append(running, resp.ID)
+	}
+	for range jobWorkers {
+		<-started
+	}
+	queued, _ := m.submit(context.Background(), chainOfTwo)
+
+	resp, err := m.cancel(context.Background(), queued.ID)
+	if err != nil || resp.Status != JobCanceled {
+		t.Fatalf("expected the queued job to be canceled at once, got %+v, %v", resp, err)
+	}
+
+	if _, err := m.cancel(context.Background(), running[0]); err != nil {
+		t.Fatal(err)
+	}
+	resp = waitForJob(t, m, running[0], finished)
+	if resp.Status != JobCanceled || resp.Error != context.Canceled.Error() {
+		t.Fatalf("expected the running job to be canceled, got %+v", resp)
+	}
+
+	if _, err := m.cancel(context.Background(), running[0]); !errors.Is(err, errJobFinished) {
+		t.Errorf("expected errJobFinished, got %v", err)
+	}
+	if _, err := m.cancel(context.Background(), "missing"); !errors.Is(err, errJobNotFound) {
+		t.Errorf("expected errJobNotFound, got %v", err)
+	}
+}
+
+func TestJobManagerQueueFullAndShutdown(t *testing.T) {
+	initBatchMetrics(t)
+
+	block := make(chan struct{})
+	m := newJobManager(func(ctx context.Context, _ *job, _ func()) (ChainResponse, error) {
+		select {
+		case <-ctx.Done():
+			return ChainResponse{}, ctx.Err()
+		case <-block:
+			return ChainResponse{}, nil
+		}
+	})
+	defer close(block)
+
+	var err error
+	var submitted []string
+	for range jobWorkers + maxQueuedJobs + 1 {
+		var resp JobResponse
+		if resp, err = m.submit(context.Background(), chainOfTwo); err != nil {
+			break
+		}
+		submitted = append(submitted, resp.ID)
+	}
+	if !errors.Is(err, errQueueFull) {
+		t.Fatalf("expected errQueueFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	for _, id := range submitted {
+		if resp, _ := m.get(id); resp.Status != JobCanceled {
+			t.Fatalf("expected job %s to be canceled by shutdown, got %+v", id, resp)
+		}
+	}
+	if _, err := m.submit(context.Background(), chainOfTwo); !errors.Is(err, errJobsShutdown) {
+		t.Errorf("expected errJobsShutdown, got %v", err)
+	}
+}
+
+func TestJobManagerForgetsOldestFinishedJobs(t *testing.T) {
+	initBatchMetrics(t)
+
+	m := newJobManager(func(context.Context, *job, func()) (ChainResponse, error) {
+		return ChainResponse{}, nil
+	})
+	t.Cleanup(func() { m.shutdown(context.Background()) })
+
+	var ids []string
+	for range maxRetainedJobs + 10 {
+		resp, err := m.submit(context.Background(), chainOfTwo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitForJob(t, m, resp.ID, finished)
+		ids = append(ids, resp.ID)
+	}
+
+	m.mu.Lock()
+	kept := len(m.jobs)
+	m.mu.Unlock()
+	if kept != maxRetainedJobs {
+		t.Fatalf("expected %d jobs kept, got %d", maxRetainedJobs, kept)
+	}
+	if _, err := m.get(ids[9]); !errors.Is(err, errJobNotFound) {
+		t.Errorf("expected the 10 oldest jobs to be forgotten, got %v", err)
+	}
+	if _, err := m.get(ids[10]); err != nil {
+		t.Errorf("expected the newer jobs to be kept, got %v", err)
+	}
+}
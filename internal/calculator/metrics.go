@@ -1,6 +1,7 @@
 package calculator
 
 import (
+	"context"
 	"fmt"
 
 	"go.opentelemetry.io/otel"
@@ -13,6 +14,19 @@ var (
 	opsHistogram metric.Float64Histogram
 	errorCounter metric.Int64Counter
 	resultGauge  metric.Float64Gauge
+
+	anomalyCounter metric.Int64Counter
+
+	batchSize      metric.Int64Histogram
+	batchQueueWait metric.Float64Histogram
+	batchItems     metric.Int64Counter
+
+	jobsTotal   metric.Int64Counter
+	jobsActive  metric.Int64UpDownCounter
+	jobDuration metric.Float64Histogram
+
+	sessionsActive  metric.Int64ObservableGauge
+	sessionsExpired metric.Int64Counter
 )
 
 // InitMetrics registers custom OTel metric instruments for the calculator domain.
@@ -55,5 +69,84 @@ func InitMetrics() error {
 		return fmt.Errorf("creating result gauge: %w", err)
 	}
 
+	anomalyCounter, err = meter.Int64Counter("calculator.numeric_anomalies.total",
+		metric.WithDescription("Results that overflowed, were not a number or lost precision"),
+		metric.WithUnit("{anomaly}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating anomaly counter: %w", err)
+	}
+
+	batchSize, err = meter.Int64Histogram("calculator.batch.size",
+		metric.WithDescription("Number of items per batch request"),
+		metric.WithUnit("{item}"),
+		metric.WithExplicitBucketBoundaries(1, 5, 10, 50, 100, 500, 1000),
+	)
+	if err != nil {
+		return fmt.Errorf("creating batch size histogram: %w", err)
+	}
+
+	batchQueueWait, err = meter.Float64Histogram("calculator.batch.queue_wait",
+		metric.WithDescription("Time batch items wait for a worker in milliseconds"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.1, 1, 5, 10, 50, 100, 500),
+	)
+	if err != nil {
+		return fmt.Errorf("creating batch queue wait histogram: %w", err)
+	}
+
+	batchItems, err = meter.Int64Counter("calculator.batch.items.total",
+		metric.WithDescription("Batch items processed, by outcome (succeeded, failed, canceled)"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating batch items counter: %w", err)
+	}
+
+	jobsTotal, err = meter.Int64Counter("calculator.jobs.total",
+		metric.WithDescription("Asynchronous jobs entering each status (queued, running, succeeded, failed, canceled)"),
+		metric.WithUnit("{job}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating jobs counter: %w", err)
+	}
+
+	jobsActive, err = meter.Int64UpDownCounter("calculator.jobs.active",
+		metric.WithDescription("Asynchronous jobs currently queued or running"),
+		metric.WithUnit("{job}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating active jobs counter: %w", err)
+	}
+
+	jobDuration, err = meter.Float64Histogram("calculator.job.duration",
+		metric.WithDescription("Run time of asynchronous jobs in milliseconds, by final status"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(1, 5, 10, 50, 100, 500, 1000, 5000),
+	)
+	if err != nil {
+		return fmt.Errorf("creating job duration histogram: %w", err)
+	}
+
+	sessionsActive, err = meter.Int64ObservableGauge("calculator.sessions.active",
+		metric.WithDescription("Sessions that have not expired"),
+		metric.WithUnit("{session}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(sessions.active()))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("creating active sessions gauge: %w", err)
+	}
+
+	sessionsExpired, err = meter.Int64Counter("calculator.sessions.expired.total",
+		metric.WithDescription("Sessions removed after their TTL passed without use"),
+		metric.WithUnit("{session}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating expired sessions counter: %w", err)
+	}
+
 	return nil
 }
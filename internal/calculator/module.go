@@ -0,0 +1,37 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-chi/chi/v5"
+
+	"go-chi-observability/internal/observability"
+)
+
+// Module plugs the calculator domain into the server (see server.Module).
+type Module struct{}
+
+func (Module) Name() string { return "calculator" }
+
+func (Module) InitMetrics() error { return InitMetrics() }
+
+func (Module) RegisterRoutes(r chi.Router) { RegisterRoutes(r) }
+
+// HealthChecks reports whether the calculation history could be opened.
+// Calculations work without it, so the check is not critical.
+func (Module) HealthChecks() []observability.HealthCheck {
+	return []observability.HealthCheck{{
+		Name: "history",
+		Check: func(context.Context) error {
+			_, err := historyRepository()
+			return err
+		},
+	}}
+}
+
+// Shutdown cancels running and queued jobs, waits for the job workers, then
+// closes the calculation history.
+func (Module) Shutdown(ctx context.Context) error {
+	return errors.Join(jobs.shutdown(ctx), closeHistory())
+}
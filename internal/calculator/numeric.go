@@ -0,0 +1,132 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"go-chi-observability/internal/observability"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ResultPolicy decides what happens to a result float64 cannot represent
+// finitely. Inputs are always required to be finite.
+type ResultPolicy string
+
+const (
+	PolicyError  ResultPolicy = "error"  // the default: reject with 400
+	PolicyClamp  ResultPolicy = "clamp"  // ±Inf becomes ±math.MaxFloat64; NaN is still an error
+	PolicyString ResultPolicy = "string" // returned as "+Inf", "-Inf" or "NaN"
+)
+
+// validate reports whether p is a known policy; empty means PolicyError.
+func (p ResultPolicy) validate() error {
+	switch p {
+	case "", PolicyError, PolicyClamp, PolicyString:
+		return nil
+	}
+	return fmt.Errorf("unknown result policy %q, expected %q, %q or %q", p, PolicyError, PolicyClamp, PolicyString)
+}
+
+func (p ResultPolicy) orDefault() ResultPolicy {
+	if p == "" {
+		return PolicyError
+	}
+	return p
+}
+
+// Anomaly classifies a result that lost range or precision.
+type Anomaly string
+
+const (
+	AnomalyOverflow  Anomaly = "overflow"  // ±Inf from finite operands
+	AnomalyNaN       Anomaly = "nan"       // not a number, e.g. power(-8, 0.5)
+	AnomalyUnderflow Anomaly = "underflow" // rounded to zero although it cannot be zero
+	AnomalySubnormal Anomaly = "subnormal" // below the normal range, with reduced precision
+)
+
+// ErrOverflow and ErrNotANumber are the ErrorKinds of results rejected by
+// PolicyError (and, for NaN, by PolicyClamp).
+const (
+	ErrOverflow   ErrorKind = "overflow"
+	ErrNotANumber ErrorKind = "not_a_number"
+)
+
+// detectAnomaly classifies the result of applying op to args, or returns ""
+// for an ordinary result.
+func detectAnomaly(op Operation, args []float64, result float64) Anomaly {
+	switch {
+	case math.IsNaN(result):
+		return AnomalyNaN
+	case math.IsInf(result, 0):
+		return AnomalyOverflow
+	case result == 0:
+		if !op.NonZero || slices.Contains(args, 0) {
+			return ""
+		}
+		return AnomalyUnderflow
+	case math.Abs(result) < smallestNormal:
+		return AnomalySubnormal
+	}
+	return ""
+}
+
+// smallestNormal is the smallest positive normal float64.
+const smallestNormal = 0x1p-1022
+
+// calculate applies op and checks its result against policy. Anomalies are
+// counted and recorded as an event on the span in ctx.
+func calculate(ctx context.Context, op Operation, angle AngleUnit, policy ResultPolicy, args ...float64) (float64, error) {
+	result, err := op.apply(angle, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	anomaly := detectAnomaly(op, args, result)
+	// Checked first: calculate runs for every chain step and subexpression,
+	// and building a logger for each would cost even when nothing is logged.
+	if observability.DebugLoggingFromContext(ctx) || observability.LogLevel.Enabled(zap.DebugLevel) {
+		observability.LoggerWithTrace(ctx).Debug("calculator operation computed",
+			zap.String("operation", op.Name),
+			zap.Float64s("operands", args),
+			zap.String("angle", string(angle)),
+			zap.Float64("result", result),
+			zap.String("anomaly", string(anomaly)),
+		)
+	}
+	if anomaly == "" {
+		return result, nil
+	}
+	policy = policy.orDefault()
+
+	anomalyCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("operation", op.Name),
+		attribute.String("anomaly", string(anomaly)),
+		attribute.String("policy", string(policy)),
+	))
+	trace.SpanFromContext(ctx).AddEvent("anomaly.detected", trace.WithAttributes(
+		attribute.String("anomaly", string(anomaly)),
+		attribute.String("policy", string(policy)),
+		attribute.Float64("result", result),
+	))
+
+	switch anomaly {
+	case AnomalyOverflow:
+		switch policy {
+		case PolicyClamp:
+			return math.Copysign(math.MaxFloat64, result), nil
+		case PolicyError:
+			return 0, operandErrorf(op.Name, ErrOverflow, "%s result overflows float64 (%g)", op.Name, result)
+		}
+	case AnomalyNaN:
+		if policy != PolicyString {
+			return 0, operandErrorf(op.Name, ErrNotANumber, "%s result is not a number", op.Name)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package calculator
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestDetectAnomaly(t *testing.T) {
+	tests := []struct {
+		op     string
+		args   []float64
+		result float64
+		want   Anomaly
+	}{
+		{"multiply", []float64{1e308, 10}, math.Inf(1), AnomalyOverflow},
+		{"power", []float64{-8, 0.5}, math.NaN(), AnomalyNaN},
+		{"multiply", []float64{1e-200, 1e-200}, 0, AnomalyUnderflow},
+		{"multiply", []float64{0, 1e-200}, 0, ""},
+		{"subtract", []float64{5, 5}, 0, ""},
+		{"divide", []float64{1e-300, 1e10}, 1e-310, AnomalySubnormal},
+		{"add", []float64{1, 2}, 3, ""},
+	}
+
+	for _, tt := range tests {
+		op, _ := LookupOperation(tt.op)
+		if got := detectAnomaly(op, tt.args, tt.result); got != tt.want {
+			t.Errorf("detectAnomaly(%s, %v, %g) = %q, want %q", tt.op, tt.args, tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestNumberJSON(t *testing.T) {
+	tests := []struct {
+		n    Number
+		want string
+	}{
+		{1.5, `1.5`},
+		{1e21, `1e+21`},
+		{Number(math.Inf(1)), `"+Inf"`},
+		{Number(math.Inf(-1)), `"-Inf"`},
+		{Number(math.NaN()), `"NaN"`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.n)
+		if err != nil || string(data) != tt.want {
+			t.Errorf("Marshal(%v) = %s (%v), want %s", tt.n, data, err, tt.want)
+			continue
+		}
+		var back Number
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Errorf("Unmarshal(%s): %v", data, err)
+		} else if back != tt.n && !math.IsNaN(float64(back)) {
+			t.Errorf("Unmarshal(%s) = %v, want %v", data, back, tt.n)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Operation is a calculator operation. Every registered operation is served
+// at POST /calculator/<Name>, accepted as a chain step, listed by
+// GET /calculator/operations and, for the arithmetic operators, used by
+// /calculator/evaluate — all with the same spans, metrics and logs.
+type Operation struct {
+	Name        string
+	Description string
+	// Arity is the number of operands. In a chain, the running total is the
+	// first operand and the step value the second.
+	Arity int
+	// Angle marks the first operand as an angle. It is given in the
+	// request's AngleUnit and converted to radians before Compute.
+	Angle bool
+	// NonZero marks operations whose result is never zero for non-zero
+	// operands, so a zero result is reported as an underflow.
+	NonZero bool
+	// Validate rejects operands Compute cannot handle, preferably with an
+	// *OperandError. Optional.
+	Validate func(args []float64) error
+	Compute  func(args []float64) float64
+	// Decimal computes the operation exactly for decimal precision, or to
+	// more than scale places if the result may not terminate. Operations
+	// without it are not available in decimal precision.
+	Decimal func(args []*big.Rat, scale int) (*big.Rat, error)
+}
+
+// apply validates args and computes the result.
+func (op Operation) apply(unit AngleUnit, args ...float64) (float64, error) {
+	if len(args) != op.Arity {
+		return 0, fmt.Errorf("%s takes %d operand(s), got %d", op.Name, op.Arity, len(args))
+	}
+	if op.Angle && unit == Degrees {
+		args = slices.Clone(args)
+		args[0] = args[0] * math.Pi / 180
+	}
+	if op.Validate != nil {
+		if err := op.Validate(args); err != nil {
+			return 0, err
+		}
+	}
+	return op.Compute(args), nil
+}
+
+// validate reports whether u is a known unit; empty means Radians.
+func (u AngleUnit) validate() error {
+	switch u {
+	case "", Radians, Degrees:
+		return nil
+	}
+	return fmt.Errorf("unknown angle unit %q, expected %q or %q", u, Radians, Degrees)
+}
+
+// ErrorKind classifies an OperandError for clients, spans and logs.
+type ErrorKind string
+
+const (
+	ErrDivisionByZero ErrorKind = "division_by_zero"
+	ErrOutOfDomain    ErrorKind = "out_of_domain" // e.g. sqrt(-1), ln(0)
+	ErrNotInteger     ErrorKind = "not_integer"   // e.g. factorial(2.5)
+	ErrTooLarge       ErrorKind = "too_large"     // the result cannot be represented
+)
+
+// OperandError reports operands an operation is not defined for.
+type OperandError struct {
+	Op   string
+	Kind ErrorKind
+	Msg  string
+}
+
+func (e *OperandError) Error() string { return e.Msg }
+
+func operandErrorf(op string, kind ErrorKind, format string, args ...any) error {
+	return &OperandError{Op: op, Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+// errorKind returns the ErrorKind behind err, or "" if it is not an
+// *OperandError.
+func errorKind(err error) ErrorKind {
+	var opErr *OperandError
+	if errors.As(err, &opErr) {
+		return opErr.Kind
+	}
+	return ""
+}
+
+// builtinOperations is the registry's initial content. Adding an operation
+// here is all it takes to expose it everywhere.
+var builtinOperations = []Operation{
+	{
+		Name:        "add",
+		Description: "a + b",
+		Arity:       2,
+		Compute:     func(a []float64) float64 { return a[0] + a[1] },
+		Decimal:     decimalAdd,
+	},
+	{
+		Name:        "subtract",
+		Description: "a - b",
+		Arity:       2,
+		Compute:     func(a []float64) float64 { return a[0] - a[1] },
+		Decimal:     decimalSubtract,
+	},
+	{
+		Name:        "multiply",
+		Description: "a * b",
+		Arity:       2,
+		NonZero:     true,
+		Compute:     func(a []float64) float64 { return a[0] * a[1] },
+		Decimal:     decimalMultiply,
+	},
+	{
+		Name:        "divide",
+		Description: "a / b; b must not be zero",
+		Arity:       2,
+		Validate:    nonZeroDivisor("divide"),
+		NonZero:     true,
+		Compute:     func(a []float64) float64 { return a[0] / a[1] },
+		Decimal:     decimalDivide,
+	},
+	{
+		Name:        "power",
+		Description: "a raised to the power b",
+		Arity:       2,
+		NonZero:     true,
+		Compute:     func(a []float64) float64 { return math.Pow(a[0], a[1]) },
+		Decimal:     decimalPower,
+	},
+	{
+		Name:        "modulo",
+		Description: "remainder of a / b, with the sign of a; b must not be zero",
+		Arity:       2,
+		Validate:    nonZeroDivisor("modulo"),
+		Compute:     func(a []float64) float64 { return math.Mod(a[0], a[1]) },
+		Decimal:     decimalModulo,
+	},
+	{
+		Name:        "root",
+		Description: "the b-th root of a; a must be non-negative unless b is an odd integer",
+		Arity:       2,
+		Validate:    validateRoot,
+		NonZero:     true,
+		Compute:     computeRoot,
+		Decimal:     decimalRoot,
+	},
+	{
+		Name:        "gcd",
+		Description: "greatest common divisor of integers a and b",
+		Arity:       2,
+		Validate:    integers("gcd"),
+		Compute:     func(a []float64) float64 { return gcd(a[0], a[1]) },
+		Decimal:     decimalGCD,
+	},
+	{
+		Name:        "lcm",
+		Description: "least common multiple of integers a and b",
+		Arity:       2,
+		Validate:    integers("lcm"),
+		Compute:     computeLCM,
+		Decimal:     decimalLCM,
+	},
+	{
+		Name:        "sqrt",
+		Description: "square root of a; a must be non-negative",
+		Arity:       1,
+		Validate:    nonNegative("sqrt", "square root"),
+		Compute:     func(a []float64) float64 { return math.Sqrt(a[0]) },
+		Decimal:     decimalSqrt,
+	},
+	{
+		Name:        "abs",
+		Description: "absolute value of a",
+		Arity:       1,
+		Compute:     func(a []float64) float64 { return math.Abs(a[0]) },
+		Decimal:     decimalAbs,
+	},
+	{
+		Name:        "ln",
+		Description: "natural logarithm of a; a must be positive",
+		Arity:       1,
+		Validate:    positive("ln"),
+		Compute:     func(a []float64) float64 { return math.Log(a[0]) },
+		Decimal:     decimalLn,
+	},
+	{
+		Name:        "log10",
+		Description: "base-10 logarithm of a; a must be positive",
+		Arity:       1,
+		Validate:    positive("log10"),
+		Compute:     func(a []float64) float64 { return math.Log10(a[0]) },
+		Decimal:     decimalLog10,
+	},
+	{
+		Name:        "exp",
+		Description: "e raised to the power a",
+		Arity:       1,
+		NonZero:     true,
+		Compute:     func(a []float64) float64 { return math.Exp(a[0]) },
+		Decimal:     decimalExp,
+	},
+	{
+		Name:        "sin",
+		Description: "sine of the angle a",
+		Arity:       1,
+		Angle:       true,
+		Compute:     func(a []float64) float64 { return math.Sin(a[0]) },
+		Decimal:     decimalSin,
+	},
+	{
+		Name:        "cos",
+		Description: "cosine of the angle a",
+		Arity:       1,
+		Angle:       true,
+		Compute:     func(a []float64) float64 { return math.Cos(a[0]) },
+		Decimal:     decimalCos,
+	},
+	{
+		Name:        "tan",
+		Description: "tangent of the angle a",
+		Arity:       1,
+		Angle:       true,
+		Compute:     func(a []float64) float64 { return math.Tan(a[0]) },
+		Decimal:     decimalTan,
+	},
+	{
+		Name:        "factorial",
+		Description: "a! for integers 0 <= a <= 170",
+		Arity:       1,
+		Validate:    validateFactorial,
+		Compute:     computeFactorial,
+		Decimal:     decimalFactorial,
+	},
+}
+
+// ---------------------------------------------------------------------------
+// Operand validation and computation helpers
+// ---------------------------------------------------------------------------
+
+// maxExactInteger is the largest integer float64 represents exactly; gcd and
+// lcm refuse anything beyond it.
+const maxExactInteger = 1 << 53
+
+// maxFactorial is the largest n whose factorial fits in a float64.
+const maxFactorial = 170
+
+func nonZeroDivisor(op string) func([]float64) error {
+	return func(a []float64) error {
+		if a[1] == 0 {
+			return operandErrorf(op, ErrDivisionByZero, "division by zero: %g / %g", a[0], a[1])
+		}
+		return nil
+	}
+}
+
+func nonNegative(op, what string) func([]float64) error {
+	return func(a []float64) error {
+		if a[0] < 0 {
+			return operandErrorf(op, ErrOutOfDomain, "%s of negative number %g", what, a[0])
+		}
+		return nil
+	}
+}
+
+func positive(op string) func([]float64) error {
+	return func(a []float64) error {
+		if a[0] <= 0 {
+			return operandErrorf(op, ErrOutOfDomain, "logarithm of non-positive number %g", a[0])
+		}
+		return nil
+	}
+}
+
+func isInteger(x float64) bool { return x == math.Trunc(x) }
+
+func integers(op string) func([]float64) error {
+	return func(a []float64) error {
+		for _, x := range a {
+			if !isInteger(x) {
+				return operandErrorf(op, ErrNotInteger, "%s requires integers, got %g", op, x)
+			}
+			if math.Abs(x) > maxExactInteger {
+				return operandErrorf(op, ErrTooLarge, "%s operand %g exceeds %d", op, x, int64(maxExactInteger))
+			}
+		}
+		return nil
+	}
+}
+
+func validateRoot(a []float64) error {
+	x, n := a[0], a[1]
+	switch {
+	case n == 0:
+		return operandErrorf("root", ErrOutOfDomain, "zeroth root of %g is undefined", x)
+	case x < 0 && !(isInteger(n) && math.Mod(n, 2) != 0):
+		return operandErrorf("root", ErrOutOfDomain, "root %g of negative number %g requires an odd integer degree", n, x)
+	}
+	return nil
+}
+
+func computeRoot(a []float64) float64 {
+	x, n := math.Abs(a[0]), a[1]
+	var r float64
+	switch n {
+	case 2:
+		r = math.Sqrt(x)
+	case 3:
+		r = math.Cbrt(x)
+	default:
+		r = math.Pow(x, 1/n)
+	}
+	if a[0] < 0 {
+		r = -r // odd degree, checked by validateRoot
+	}
+	return r
+}
+
+func gcd(a, b float64) float64 {
+	x, y := int64(math.Abs(a)), int64(math.Abs(b))
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return float64(x)
+}
+
+func computeLCM(a []float64) float64 {
+	if a[0] == 0 || a[1] == 0 {
+		return 0
+	}
+	return math.Abs(a[0]) / gcd(a[0], a[1]) * math.Abs(a[1])
+}
+
+func validateFactorial(a []float64) error {
+	n := a[0]
+	switch {
+	case n < 0 || !isInteger(n):
+		return operandErrorf("factorial", ErrNotInteger, "factorial requires a non-negative integer, got %g", n)
+	case n > maxFactorial:
+		return operandErrorf("factorial", ErrTooLarge, "factorial of %g exceeds the float64 range (max %d)", n, maxFactorial)
+	}
+	return nil
+}
+
+func computeFactorial(a []float64) float64 {
+	result := 1.0
+	for i := 2.0; i <= a[0]; i++ {
+		result *= i
+	}
+	return result
+}
+
+// operations holds the registered operations by name.
+var operations = mustNewRegistry(builtinOperations)
+
+type registry struct {
+	byName map[string]Operation
+	names  []string // sorted
+}
+
+func mustNewRegistry(ops []Operation) *registry {
+	reg := &registry{byName: make(map[string]Operation)}
+	for _, op := range ops {
+		if err := reg.register(op); err != nil {
+			panic(err)
+		}
+	}
+	return reg
+}
+
+func (reg *registry) register(op Operation) error {
+	switch {
+	case op.Name == "" || strings.ToLower(op.Name) != op.Name || strings.ContainsAny(op.Name, "/. "):
+		return fmt.Errorf("operation %q: name must be a lower-case path segment", op.Name)
+	case op.Arity < 1 || op.Arity > 2:
+		return fmt.Errorf("operation %q: arity must be 1 or 2", op.Name)
+	case op.Compute == nil:
+		return fmt.Errorf("operation %q: Compute is required", op.Name)
+	case slices.Contains(reservedRoutes, op.Name):
+		return fmt.Errorf("operation %q: name is taken by another endpoint", op.Name)
+	}
+	if _, dup := reg.byName[op.Name]; dup {
+		return fmt.Errorf("operation %q: registered twice", op.Name)
+	}
+	reg.byName[op.Name] = op
+	reg.names = append(reg.names, op.Name)
+	slices.Sort(reg.names)
+	return nil
+}
+
+// reservedRoutes are the calculator endpoints that are not operations.
+var reservedRoutes = []string{"batch", "chain", "evaluate", "history", "jobs", "operations", "sessions"}
+
+// LookupOperation returns the registered operation with the given name.
+func LookupOperation(name string) (Operation, bool) {
+	op, ok := operations.byName[name]
+	return op, ok
+}
+
+// Operations returns every registered operation, sorted by name.
+func Operations() []Operation {
+	out := make([]Operation, len(operations.names))
+	for i, name := range operations.names {
+		out[i] = operations.byName[name]
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegistryRejectsInvalidOperations(t *testing.T) {
+	compute := func(a []float64) float64 { return a[0] }
+
+	tests := []struct {
+		op   Operation
+		want string
+	}{
+		{Operation{Name: "Square", Arity: 1, Compute: compute}, "lower-case path segment"},
+		{Operation{Name: "a/b", Arity: 1, Compute: compute}, "lower-case path segment"},
+		{Operation{Name: "square", Arity: 3, Compute: compute}, "arity"},
+		{Operation{Name: "square", Arity: 1}, "Compute is required"},
+		{Operation{Name: "chain", Arity: 1, Compute: compute}, "taken by another endpoint"},
+		{Operation{Name: "add", Arity: 2, Compute: compute}, "registered twice"},
+	}
+
+	for _, tt := range tests {
+		reg := mustNewRegistry(builtinOperations)
+		err := reg.register(tt.op)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("register(%q): expected error containing %q, got %v", tt.op.Name, tt.want, err)
+		}
+	}
+}
+
+func TestOperationApplyValidatesBeforeComputing(t *testing.T) {
+	divide, ok := LookupOperation("divide")
+	if !ok {
+		t.Fatal("divide is not registered")
+	}
+
+	if _, err := divide.apply(Radians, 1, 0); err == nil || err.Error() != "division by zero: 1 / 0" {
+		t.Fatalf("expected division by zero error, got %v", err)
+	}
+	if _, err := divide.apply(Radians, 1); err == nil {
+		t.Fatal("expected an arity error")
+	}
+	if got, err := divide.apply(Radians, 9, 3); err != nil || got != 3 {
+		t.Fatalf("expected 3, got %v (%v)", got, err)
+	}
+}
+
+func TestEveryBinaryOperatorIsRegistered(t *testing.T) {
+	for sym, name := range binaryOperators {
+		if op, ok := LookupOperation(name); !ok || op.Arity != 2 {
+			t.Errorf("operator %q: %q is not a registered binary operation", sym, name)
+		}
+	}
+}
+
+func TestScientificOperations(t *testing.T) {
+	tests := []struct {
+		op   string
+		unit AngleUnit
+		args []float64
+		want float64
+	}{
+		{"sqrt", Radians, []float64{16}, 4},
+		{"abs", Radians, []float64{-2.5}, 2.5},
+		{"ln", Radians, []float64{math.E}, 1},
+		{"log10", Radians, []float64{1000}, 3},
+		{"exp", Radians, []float64{0}, 1},
+		{"sin", Radians, []float64{math.Pi / 2}, 1},
+		{"sin", Degrees, []float64{30}, 0.5},
+		{"cos", Degrees, []float64{60}, 0.5},
+		{"tan", Degrees, []float64{45}, 1},
+		{"factorial", Radians, []float64{0}, 1},
+		{"factorial", Radians, []float64{10}, 3628800},
+		{"root", Radians, []float64{27, 3}, 3},
+		{"root", Radians, []float64{-32, 5}, -2},
+		{"root", Radians, []float64{16, 4}, 2},
+		{"gcd", Radians, []float64{-12, 18}, 6},
+		{"lcm", Radians, []float64{4, 6}, 12},
+		{"lcm", Radians, []float64{0, 6}, 0},
+	}
+
+	for _, tt := range tests {
+		op, ok := LookupOperation(tt.op)
+		if !ok {
+			t.Errorf("%s is not registered", tt.op)
+			continue
+		}
+		got, err := op.apply(tt.unit, tt.args...)
+		if err != nil || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s(%v) in %s = %v (%v), want %v", tt.op, tt.args, tt.unit, got, err, tt.want)
+		}
+	}
+}
+
+func TestOperationsReturnTypedDomainErrors(t *testing.T) {
+	tests := []struct {
+		op   string
+		args []float64
+		kind ErrorKind
+		msg  string
+	}{
+		{"modulo", []float64{5, 0}, ErrDivisionByZero, "division by zero: 5 / 0"},
+		{"sqrt", []float64{-4}, ErrOutOfDomain, "square root of negative number -4"},
+		{"ln", []float64{0}, ErrOutOfDomain, "logarithm of non-positive number 0"},
+		{"log10", []float64{-1}, ErrOutOfDomain, "logarithm of non-positive number -1"},
+		{"root", []float64{-16, 2}, ErrOutOfDomain, "requires an odd integer degree"},
+		{"root", []float64{8, 0}, ErrOutOfDomain, "zeroth root"},
+		{"factorial", []float64{2.5}, ErrNotInteger, "non-negative integer"},
+		{"factorial", []float64{-1}, ErrNotInteger, "non-negative integer"},
+		{"factorial", []float64{171}, ErrTooLarge, "exceeds the float64 range"},
+		{"gcd", []float64{1.5, 3}, ErrNotInteger, "gcd requires integers"},
+		{"lcm", []float64{1e300, 3}, ErrTooLarge, "exceeds"},
+	}
+
+	for _, tt := range tests {
+		op, _ := LookupOperation(tt.op)
+		_, err := op.apply(Radians, tt.args...)
+
+		var opErr *OperandError
+		if !errors.As(err, &opErr) {
+			t.Errorf("%s(%v): expected *OperandError, got %v", tt.op, tt.args, err)
+			continue
+		}
+		if opErr.Op != tt.op || opErr.Kind != tt.kind || !strings.Contains(opErr.Msg, tt.msg) {
+			t.Errorf("%s(%v) = %+v, want kind %s containing %q", tt.op, tt.args, opErr, tt.kind, tt.msg)
+		}
+	}
+}
+
+func TestAngleUnitValidate(t *testing.T) {
+	for _, u := range []AngleUnit{"", Radians, Degrees} {
+		if err := u.validate(); err != nil {
+			t.Errorf("%q: unexpected error %v", u, err)
+		}
+	}
+	if err := AngleUnit("gradians").validate(); err == nil {
+		t.Error("expected an error for gradians")
+	}
+}
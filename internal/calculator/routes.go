@@ -2,14 +2,22 @@ package calculator
 
 import "github.com/go-chi/chi/v5"
 
-// RegisterRoutes mounts all calculator endpoints onto the given router
-// under the /calculator prefix.
+// RegisterRoutes mounts all calculator endpoints onto the given router. The
+// server mounts it under the /calculator prefix (see Module). Each registered
+// Operation gets its own POST route.
 func RegisterRoutes(r chi.Router) {
-	r.Route("/calculator", func(r chi.Router) {
-		r.Post("/add", Add)
-		r.Post("/subtract", Subtract)
-		r.Post("/multiply", Multiply)
-		r.Post("/divide", Divide)
-		r.Post("/chain", Chain)
-	})
+	for _, op := range Operations() {
+		r.Post("/"+op.Name, OperationHandler(op))
+	}
+	r.Post("/chain", Chain)
+	r.Post("/batch", Batch)
+	r.Post("/jobs", SubmitJob)
+	r.Get("/jobs/{id}", GetJob)
+	r.Delete("/jobs/{id}", CancelJob)
+	r.Post("/evaluate", Evaluate)
+	r.Get("/history", History)
+	r.Post("/sessions", CreateSession)
+	r.Get("/sessions/{id}", GetSession)
+	r.Delete("/sessions/{id}", DeleteSession)
+	r.Get("/operations", ListOperations)
 }
@@ -0,0 +1,255 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultSessionTTL and MaxSessionTTL bound how long a session may stay
+	// idle before it expires.
+	DefaultSessionTTL = 30 * time.Minute
+	MaxSessionTTL     = 24 * time.Hour
+
+	// maxSessions bounds the live sessions; creating more is refused.
+	maxSessions = 10_000
+	// maxRegisters bounds the registers of one session.
+	maxRegisters = 100
+)
+
+var (
+	errSessionNotFound  = errors.New("session not found or expired")
+	errTooManySessions  = errors.New("too many active sessions")
+	errTooManyRegisters = fmt.Errorf("a session holds at most %d registers", maxRegisters)
+)
+
+var registerPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// validRegister reports whether name can name a register: an identifier of
+// up to 64 characters, referenced as "$name".
+func validRegister(name string) bool { return registerPattern.MatchString(name) }
+
+// validateStore checks the "store" field of a request using session.
+func validateStore(store, session string) error {
+	switch {
+	case store == "":
+		return nil
+	case session == "":
+		return fmt.Errorf("store %q needs a session", store)
+	case !validRegister(store):
+		return fmt.Errorf("invalid register name %q", store)
+	}
+	return nil
+}
+
+// sessions holds the sessions created by POST /calculator/sessions.
+var sessions = newSessionStore(time.Now)
+
+// session is a set of named registers shared by the requests that name it.
+type session struct {
+	id      string
+	ttl     time.Duration
+	created time.Time
+
+	mu        sync.Mutex
+	expires   time.Time
+	registers map[string]float64
+}
+
+// load returns the value of a register.
+func (s *session) load(name string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.registers[name]
+	if !ok {
+		return 0, fmt.Errorf("register $%s is not set in session %s", name, s.id)
+	}
+	return v, nil
+}
+
+// store sets a register, creating it if there is room. Registers only hold
+// finite values, so reading one never needs the checks of a request operand.
+func (s *session) store(name string, v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("cannot store %g in $%s: registers hold finite values only", v, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.registers[name]; !ok && len(s.registers) >= maxRegisters {
+		return errTooManyRegisters
+	}
+	s.registers[name] = v
+	return nil
+}
+
+// resolve returns the value of an operand: v itself, or the register it
+// names. s is nil when the request has no session.
+func (s *session) resolve(v float64, register string) (float64, error) {
+	if register == "" {
+		return v, nil
+	}
+	if s == nil {
+		return 0, fmt.Errorf("$%s needs a session", register)
+	}
+	return s.load(register)
+}
+
+// resolve replaces the first arity operands of r that name registers of s
+// with their values, noting the registers on the span in ctx.
+func (r *CalcRequest) resolve(ctx context.Context, s *session, arity int) error {
+	span := trace.SpanFromContext(ctx)
+	for i, operand := range []*float64{&r.A, &r.B}[:arity] {
+		register := r.registers[i]
+		if register == "" {
+			continue
+		}
+		v, err := s.resolve(*operand, register)
+		if err != nil {
+			return err
+		}
+		*operand = v
+		span.SetAttributes(attribute.String("calculator.operand."+string(rune('a'+i))+".register", register))
+	}
+	return nil
+}
+
+func (s *session) view() SessionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := SessionResponse{
+		ID:         s.id,
+		Registers:  make(map[string]Number, len(s.registers)),
+		TTLSeconds: int(s.ttl / time.Second),
+		CreatedAt:  s.created,
+		ExpiresAt:  s.expires,
+	}
+	for name, v := range s.registers {
+		resp.Registers[name] = Number(v)
+	}
+	return resp
+}
+
+// sessionAttrs tags the span in ctx with the session a request uses.
+func sessionAttrs(ctx context.Context, s *session) {
+	if s != nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("calculator.session.id", s.id))
+	}
+}
+
+type sessionStore struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func newSessionStore(now func() time.Time) *sessionStore {
+	return &sessionStore{now: now, sessions: make(map[string]*session)}
+}
+
+// create starts a session that expires after ttl without use.
+func (st *sessionStore) create(ctx context.Context, ttl time.Duration) (*session, error) {
+	now := st.now()
+	s := &session{
+		id:        uuid.New().String(),
+		ttl:       ttl,
+		created:   now,
+		expires:   now.Add(ttl),
+		registers: make(map[string]float64),
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.prune(ctx, now)
+	if len(st.sessions) >= maxSessions {
+		return nil, errTooManySessions
+	}
+	st.sessions[s.id] = s
+	return s, nil
+}
+
+// get returns a live session and extends its expiry. An empty id means no
+// session and returns nil.
+func (st *sessionStore) get(ctx context.Context, id string) (*session, error) {
+	if id == "" {
+		return nil, nil
+	}
+	now := st.now()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s, ok := st.sessions[id]
+	if !ok {
+		return nil, errSessionNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !now.Before(s.expires) {
+		delete(st.sessions, id)
+		sessionsExpired.Add(ctx, 1)
+		return nil, errSessionNotFound
+	}
+	s.expires = now.Add(s.ttl)
+	return s, nil
+}
+
+// delete ends a session.
+func (st *sessionStore) delete(ctx context.Context, id string) error {
+	if _, err := st.get(ctx, id); err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.sessions, id)
+	return nil
+}
+
+// active counts the sessions that have not expired.
+func (st *sessionStore) active() int {
+	now := st.now()
+
+	st.mu.Lock()
+	all := maps.Clone(st.sessions)
+	st.mu.Unlock()
+
+	n := 0
+	for _, s := range all {
+		s.mu.Lock()
+		if now.Before(s.expires) {
+			n++
+		}
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// prune forgets expired sessions. The caller holds st.mu.
+func (st *sessionStore) prune(ctx context.Context, now time.Time) {
+	for id, s := range st.sessions {
+		s.mu.Lock()
+		expired := !now.Before(s.expires)
+		s.mu.Unlock()
+		if expired {
+			delete(st.sessions, id)
+			sessionsExpired.Add(ctx, 1)
+		}
+	}
+}
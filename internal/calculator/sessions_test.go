@@ -0,0 +1,107 @@
+package calculator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionStoreExpiresIdleSessions(t *testing.T) {
+	initBatchMetrics(t)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	st := newSessionStore(func() time.Time { return now })
+	ctx := context.Background()
+
+	s, err := st.create(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each use extends the expiry by the TTL.
+	now = now.Add(50 * time.Second)
+	if _, err := st.get(ctx, s.id); err != nil {
+		t.Fatalf("expected the session to be live: %v", err)
+	}
+	now = now.Add(50 * time.Second)
+	if _, err := st.get(ctx, s.id); err != nil {
+		t.Fatalf("expected the session to be extended: %v", err)
+	}
+	if got := st.active(); got != 1 {
+		t.Fatalf("expected 1 active session, got %d", got)
+	}
+
+	now = now.Add(time.Minute)
+	if got := st.active(); got != 0 {
+		t.Errorf("expected no active sessions, got %d", got)
+	}
+	if _, err := st.get(ctx, s.id); !errors.Is(err, errSessionNotFound) {
+		t.Errorf("expected errSessionNotFound, got %v", err)
+	}
+}
+
+func TestSessionRegisters(t *testing.T) {
+	initBatchMetrics(t)
+
+	st := newSessionStore(time.Now)
+	s, _ := st.create(context.Background(), time.Minute)
+
+	if err := s.store("x", 42); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.resolve(0, "x"); err != nil || v != 42 {
+		t.Errorf("expected 42, got %g, %v", v, err)
+	}
+	if v, err := s.resolve(7, ""); err != nil || v != 7 {
+		t.Errorf("expected a literal operand to pass through, got %g, %v", v, err)
+	}
+	if _, err := s.resolve(0, "y"); err == nil || !strings.Contains(err.Error(), "$y is not set") {
+		t.Errorf("expected an unset register error, got %v", err)
+	}
+	if _, err := (*session)(nil).resolve(0, "x"); err == nil || !strings.Contains(err.Error(), "needs a session") {
+		t.Errorf("expected a missing session error, got %v", err)
+	}
+	if err := s.store("inf", math.Inf(1)); err == nil {
+		t.Error("expected storing +Inf to fail")
+	}
+
+	for i := range maxRegisters - 1 {
+		if err := s.store(string(rune('a'+i%26))+strings.Repeat("_", i/26+1), float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.store("x", 1); err != nil {
+		t.Errorf("expected overwriting a register to succeed when full, got %v", err)
+	}
+	if err := s.store("one_too_many", 1); !errors.Is(err, errTooManyRegisters) {
+		t.Errorf("expected errTooManyRegisters, got %v", err)
+	}
+}
+
+func TestRequestOperandsMayNameRegisters(t *testing.T) {
+	var calc CalcRequest
+	if err := json.Unmarshal([]byte(`{"a":"$x","b":2,"session":"s","store":"y"}`), &calc); err != nil {
+		t.Fatal(err)
+	}
+	if calc.registers != [2]string{"x", ""} || calc.B != 2 || calc.Session != "s" || calc.Store != "y" {
+		t.Errorf("unexpected request %+v", calc)
+	}
+
+	var chain ChainRequest
+	if err := json.Unmarshal([]byte(`{"initial":"$x","steps":[{"op":"add","value":"$y","store":"z"},{"op":"add","value":1}]}`), &chain); err != nil {
+		t.Fatal(err)
+	}
+	if chain.register != "x" || chain.Steps[0].register != "y" || chain.Steps[0].Store != "z" || chain.Steps[1].Value != 1 {
+		t.Errorf("unexpected chain %+v", chain)
+	}
+
+	for _, body := range []string{`{"a":"x"}`, `{"a":"$1x"}`, `{"a":"$"}`} {
+		if err := json.Unmarshal([]byte(body), &calc); err == nil {
+			t.Errorf("expected %s to be rejected", body)
+		}
+	}
+}
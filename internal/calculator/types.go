@@ -1,9 +1,104 @@
 package calculator
 
-// CalcRequest is the JSON body for binary operations (add, subtract, multiply, divide).
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AngleUnit is the unit trigonometric operands are given in.
+type AngleUnit string
+
+const (
+	Radians AngleUnit = "radians" // the default
+	Degrees AngleUnit = "degrees"
+)
+
+// CalcRequest is the JSON body for the operation endpoints. Unary operations
+// (sqrt, ln, sin, ...) read only A. A and B may also be given as "$name" to
+// read a register of the session.
 type CalcRequest struct {
-	A float64 `json:"a"`
-	B float64 `json:"b"`
+	A       float64      `json:"a"`
+	B       float64      `json:"b"`
+	Angle   AngleUnit    `json:"angle,omitempty"`         // unit of trigonometric operands
+	Policy  ResultPolicy `json:"result_policy,omitempty"` // what to do with a non-finite result
+	Session string       `json:"session,omitempty"`       // ID from POST /calculator/sessions
+	Store   string       `json:"store,omitempty"`         // register of the session to store the result in
+
+	registers [2]string // registers named by A and B, resolved by resolve
+}
+
+func (r *CalcRequest) UnmarshalJSON(data []byte) error {
+	type plain CalcRequest
+	v := struct {
+		*plain
+		A operand `json:"a"`
+		B operand `json:"b"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.A, r.B = v.A.value, v.B.value
+	r.registers = [2]string{v.A.register, v.B.register}
+	return nil
+}
+
+// operand decodes a request operand: a JSON number, or a string "$name"
+// naming a session register.
+type operand struct {
+	value    float64
+	register string
+}
+
+func (o *operand) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '"' {
+		return json.Unmarshal(data, &o.value)
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	name, ok := strings.CutPrefix(s, "$")
+	if !ok || !validRegister(name) {
+		return fmt.Errorf("invalid operand %q, expected a number or \"$name\"", s)
+	}
+	o.register = name
+	return nil
+}
+
+// Number is a result that may be non-finite under PolicyString. Finite values
+// encode as JSON numbers; ±Inf and NaN, which JSON cannot represent, as the
+// strings "+Inf", "-Inf" and "NaN".
+type Number float64
+
+func (n Number) MarshalJSON() ([]byte, error) {
+	f := float64(n)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return json.Marshal(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return json.Marshal(f)
+}
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	var f float64
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f = v
+	} else if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*n = Number(f)
+	return nil
 }
 
 // CalcResponse is the JSON response for all calculator endpoints.
@@ -11,31 +106,334 @@ type CalcResponse struct {
 	Operation string  `json:"operation"`
 	A         float64 `json:"a"`
 	B         float64 `json:"b"`
-	Result    float64 `json:"result"`
+	Result    Number  `json:"result"`
 }
 
 // ChainStep describes a single step in a chained calculation.
 type ChainStep struct {
-	Op    string  `json:"op"`    // "add", "subtract", "multiply", "divide"
-	Value float64 `json:"value"` // the operand applied with the running total
+	Op        string  `json:"op"`                   // any registered operation, e.g. "add" or "sqrt"
+	Value     float64 `json:"value"`                // the operand applied with the running total; ignored by unary operations
+	ValueFrom *int    `json:"value_from,omitempty"` // use the result of this earlier step (0-based) instead of value
+	If        string  `json:"if,omitempty"`         // run only when this condition holds, e.g. "result > 100"
+	Store     string  `json:"store,omitempty"`      // register of the session to store the step result in
+
+	register string // register named by Value, resolved when the step runs
+}
+
+func (s *ChainStep) UnmarshalJSON(data []byte) error {
+	type plain ChainStep
+	v := struct {
+		*plain
+		Value operand `json:"value"`
+	}{plain: (*plain)(s)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	s.Value, s.register = v.Value.value, v.Value.register
+	return nil
 }
 
-// ChainRequest is the JSON body for POST /calculator/chain.
+// ChainRequest is the JSON body for POST /calculator/chain. Initial and step
+// values may be given as "$name" to read a register of the session.
 type ChainRequest struct {
-	Initial float64     `json:"initial"` // starting value
-	Steps   []ChainStep `json:"steps"`
+	Initial float64      `json:"initial"` // starting value
+	Steps   []ChainStep  `json:"steps"`
+	Angle   AngleUnit    `json:"angle,omitempty"`         // unit of trigonometric operands
+	Policy  ResultPolicy `json:"result_policy,omitempty"` // applied to every step
+	Session string       `json:"session,omitempty"`       // ID from POST /calculator/sessions
+	Mode    ChainMode    `json:"mode,omitempty"`          // what a failed step does to the rest of the chain
+
+	register string // register named by Initial
+}
+
+// ChainMode decides what happens after a chain step fails.
+type ChainMode string
+
+const (
+	ChainAbort    ChainMode = "abort"    // the default: the chain stops and the request fails
+	ChainContinue ChainMode = "continue" // the step is recorded as failed and the chain goes on
+)
+
+func (r *ChainRequest) UnmarshalJSON(data []byte) error {
+	type plain ChainRequest
+	v := struct {
+		*plain
+		Initial operand `json:"initial"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.Initial, r.register = v.Initial.value, v.Initial.register
+	return nil
 }
 
 // ChainResponse is the JSON response for POST /calculator/chain.
 type ChainResponse struct {
 	Initial float64       `json:"initial"`
 	Steps   []ChainResult `json:"steps"`
-	Result  float64       `json:"result"`
+	Result  Number        `json:"result"`
+	Status  ChainStatus   `json:"status"`
+	Failed  int           `json:"failed,omitempty"`  // steps that failed in ChainContinue mode
+	Skipped int           `json:"skipped,omitempty"` // steps whose condition did not hold
 }
 
-// ChainResult records one executed step.
+// ChainStatus summarises a completed chain.
+type ChainStatus string
+
+const (
+	ChainSucceeded ChainStatus = "succeeded" // every step succeeded or was skipped
+	ChainPartial   ChainStatus = "partial"   // some steps failed in ChainContinue mode
+)
+
+// ChainResult records one step. Result is the running total after the step,
+// which skipped and failed steps leave unchanged.
 type ChainResult struct {
-	Op     string  `json:"op"`
-	Value  float64 `json:"value"`
-	Result float64 `json:"result"`
+	Op        string     `json:"op"`
+	Value     float64    `json:"value"`
+	Result    Number     `json:"result"`
+	Status    StepStatus `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	ErrorKind ErrorKind  `json:"error_kind,omitempty"`
+}
+
+// StepStatus is the outcome of a chain step.
+type StepStatus string
+
+const (
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	StepSkipped   StepStatus = "skipped"
+)
+
+// BatchItem is one independent job in a batch: a single operation (Op, A and
+// B, as for POST /calculator/<op>) or a Chain.
+type BatchItem struct {
+	Op    string        `json:"op,omitempty"`
+	A     float64       `json:"a,omitempty"`
+	B     float64       `json:"b,omitempty"`
+	Chain *ChainRequest `json:"chain,omitempty"`
+}
+
+// BatchRequest is the JSON body for POST /calculator/batch. Angle and Policy
+// apply to operation items, and to chains that do not set their own.
+type BatchRequest struct {
+	Items  []BatchItem  `json:"items"`
+	Angle  AngleUnit    `json:"angle,omitempty"`
+	Policy ResultPolicy `json:"result_policy,omitempty"`
+}
+
+// BatchResponse is the JSON response for POST /calculator/batch.
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"` // in request order
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"` // including canceled items
+}
+
+// BatchResult is the outcome of one item: Result (and Steps for chains), or
+// Error.
+type BatchResult struct {
+	Index     int           `json:"index"`
+	Result    *Number       `json:"result,omitempty"`
+	Steps     []ChainResult `json:"steps,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	ErrorKind ErrorKind     `json:"error_kind,omitempty"`
+}
+
+// JobStatus is the lifecycle state of an asynchronous job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// JobResponse is the JSON response for the /calculator/jobs endpoints. The
+// body of POST /calculator/jobs is a ChainRequest.
+type JobResponse struct {
+	ID         string         `json:"id"`
+	Status     JobStatus      `json:"status"`
+	Progress   JobProgress    `json:"progress"`
+	Result     *ChainResponse `json:"result,omitempty"` // once succeeded
+	Error      string         `json:"error,omitempty"`  // once failed or canceled
+	ErrorKind  ErrorKind      `json:"error_kind,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// JobProgress counts the chain steps a job has completed.
+type JobProgress struct {
+	CompletedSteps int `json:"completed_steps"`
+	TotalSteps     int `json:"total_steps"`
+}
+
+// Calculation is a successful calculation kept in the history.
+type Calculation struct {
+	ID         string    `json:"id"`
+	Operation  string    `json:"operation"`                // operation name, "chain" or "evaluate"
+	Operands   []Number  `json:"operands,omitempty"`       // a and b, or a chain's initial value
+	Expression string    `json:"expression,omitempty"`     // set for "evaluate"
+	Result     Number    `json:"result"`                   // float64 approximation in decimal precision
+	Decimal    string    `json:"decimal_result,omitempty"` // exact result in decimal precision
+	RequestID  string    `json:"request_id"`
+	TraceID    string    `json:"trace_id,omitempty"` // empty when the request was not traced
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// HistoryPage is a page of calculations, newest first, as returned by a
+// Repository and by GET /calculator/history.
+type HistoryPage struct {
+	Calculations []Calculation `json:"calculations"`
+	NextCursor   string        `json:"next_cursor,omitempty"` // pass as ?cursor= for the next page
+}
+
+// SessionRequest is the optional JSON body for POST /calculator/sessions.
+type SessionRequest struct {
+	TTLSeconds int `json:"ttl_seconds,omitempty"` // idle time before expiry; DefaultSessionTTL when zero
+}
+
+// SessionResponse describes a session and its registers.
+type SessionResponse struct {
+	ID         string            `json:"id"`
+	Registers  map[string]Number `json:"registers"`
+	TTLSeconds int               `json:"ttl_seconds"`
+	CreatedAt  time.Time         `json:"created_at"`
+	ExpiresAt  time.Time         `json:"expires_at"` // extended by every request using the session
+}
+
+// Precision selects the arithmetic used by the operation and chain endpoints.
+// It is set with the "precision" field or the ?precision= query parameter.
+type Precision string
+
+const (
+	PrecisionFloat   Precision = "float"   // the default: float64
+	PrecisionDecimal Precision = "decimal" // exact decimal arithmetic on strings
+)
+
+// RoundingMode rounds decimal results to their scale.
+type RoundingMode string
+
+const (
+	RoundHalfEven RoundingMode = "half-even" // the default; ties to the even digit
+	RoundHalfUp   RoundingMode = "half-up"   // ties away from zero
+	RoundFloor    RoundingMode = "floor"     // towards -Inf
+	RoundCeiling  RoundingMode = "ceiling"   // towards +Inf
+)
+
+// DecimalOptions configure decimal precision. Without a scale, results that
+// terminate are exact and others are rounded to DefaultDecimalScale digits.
+type DecimalOptions struct {
+	Precision Precision    `json:"precision,omitempty"`
+	Scale     *int         `json:"scale,omitempty"` // digits after the decimal point, 0–MaxDecimalScale
+	Rounding  RoundingMode `json:"rounding,omitempty"`
+}
+
+// DecimalCalcRequest is the JSON body for the operation endpoints in decimal
+// precision. Operands may be JSON numbers or strings; both are read exactly.
+type DecimalCalcRequest struct {
+	A     json.Number `json:"a"`
+	B     json.Number `json:"b"`
+	Angle AngleUnit   `json:"angle,omitempty"` // unit of trigonometric operands
+	DecimalOptions
+
+	// Decoded only to be rejected: sessions hold float64 registers.
+	Session string `json:"session,omitempty"`
+	Store   string `json:"store,omitempty"`
+}
+
+// DecimalCalcResponse is the JSON response for the operation endpoints in
+// decimal precision. All numbers are strings.
+type DecimalCalcResponse struct {
+	Operation string       `json:"operation"`
+	A         string       `json:"a"`
+	B         string       `json:"b,omitempty"` // omitted for unary operations
+	Result    string       `json:"result"`
+	Precision Precision    `json:"precision"`
+	Rounding  RoundingMode `json:"rounding"`
+}
+
+// DecimalChainStep is a ChainStep in decimal precision.
+type DecimalChainStep struct {
+	Op    string      `json:"op"`
+	Value json.Number `json:"value"`
+
+	// Decoded only to be rejected: decimal chains run every step in order
+	// and do not use sessions.
+	ValueFrom *int   `json:"value_from,omitempty"`
+	If        string `json:"if,omitempty"`
+	Store     string `json:"store,omitempty"`
+}
+
+// DecimalChainRequest is the JSON body for POST /calculator/chain in decimal
+// precision. Every step's result is rounded before the next step uses it.
+type DecimalChainRequest struct {
+	Initial json.Number        `json:"initial"`
+	Steps   []DecimalChainStep `json:"steps"`
+	Mode    ChainMode          `json:"mode,omitempty"`  // only ChainAbort
+	Angle   AngleUnit          `json:"angle,omitempty"` // unit of trigonometric operands
+	DecimalOptions
+
+	// Decoded only to be rejected: sessions hold float64 registers.
+	Session string `json:"session,omitempty"`
+}
+
+// DecimalChainResponse is the JSON response for POST /calculator/chain in
+// decimal precision.
+type DecimalChainResponse struct {
+	Initial   string               `json:"initial"`
+	Steps     []DecimalChainResult `json:"steps"`
+	Result    string               `json:"result"`
+	Precision Precision            `json:"precision"`
+	Rounding  RoundingMode         `json:"rounding"`
+}
+
+// DecimalChainResult records one executed decimal step.
+type DecimalChainResult struct {
+	Op     string `json:"op"`
+	Value  string `json:"value,omitempty"`
+	Result string `json:"result"`
+}
+
+// EvaluateRequest is the JSON body for POST /calculator/evaluate.
+type EvaluateRequest struct {
+	Expression string             `json:"expression"`              // e.g. "(10 + 5) * x / 2"
+	Variables  map[string]float64 `json:"variables,omitempty"`     // values for identifiers in the expression
+	Trace      bool               `json:"trace,omitempty"`         // include every evaluated subexpression
+	Angle      AngleUnit          `json:"angle,omitempty"`         // unit of trigonometric operands
+	Policy     ResultPolicy       `json:"result_policy,omitempty"` // applied to every subexpression
+	Session    string             `json:"session,omitempty"`       // ID from POST /calculator/sessions; its registers are $name
+	Store      string             `json:"store,omitempty"`         // register of the session to store the result in
+}
+
+// EvaluateResponse is the JSON response for POST /calculator/evaluate.
+type EvaluateResponse struct {
+	Expression string           `json:"expression"` // fully parenthesised form of what was evaluated
+	Result     Number           `json:"result"`
+	Trace      []EvaluationStep `json:"trace,omitempty"`
+}
+
+// EvaluationStep records one evaluated subexpression. Steps are listed in
+// evaluation order, innermost first.
+type EvaluationStep struct {
+	Expression string `json:"expression"`
+	Position   int    `json:"position"` // 1-based position in the request expression
+	Result     Number `json:"result"`
+}
+
+// OperationInfo describes a registered operation in GET /calculator/operations.
+type OperationInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Arity       int    `json:"arity"`
+	Endpoint    string `json:"endpoint"`           // POST endpoint applying the operation
+	Operator    string `json:"operator,omitempty"` // infix operator in /calculator/evaluate
+	Decimal     bool   `json:"decimal"`            // available in decimal precision
+}
+
+// OperationsResponse is the JSON response for GET /calculator/operations.
+type OperationsResponse struct {
+	Operations []OperationInfo `json:"operations"`
 }
@@ -0,0 +1,117 @@
+// Package importrules defines an analyzer that enforces the dependency rules
+// in docs/api-structure.md.
+package importrules
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "importrules",
+	Doc: `check the project's package dependency rules
+
+  internal/observability, internal/testutil, internal/lint -> no internal packages
+  internal/handlers, cmd/newdomain                         -> standard library only
+  internal/server                                          -> observability, handlers
+  internal/<domain>                                        -> observability, handlers
+
+Domains never import other domains. Test files are exempt.`,
+	Run: run,
+}
+
+// rule lists the internal packages a package may import. stdlibOnly further
+// forbids every non-standard-library import.
+type rule struct {
+	internal   []string
+	stdlibOnly bool
+}
+
+// sharedPackages are the internal packages that are not domains.
+var sharedPackages = map[string]rule{
+	"observability": {},
+	"testutil":      {},
+	"lint":          {},
+	"handlers":      {stdlibOnly: true},
+	"server":        {internal: []string{"observability", "handlers"}},
+}
+
+var domainRule = rule{internal: []string{"observability", "handlers"}}
+
+var commandRules = map[string]rule{
+	"newdomain": {stdlibOnly: true},
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	root, r, ok := ruleFor(pass.Pkg.Path())
+	if !ok {
+		return nil, nil
+	}
+
+	for _, f := range pass.Files {
+		if strings.HasSuffix(pass.Fset.File(f.Pos()).Name(), "_test.go") {
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+
+			if internal, ok := strings.CutPrefix(path, root+"/internal/"); ok {
+				top, _, _ := strings.Cut(internal, "/")
+				if !contains(r.internal, top) {
+					pass.ReportRangef(spec, "%s must not import internal/%s (see docs/api-structure.md#dependency-rules)", relPath(root, pass.Pkg.Path()), top)
+				}
+				continue
+			}
+
+			if r.stdlibOnly && !isStdlib(path) {
+				pass.ReportRangef(spec, "%s may only import the standard library, not %s", relPath(root, pass.Pkg.Path()), path)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ruleFor finds the module root of pkg and the rule that applies to it.
+func ruleFor(pkg string) (root string, r rule, ok bool) {
+	if strings.HasSuffix(pkg, ".test") || strings.HasSuffix(pkg, "_test") {
+		return "", rule{}, false // generated test main or external test package
+	}
+	if i := strings.Index(pkg, "/internal/"); i >= 0 {
+		root = pkg[:i]
+		top, _, _ := strings.Cut(pkg[i+len("/internal/"):], "/")
+		if r, ok := sharedPackages[top]; ok {
+			return root, r, true
+		}
+		return root, domainRule, true
+	}
+	if i := strings.Index(pkg, "/cmd/"); i >= 0 {
+		r, ok := commandRules[pkg[i+len("/cmd/"):]]
+		return pkg[:i], r, ok
+	}
+	return "", rule{}, false
+}
+
+func relPath(root, pkg string) string {
+	return strings.TrimPrefix(pkg, root+"/")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isStdlib reports whether path is a standard library import: its first
+// element has no dot.
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,17 @@
+package importrules_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"go-chi-observability/internal/lint/importrules"
+)
+
+// testdata is shared by all analyzers; see ../testdata/src.
+var testdata, _ = filepath.Abs(filepath.Join("..", "testdata"))
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, testdata, importrules.Analyzer, "example.com/app/internal/orders", "example.com/app/internal/handlers", "example.com/app/internal/server")
+}
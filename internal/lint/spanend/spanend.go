@@ -0,0 +1,218 @@
+// Package spanend defines an analyzer that checks spans are ended on every
+// path out of the function that started them.
+package spanend
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+)
+
+const tracePackage = "go.opentelemetry.io/otel/trace"
+
+var Analyzer = &analysis.Analyzer{
+	Name: "spanend",
+	Doc: `check that spans are ended on all paths
+
+A span returned as the second result of a call such as Tracer.Start must be
+ended, either with "defer span.End()" or with span.End() on every path to a
+return. A span that is returned, stored or passed to another function is
+assumed to be ended by its new owner.`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+
+	insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(n ast.Node) {
+		var body *ast.BlockStmt
+		var g *cfg.CFG
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			body, g = fn.Body, cfgs.FuncDecl(fn)
+		case *ast.FuncLit:
+			body, g = fn.Body, cfgs.FuncLit(fn)
+		}
+		if body == nil || g == nil {
+			return
+		}
+		checkFunc(pass, body, g)
+	})
+	return nil, nil
+}
+
+// checkFunc checks the spans started directly in body (not in nested
+// function literals, which are checked on their own).
+func checkFunc(pass *analysis.Pass, body *ast.BlockStmt, g *cfg.CFG) {
+	spans := make(map[*types.Var]ast.Node)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		var lhs []ast.Expr
+		var rhs []ast.Expr
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			lhs, rhs = n.Lhs, n.Rhs
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				lhs = append(lhs, id)
+			}
+			rhs = n.Values
+		default:
+			return true
+		}
+
+		if len(lhs) != 2 || len(rhs) != 1 || !returnsSpan(pass, rhs[0]) {
+			return true
+		}
+		id, ok := lhs[1].(*ast.Ident)
+		if !ok {
+			return true // stored in a field or element: owned elsewhere
+		}
+		if id.Name == "_" {
+			pass.ReportRangef(id, "the span returned here is discarded and can never be ended")
+			return true
+		}
+
+		v, _ := pass.TypesInfo.Defs[id].(*types.Var)
+		if v == nil {
+			v, _ = pass.TypesInfo.Uses[id].(*types.Var)
+		}
+		if v != nil {
+			spans[v] = n
+		}
+		return true
+	})
+
+	for v, stmt := range spans {
+		if ret := unendedPath(pass, g, v, stmt); ret != nil {
+			pass.ReportRangef(stmt, "span %s is not ended on all paths; add defer %s.End() or end it before each return", v.Name(), v.Name())
+
+			pos, end := ret.Pos(), ret.End()
+			if pass.Fset.File(pos) != pass.Fset.File(end) {
+				end = pos // synthetic return at the end of the function
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:     pos,
+				End:     end,
+				Message: "this return statement may be reached without ending span " + v.Name(),
+			})
+		}
+	}
+}
+
+// returnsSpan reports whether call returns a trace.Span as its second result.
+func returnsSpan(pass *analysis.Pass, expr ast.Expr) bool {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	tuple, ok := pass.TypesInfo.TypeOf(call).(*types.Tuple)
+	if !ok || tuple.Len() != 2 {
+		return false
+	}
+	return isSpan(tuple.At(1).Type())
+}
+
+func isSpan(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Name() == "Span" && obj.Pkg() != nil && obj.Pkg().Path() == tracePackage
+}
+
+// unendedPath finds a path through the CFG from stmt, which defines span v,
+// to a return that neither ends nor hands off v. It returns that return
+// statement, which may be synthetic, or nil.
+func unendedPath(pass *analysis.Pass, g *cfg.CFG, v *types.Var, stmt ast.Node) *ast.ReturnStmt {
+	memo := make(map[*cfg.Block]bool)
+	blockEnds := func(b *cfg.Block) bool {
+		res, ok := memo[b]
+		if !ok {
+			res = ends(pass, v, b.Nodes)
+			memo[b] = res
+		}
+		return res
+	}
+
+	var defblock *cfg.Block
+	var rest []ast.Node
+outer:
+	for _, b := range g.Blocks {
+		for i, n := range b.Nodes {
+			if n == stmt {
+				defblock, rest = b, b.Nodes[i+1:]
+				break outer
+			}
+		}
+	}
+	if defblock == nil {
+		return nil // e.g. a declaration the CFG does not record as a node
+	}
+
+	if ends(pass, v, rest) {
+		return nil
+	}
+	if ret := defblock.Return(); ret != nil {
+		return ret
+	}
+
+	seen := make(map[*cfg.Block]bool)
+	var search func(blocks []*cfg.Block) *ast.ReturnStmt
+	search = func(blocks []*cfg.Block) *ast.ReturnStmt {
+		for _, b := range blocks {
+			if seen[b] {
+				continue
+			}
+			seen[b] = true
+
+			if blockEnds(b) {
+				continue
+			}
+			if ret := b.Return(); ret != nil {
+				return ret
+			}
+			if ret := search(b.Succs); ret != nil {
+				return ret
+			}
+		}
+		return nil
+	}
+	return search(defblock.Succs)
+}
+
+// ends reports whether nodes call v.End (directly, deferred, or in a deferred
+// closure) or hand v off by using it other than as a method receiver.
+func ends(pass *analysis.Pass, v *types.Var, nodes []ast.Node) bool {
+	found := false
+	for _, n := range nodes {
+		ast.Inspect(n, func(n ast.Node) bool {
+			if found {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if id, ok := n.X.(*ast.Ident); ok && pass.TypesInfo.Uses[id] == v {
+					found = n.Sel.Name == "End"
+					return false // the receiver itself is not a hand-off
+				}
+			case *ast.Ident:
+				if pass.TypesInfo.Uses[n] == v {
+					found = true
+				}
+			}
+			return true
+		})
+	}
+	return found
+}
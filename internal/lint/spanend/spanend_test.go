@@ -0,0 +1,17 @@
+package spanend_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"go-chi-observability/internal/lint/spanend"
+)
+
+// testdata is shared by all analyzers; see ../testdata/src.
+var testdata, _ = filepath.Abs(filepath.Join("..", "testdata"))
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, testdata, spanend.Analyzer, "spanend")
+}
@@ -0,0 +1,169 @@
+// Package telemetrynames defines an analyzer that checks tracer, meter,
+// metric and span names follow the project's naming conventions.
+package telemetrynames
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "telemetrynames",
+	Doc: `check telemetry naming conventions
+
+Tracer and meter names passed to otel.Tracer and otel.Meter must be the
+package name. Metric and span names must be dot-separated lower-case
+segments starting with the package name ("calculator.operations.total",
+"calculator.chain"). Counters end in ".total"; other instruments must not.
+Span names built with fmt.Sprintf are checked through their format string.`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// namePattern is the shape of metric and span names.
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z0-9_]+)+$`)
+
+// verbPattern matches fmt verbs, replaced by a placeholder segment when a
+// Sprintf format is checked against namePattern.
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.*]*[a-zA-Z]`)
+
+func run(pass *analysis.Pass) (any, error) {
+	if pass.Pkg.Name() == "main" {
+		return nil, nil
+	}
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		if strings.HasSuffix(pass.Fset.File(call.Pos()).Name(), "_test.go") {
+			return
+		}
+
+		fn := typeutil.Callee(pass.TypesInfo, call)
+		f, ok := fn.(*types.Func)
+		if !ok || f.Pkg() == nil || len(call.Args) == 0 {
+			return
+		}
+
+		switch {
+		case f.Pkg().Path() == "go.opentelemetry.io/otel" && (f.Name() == "Tracer" || f.Name() == "Meter"):
+			checkScope(pass, f.Name(), call.Args[0])
+		case isMethodOf(f, "go.opentelemetry.io/otel/metric", "Meter"):
+			if kind := instrumentKind(f.Name()); kind != "" {
+				checkMetric(pass, kind, call.Args[0])
+			}
+		case isMethodOf(f, "go.opentelemetry.io/otel/trace", "Tracer") && f.Name() == "Start" && len(call.Args) > 1:
+			checkSpan(pass, call.Args[1])
+		}
+	})
+	return nil, nil
+}
+
+func isMethodOf(f *types.Func, pkg, typ string) bool {
+	recv := f.Signature().Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && named.Obj().Name() == typ && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkg
+}
+
+// instrumentKind returns "counter" for monotonic counters, "other" for the
+// remaining instrument constructors, and "" for other Meter methods.
+func instrumentKind(method string) string {
+	for _, prefix := range []string{"Int64", "Float64"} {
+		kind, ok := strings.CutPrefix(method, prefix)
+		if !ok {
+			continue
+		}
+		switch kind {
+		case "Counter", "ObservableCounter":
+			return "counter"
+		case "UpDownCounter", "ObservableUpDownCounter", "Histogram", "Gauge", "ObservableGauge":
+			return "other"
+		}
+	}
+	return ""
+}
+
+func constString(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+func checkScope(pass *analysis.Pass, kind string, arg ast.Expr) {
+	name, ok := constString(pass, arg)
+	if ok && name != pass.Pkg.Name() {
+		pass.ReportRangef(arg, "%s name %q should be the package name %q", strings.ToLower(kind), name, pass.Pkg.Name())
+	}
+}
+
+func checkMetric(pass *analysis.Pass, kind string, arg ast.Expr) {
+	name, ok := constString(pass, arg)
+	if !ok {
+		return
+	}
+	if msg := checkName(pass, name); msg != "" {
+		pass.ReportRangef(arg, "metric name %q %s", name, msg)
+		return
+	}
+	switch total := strings.HasSuffix(name, ".total"); {
+	case kind == "counter" && !total:
+		pass.ReportRangef(arg, "counter name %q should end in \".total\"", name)
+	case kind != "counter" && total:
+		pass.ReportRangef(arg, "metric name %q: only counters end in \".total\"", name)
+	}
+}
+
+func checkSpan(pass *analysis.Pass, arg ast.Expr) {
+	name, ok := constString(pass, arg)
+	if !ok {
+		format, ok := sprintfFormat(pass, arg)
+		if !ok {
+			return // built some other way; nothing to check statically
+		}
+		name = verbPattern.ReplaceAllString(format, "x")
+	}
+	if msg := checkName(pass, name); msg != "" {
+		pass.ReportRangef(arg, "span name %q %s", name, msg)
+	}
+}
+
+// checkName returns why name does not follow <package>.<segment>..., or "".
+func checkName(pass *analysis.Pass, name string) string {
+	if !namePattern.MatchString(name) {
+		return "should be dot-separated lower-case segments, e.g. \"" + pass.Pkg.Name() + ".operation\""
+	}
+	if prefix, _, _ := strings.Cut(name, "."); prefix != pass.Pkg.Name() {
+		return "should start with the package name \"" + pass.Pkg.Name() + ".\""
+	}
+	return ""
+}
+
+// sprintfFormat returns the constant format of a fmt.Sprintf call.
+func sprintfFormat(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return "", false
+	}
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "fmt" || fn.Name() != "Sprintf" {
+		return "", false
+	}
+	return constString(pass, call.Args[0])
+}
@@ -0,0 +1,31 @@
+package billing
+
+import (
+	"context"
+	"net/http"
+
+	"example.com/app/internal/observability"
+	"go.uber.org/zap"
+)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	observability.Logger.Info("no trace") // want `observability.Logger has no trace context here`
+	observability.LoggerWithTrace(r.Context()).Info("ok")
+}
+
+func withContext(ctx context.Context) {
+	zap.L().Info("global")   // want `zap.L\(\) has no trace context here`
+	zap.S().Infow("sugared") // want `zap.S\(\) has no trace context here`
+
+	go func() {
+		observability.Logger.Info("in closure") // want `observability.Logger has no trace context here`
+	}()
+}
+
+func background() {
+	observability.LoggerWithTrace(context.Background()).Info("x") // want `has no span to correlate with`
+}
+
+func startup() {
+	observability.Logger.Info("server started")
+}
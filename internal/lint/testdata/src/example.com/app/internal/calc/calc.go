@@ -0,0 +1,32 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+)
+
+var (
+	tracer = otel.Tracer("calc")
+	meter  = otel.Meter("calculator") // want `meter name "calculator" should be the package name "calc"`
+)
+
+func instruments() {
+	meter.Int64Counter("calc.operations.total")
+	meter.Int64Counter("calc.operations")         // want `counter name "calc.operations" should end in ".total"`
+	meter.Float64Histogram("calc.duration.total") // want `only counters end in ".total"`
+	meter.Int64ObservableGauge("Calc.Queue")      // want `should be dot-separated lower-case segments`
+	meter.Float64Histogram("other.duration")      // want `should start with the package name "calc."`
+}
+
+func spans(ctx context.Context, op string) {
+	_, a := tracer.Start(ctx, "calc.add")
+	defer a.End()
+	_, b := tracer.Start(ctx, fmt.Sprintf("calc.chain.step.%d.%s", 1, op))
+	defer b.End()
+	_, c := tracer.Start(ctx, "HandleAdd") // want `span name "HandleAdd" should be dot-separated`
+	defer c.End()
+	_, d := tracer.Start(ctx, fmt.Sprintf("%s", op)) // want `span name "x" should be dot-separated`
+	defer d.End()
+}
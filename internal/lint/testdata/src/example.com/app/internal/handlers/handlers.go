@@ -0,0 +1,8 @@
+package handlers
+
+import (
+	_ "net/http"
+
+	_ "example.com/app/internal/observability" // want `internal/handlers must not import internal/observability`
+	_ "go.uber.org/zap"                        // want `internal/handlers may only import the standard library`
+)
@@ -0,0 +1,12 @@
+// Package observability is a minimal stand-in for internal/observability.
+package observability
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+var Logger *zap.Logger
+
+func LoggerWithTrace(ctx context.Context) *zap.Logger { return Logger }
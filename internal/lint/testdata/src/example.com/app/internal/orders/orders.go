@@ -0,0 +1,6 @@
+package orders
+
+import (
+	_ "example.com/app/internal/calc" // want `internal/orders must not import internal/calc`
+	_ "example.com/app/internal/observability"
+)
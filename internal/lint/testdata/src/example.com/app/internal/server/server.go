@@ -0,0 +1,6 @@
+package server
+
+import (
+	_ "example.com/app/internal/observability"
+	_ "example.com/app/internal/orders" // want `internal/server must not import internal/orders`
+)
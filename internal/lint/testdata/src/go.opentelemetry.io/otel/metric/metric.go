@@ -0,0 +1,16 @@
+// Package metric is a minimal stand-in for go.opentelemetry.io/otel/metric.
+package metric
+
+type InstrumentOption interface{}
+
+type Int64Counter interface{}
+
+type Float64Histogram interface{}
+
+type Int64ObservableGauge interface{}
+
+type Meter interface {
+	Int64Counter(name string, options ...InstrumentOption) (Int64Counter, error)
+	Float64Histogram(name string, options ...InstrumentOption) (Float64Histogram, error)
+	Int64ObservableGauge(name string, options ...InstrumentOption) (Int64ObservableGauge, error)
+}
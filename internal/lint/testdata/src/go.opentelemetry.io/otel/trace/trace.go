@@ -0,0 +1,17 @@
+// Package trace is a minimal stand-in for go.opentelemetry.io/otel/trace.
+package trace
+
+import "context"
+
+type SpanStartOption interface{}
+
+type SpanEndOption interface{}
+
+type Span interface {
+	End(options ...SpanEndOption)
+	SetName(name string)
+}
+
+type Tracer interface {
+	Start(ctx context.Context, spanName string, opts ...SpanStartOption) (context.Context, Span)
+}
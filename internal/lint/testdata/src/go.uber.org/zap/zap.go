@@ -0,0 +1,14 @@
+// Package zap is a minimal stand-in for go.uber.org/zap.
+package zap
+
+type Logger struct{}
+
+func (*Logger) Info(msg string) {}
+
+type SugaredLogger struct{}
+
+func (*SugaredLogger) Infow(msg string, kv ...any) {}
+
+func L() *Logger { return nil }
+
+func S() *SugaredLogger { return nil }
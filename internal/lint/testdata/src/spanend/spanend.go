@@ -0,0 +1,68 @@
+package spanend
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("spanend")
+
+func deferred(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "spanend.deferred")
+	defer span.End()
+	_ = ctx
+}
+
+func endedOnEveryPath(ctx context.Context, fail bool) error {
+	_, span := tracer.Start(ctx, "spanend.manual")
+	if fail {
+		span.End()
+		return errors.New("failed")
+	}
+	span.End()
+	return nil
+}
+
+func missedOnErrorPath(ctx context.Context, fail bool) error {
+	_, span := tracer.Start(ctx, "spanend.leaky") // want `span span is not ended on all paths`
+	if fail {
+		return errors.New("failed") // want `this return statement may be reached without ending span span`
+	}
+	span.End()
+	return nil
+}
+
+func loop(ctx context.Context, steps []int) {
+	for _, s := range steps {
+		_, step := tracer.Start(ctx, "spanend.step") // want `span step is not ended on all paths`
+		if s < 0 {
+			continue
+		}
+		step.End()
+	}
+} // want `this return statement may be reached without ending span step`
+
+func discarded(ctx context.Context) context.Context {
+	ctx, _ = tracer.Start(ctx, "spanend.discarded") // want `the span returned here is discarded`
+	return ctx
+}
+
+func handedOff(ctx context.Context) trace.Span {
+	_, span := tracer.Start(ctx, "spanend.handoff")
+	return span
+}
+
+func deferredClosure(ctx context.Context) {
+	_, span := tracer.Start(ctx, "spanend.closure")
+	defer func() {
+		span.End()
+	}()
+}
+
+func onlyAttributes(ctx context.Context) {
+	_, span := tracer.Start(ctx, "spanend.attrs") // want `span span is not ended on all paths`
+	span.SetName("renamed")
+} // want `this return statement may be reached without ending span span`
@@ -0,0 +1,127 @@
+// Package tracelogger defines an analyzer that checks request-scoped code logs
+// through observability.LoggerWithTrace.
+package tracelogger
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const observabilitySuffix = "/internal/observability"
+
+var Analyzer = &analysis.Analyzer{
+	Name: "tracelogger",
+	Doc: `check that loggers carry trace context
+
+In a function that has a context.Context or *http.Request in scope, logging
+through observability.Logger, zap.L() or zap.S() drops trace_id and span_id.
+Use observability.LoggerWithTrace(ctx) instead. Calling LoggerWithTrace with
+context.Background() or context.TODO() is reported for the same reason.`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if strings.HasSuffix(pass.Pkg.Path(), observabilitySuffix) {
+		return nil, nil // the package that defines the loggers
+	}
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodes := []ast.Node{(*ast.SelectorExpr)(nil), (*ast.CallExpr)(nil)}
+
+	insp.WithStack(nodes, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			obj, ok := pass.TypesInfo.Uses[n.Sel].(*types.Var)
+			if ok && obj.Name() == "Logger" && isObservability(obj.Pkg()) && hasRequestScope(pass, stack) {
+				pass.ReportRangef(n, "observability.Logger has no trace context here; use observability.LoggerWithTrace(ctx)")
+			}
+
+		case *ast.CallExpr:
+			fn := typeutil.StaticCallee(pass.TypesInfo, n)
+			if fn == nil || fn.Pkg() == nil {
+				return true
+			}
+			switch {
+			case fn.Pkg().Path() == "go.uber.org/zap" && (fn.Name() == "L" || fn.Name() == "S"):
+				if hasRequestScope(pass, stack) {
+					pass.ReportRangef(n, "zap.%s() has no trace context here; use observability.LoggerWithTrace(ctx)", fn.Name())
+				}
+			case fn.Name() == "LoggerWithTrace" && isObservability(fn.Pkg()) && len(n.Args) == 1:
+				if name := emptyContext(pass, n.Args[0]); name != "" {
+					pass.ReportRangef(n.Args[0], "LoggerWithTrace(context.%s()) has no span to correlate with; pass the request context", name)
+				}
+			}
+		}
+		return true
+	})
+	return nil, nil
+}
+
+func isObservability(pkg *types.Package) bool {
+	return pkg != nil && strings.HasSuffix(pkg.Path(), observabilitySuffix)
+}
+
+// hasRequestScope reports whether any enclosing function has a
+// context.Context or *http.Request parameter.
+func hasRequestScope(pass *analysis.Pass, stack []ast.Node) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var ft *ast.FuncType
+		switch fn := stack[i].(type) {
+		case *ast.FuncDecl:
+			ft = fn.Type
+		case *ast.FuncLit:
+			ft = fn.Type
+		default:
+			continue
+		}
+		for _, field := range ft.Params.List {
+			if isRequestScoped(pass.TypesInfo.TypeOf(field.Type)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isRequestScoped(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	switch named.Obj().Pkg().Path() + "." + named.Obj().Name() {
+	case "context.Context", "net/http.Request":
+		return true
+	}
+	return false
+}
+
+// emptyContext returns "Background" or "TODO" when expr is a call to that
+// context constructor.
+func emptyContext(pass *analysis.Pass, expr ast.Expr) string {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "context" {
+		return ""
+	}
+	if fn.Name() == "Background" || fn.Name() == "TODO" {
+		return fn.Name()
+	}
+	return ""
+}
@@ -0,0 +1,17 @@
+package tracelogger_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"go-chi-observability/internal/lint/tracelogger"
+)
+
+// testdata is shared by all analyzers; see ../testdata/src.
+var testdata, _ = filepath.Abs(filepath.Join("..", "testdata"))
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, testdata, tracelogger.Analyzer, "example.com/app/internal/billing")
+}
@@ -0,0 +1,124 @@
+package observability
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// DebugLogKey marks a context whose logs are emitted at debug level
+	// regardless of LogLevel.
+	DebugLogKey contextKey = "debug_log"
+
+	// DebugLogHeader carries a signed debug token on an incoming request.
+	DebugLogHeader = "X-Debug-Log"
+
+	// DebugLogBaggageKey is the W3C baggage member that may carry the same
+	// signed token, so the elevation survives hops through other services.
+	DebugLogBaggageKey = "debug.log"
+)
+
+// ContextWithDebugLogging marks ctx for debug logging: LoggerWithTrace then
+// ignores LogLevel, the log sampler keeps every entry and the trace sampler
+// samples spans started from it.
+func ContextWithDebugLogging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, DebugLogKey, true)
+}
+
+// DebugLoggingFromContext reports whether ctx was marked by
+// ContextWithDebugLogging.
+func DebugLoggingFromContext(ctx context.Context) bool {
+	on, _ := ctx.Value(DebugLogKey).(bool)
+	return on
+}
+
+// SignDebugToken returns a token of the form "<unix-expiry>.<hex-hmac>" that
+// DebugLogMiddleware accepts until expires.
+func SignDebugToken(secret []byte, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + debugTokenMAC(secret, exp)
+}
+
+// VerifyDebugToken reports whether token was produced by SignDebugToken with
+// the same secret and has not yet expired.
+func VerifyDebugToken(secret []byte, token string, now time.Time) bool {
+	if len(secret) == 0 {
+		return false
+	}
+
+	exp, mac, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(mac), []byte(debugTokenMAC(secret, exp)))
+}
+
+func debugTokenMAC(secret []byte, payload string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(payload))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DebugLogMiddleware elevates a single request to debug logging and forces it
+// to be sampled when it carries a valid token in the X-Debug-Log header or in
+// the "debug.log" baggage member. Tokens are verified against
+// DEBUG_LOG_SECRET; when the secret is unset the middleware is a no-op.
+//
+// It must run before TracingMiddleware so the sampler sees the flag when the
+// root span is started.
+func DebugLogMiddleware(next http.Handler) http.Handler {
+	secret := []byte(os.Getenv("DEBUG_LOG_SECRET"))
+	if len(secret) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		token := r.Header.Get(DebugLogHeader)
+		if token == "" {
+			ctx := propagation.Baggage{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			token = baggage.FromContext(ctx).Member(DebugLogBaggageKey).Value()
+		}
+
+		if token != "" && VerifyDebugToken(secret, token, time.Now()) {
+			r = r.WithContext(ContextWithDebugLogging(r.Context()))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DebugAuthMiddleware guards the /debug endpoints. A request must carry a
+// token from SignDebugToken as "Authorization: Bearer <token>", verified
+// against DEBUG_LOG_SECRET; when the secret is unset every request is
+// refused.
+func DebugAuthMiddleware(next http.Handler) http.Handler {
+	secret := []byte(os.Getenv("DEBUG_LOG_SECRET"))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !VerifyDebugToken(secret, token, time.Now()) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="debug"`)
+			http.Error(w, "a valid debug token is required", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
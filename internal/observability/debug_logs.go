@@ -0,0 +1,92 @@
+package observability
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LogsHandler serves recent log entries from the in-memory buffer as JSON at
+// /debug/logs. Supported query parameters:
+//
+//	trace_id    entries on this trace
+//	request_id  entries with this request ID, plus every entry on its trace
+//	level       minimum level (debug, info, warn, error)
+//	since       RFC 3339 timestamp, or a duration such as 5m meaning "5m ago"
+//	until       RFC 3339 timestamp, or a duration
+//	limit       keep only the newest N matches
+func LogsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if logBuffer == nil {
+			writeDebugError(w, http.StatusNotFound, "log buffer disabled (set DEBUG_LOGS_ENABLED=true)")
+			return
+		}
+
+		q, err := parseLogQuery(r, time.Now())
+		if err != nil {
+			writeDebugError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		entries := logBuffer.Query(q)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"count":   len(entries),
+			"entries": entries,
+		})
+	})
+}
+
+func parseLogQuery(r *http.Request, now time.Time) (LogQuery, error) {
+	v := r.URL.Query()
+	q := LogQuery{
+		TraceID:   v.Get("trace_id"),
+		RequestID: v.Get("request_id"),
+		MinLevel:  zapcore.DebugLevel,
+	}
+
+	var err error
+	if lvl := v.Get("level"); lvl != "" {
+		if q.MinLevel, err = zapcore.ParseLevel(lvl); err != nil {
+			return q, fmt.Errorf("invalid level: %w", err)
+		}
+	}
+	if q.Since, err = parseLogTime(v.Get("since"), now); err != nil {
+		return q, fmt.Errorf("invalid since: %w", err)
+	}
+	if q.Until, err = parseLogTime(v.Get("until"), now); err != nil {
+		return q, fmt.Errorf("invalid until: %w", err)
+	}
+	if limit := v.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			return q, fmt.Errorf("invalid limit: %w", err)
+		}
+	}
+
+	return q, nil
+}
+
+func parseLogTime(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// writeDebugError mirrors handlers.WriteError, which observability cannot
+// import.
+func writeDebugError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": msg,
+	})
+}
@@ -0,0 +1,143 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestVerifyDebugToken(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Unix(1_700_000_000, 0)
+	valid := SignDebugToken(secret, now.Add(time.Minute))
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+		want   bool
+	}{
+		{name: "valid", secret: secret, token: valid, want: true},
+		{name: "expired", secret: secret, token: SignDebugToken(secret, now.Add(-time.Second)), want: false},
+		{name: "wrong secret", secret: []byte("other"), token: valid, want: false},
+		{name: "no secret", secret: nil, token: valid, want: false},
+		{name: "malformed", secret: secret, token: "not-a-token", want: false},
+		{name: "tampered expiry", secret: secret, token: "9999999999" + valid[len("1700000060"):], want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := VerifyDebugToken(tc.secret, tc.token, now); got != tc.want {
+				t.Fatalf("expected %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDebugLogMiddlewareElevatesSignedRequests(t *testing.T) {
+	t.Setenv("DEBUG_LOG_SECRET", "s3cret")
+	token := SignDebugToken([]byte("s3cret"), time.Now().Add(time.Minute))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   bool
+	}{
+		{name: "header", header: DebugLogHeader, value: token, want: true},
+		{name: "baggage", header: "baggage", value: DebugLogBaggageKey + "=" + token, want: true},
+		{name: "bad signature", header: DebugLogHeader, value: "1.abc", want: false},
+		{name: "absent", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var elevated bool
+			h := DebugLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				elevated = DebugLoggingFromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/calculator/add", nil)
+			if tc.header != "" {
+				r.Header.Set(tc.header, tc.value)
+			}
+			_ = testutil.ExecuteRequest(r, h)
+
+			if elevated != tc.want {
+				t.Fatalf("expected elevated=%t, got %t", tc.want, elevated)
+			}
+		})
+	}
+}
+
+func TestDebugAuthMiddleware(t *testing.T) {
+	token := SignDebugToken([]byte("s3cret"), time.Now().Add(time.Minute))
+
+	tests := []struct {
+		name   string
+		secret string
+		auth   string
+		want   int
+	}{
+		{name: "valid", secret: "s3cret", auth: "Bearer " + token, want: http.StatusOK},
+		{name: "missing", secret: "s3cret", want: http.StatusUnauthorized},
+		{name: "not bearer", secret: "s3cret", auth: token, want: http.StatusUnauthorized},
+		{name: "bad signature", secret: "s3cret", auth: "Bearer 9999999999.abc", want: http.StatusUnauthorized},
+		{name: "no secret", auth: "Bearer " + token, want: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DEBUG_LOG_SECRET", tc.secret)
+			h := DebugAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(http.MethodGet, "/debug/traces", nil)
+			if tc.auth != "" {
+				r.Header.Set("Authorization", tc.auth)
+			}
+			w := testutil.ExecuteRequest(r, h)
+
+			testutil.CheckResponseCode(t, tc.want, w.Code)
+		})
+	}
+}
+
+func TestLoggerWithTraceHonoursDebugElevation(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	oldLogger := Logger
+	Logger = zap.New(withLogLevel(core))
+	t.Cleanup(func() { Logger = oldLogger })
+
+	LoggerWithTrace(context.Background()).Debug("suppressed")
+	LoggerWithTrace(ContextWithDebugLogging(context.Background())).Debug("elevated")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	if entries[0].Message != "elevated" {
+		t.Fatalf("expected message %q, got %q", "elevated", entries[0].Message)
+	}
+}
+
+func TestDebugSamplerForcesSampling(t *testing.T) {
+	s := debugSampler{base: sdktrace.NeverSample()}
+
+	got := s.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background()})
+	if got.Decision != sdktrace.Drop {
+		t.Fatalf("expected base decision Drop, got %v", got.Decision)
+	}
+
+	got = s.ShouldSample(sdktrace.SamplingParameters{ParentContext: ContextWithDebugLogging(context.Background())})
+	if got.Decision != sdktrace.RecordAndSample {
+		t.Fatalf("expected RecordAndSample for debug context, got %v", got.Decision)
+	}
+}
@@ -0,0 +1,236 @@
+package observability
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracesHandler serves the zpages-style trace inspector at /debug/traces.
+//
+//	/debug/traces                       summary by route, latency bucket and status
+//	/debug/traces?route=R&bucket=N      traces in one cell (either filter optional)
+//	/debug/traces?status=error          errored traces only
+//	/debug/traces?trace_id=ID           span tree of one trace
+func TracesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if traceStore == nil {
+			http.Error(w, "trace store disabled (set DEBUG_TRACES_ENABLED=true)", http.StatusNotFound)
+			return
+		}
+
+		q := r.URL.Query()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		if id := q.Get("trace_id"); id != "" {
+			serveTraceDetail(w, id)
+			return
+		}
+
+		bucket := -1
+		if b, err := strconv.Atoi(q.Get("bucket")); err == nil {
+			bucket = b
+		}
+		serveTraceList(w, q.Get("route"), bucket, q.Get("status"))
+	})
+}
+
+type traceSummaryRow struct {
+	Route   string
+	Buckets []int
+	Errors  int
+}
+
+type traceListPage struct {
+	Buckets []string
+	Rows    []traceSummaryRow
+	Traces  []TraceSummary
+	Filter  string
+}
+
+func serveTraceList(w http.ResponseWriter, route string, bucket int, status string) {
+	page := traceListPage{Buckets: latencyBucketLabels}
+	rows := make(map[string]*traceSummaryRow)
+
+	for _, sum := range traceStore.Summaries() {
+		row, ok := rows[sum.Route]
+		if !ok {
+			row = &traceSummaryRow{Route: sum.Route, Buckets: make([]int, len(latencyBuckets))}
+			rows[sum.Route] = row
+		}
+		row.Buckets[sum.Bucket]++
+		if sum.Error {
+			row.Errors++
+		}
+
+		if route != "" && sum.Route != route {
+			continue
+		}
+		if bucket >= 0 && sum.Bucket != bucket {
+			continue
+		}
+		if status == "error" && !sum.Error {
+			continue
+		}
+		page.Traces = append(page.Traces, sum)
+	}
+
+	for _, row := range rows {
+		page.Rows = append(page.Rows, *row)
+	}
+	sort.Slice(page.Rows, func(i, j int) bool { return page.Rows[i].Route < page.Rows[j].Route })
+
+	if route != "" || bucket >= 0 || status != "" {
+		page.Filter = "route=" + route + " bucket=" + strconv.Itoa(bucket) + " status=" + status
+	}
+
+	_ = traceListTemplate.Execute(w, page)
+}
+
+type spanRow struct {
+	Depth    int
+	Name     string
+	SpanID   string
+	Offset   time.Duration
+	Duration time.Duration
+	Error    bool
+	Status   string
+	Attrs    []string
+	Events   []string
+}
+
+type traceDetailPage struct {
+	TraceID string
+	Spans   []spanRow
+	Dropped int
+}
+
+func serveTraceDetail(w http.ResponseWriter, rawID string) {
+	id, err := trace.TraceIDFromHex(rawID)
+	if err != nil {
+		http.Error(w, "invalid trace_id", http.StatusBadRequest)
+		return
+	}
+
+	t, ok := traceStore.Trace(id)
+	if !ok {
+		http.Error(w, "trace not found", http.StatusNotFound)
+		return
+	}
+
+	page := traceDetailPage{TraceID: rawID, Dropped: t.Dropped, Spans: spanTree(t.Spans)}
+	_ = traceDetailTemplate.Execute(w, page)
+}
+
+// spanTree flattens spans depth-first in start order. Spans whose parent is
+// not in the store are treated as roots.
+func spanTree(spans []sdktrace.ReadOnlySpan) []spanRow {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	byID := make(map[trace.SpanID]bool, len(spans))
+	start := spans[0].StartTime()
+	for _, s := range spans {
+		byID[s.SpanContext().SpanID()] = true
+		if s.StartTime().Before(start) {
+			start = s.StartTime()
+		}
+	}
+
+	children := make(map[trace.SpanID][]sdktrace.ReadOnlySpan)
+	var roots []sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		if parent := s.Parent().SpanID(); byID[parent] {
+			children[parent] = append(children[parent], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+
+	byStart := func(list []sdktrace.ReadOnlySpan) {
+		sort.Slice(list, func(i, j int) bool { return list[i].StartTime().Before(list[j].StartTime()) })
+	}
+
+	var rows []spanRow
+	var walk func(s sdktrace.ReadOnlySpan, depth int)
+	walk = func(s sdktrace.ReadOnlySpan, depth int) {
+		row := spanRow{
+			Depth:    depth,
+			Name:     s.Name(),
+			SpanID:   s.SpanContext().SpanID().String(),
+			Offset:   s.StartTime().Sub(start),
+			Duration: s.EndTime().Sub(s.StartTime()),
+			Error:    s.Status().Code == codes.Error,
+			Status:   s.Status().Code.String(),
+		}
+		if s.Status().Description != "" {
+			row.Status += ": " + s.Status().Description
+		}
+		for _, kv := range s.Attributes() {
+			row.Attrs = append(row.Attrs, string(kv.Key)+"="+kv.Value.Emit())
+		}
+		for _, ev := range s.Events() {
+			row.Events = append(row.Events, ev.Time.Sub(start).String()+" "+ev.Name)
+		}
+		rows = append(rows, row)
+
+		kids := children[s.SpanContext().SpanID()]
+		byStart(kids)
+		for _, c := range kids {
+			walk(c, depth+1)
+		}
+	}
+
+	byStart(roots)
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	return rows
+}
+
+var traceTemplateFuncs = template.FuncMap{
+	"indent": func(depth int) int { return depth * 24 },
+}
+
+var traceListTemplate = template.Must(template.New("list").Funcs(traceTemplateFuncs).Parse(`<!DOCTYPE html>
+<html><head><title>/debug/traces</title>
+<style>body{font-family:monospace}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:2px 6px}.err{color:#b00}</style>
+</head><body>
+<h1>Traces</h1>
+<table>
+<tr><th>Route</th>{{range .Buckets}}<th>{{.}}</th>{{end}}<th>Errors</th></tr>
+{{range .Rows}}{{$route := .Route}}<tr><td>{{.Route}}</td>
+{{range $i, $n := .Buckets}}<td>{{if $n}}<a href="?route={{$route}}&bucket={{$i}}">{{$n}}</a>{{else}}0{{end}}</td>{{end}}
+<td>{{if .Errors}}<a class="err" href="?route={{.Route}}&status=error">{{.Errors}}</a>{{else}}0{{end}}</td></tr>
+{{end}}</table>
+<h2>{{if .Filter}}{{.Filter}}{{else}}All traces{{end}}</h2>
+<table>
+<tr><th>Start</th><th>Trace</th><th>Route</th><th>Duration</th><th>Spans</th><th>Status</th></tr>
+{{range .Traces}}<tr><td>{{.Start.Format "15:04:05.000"}}</td><td><a href="?trace_id={{.TraceID}}">{{.TraceID}}</a></td>
+<td>{{.Route}}</td><td>{{.Duration}}</td><td>{{.Spans}}</td><td>{{if .Error}}<span class="err">error</span>{{else}}ok{{end}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+var traceDetailTemplate = template.Must(template.New("detail").Funcs(traceTemplateFuncs).Parse(`<!DOCTYPE html>
+<html><head><title>trace {{.TraceID}}</title>
+<style>body{font-family:monospace}.span{margin:4px 0}.err{color:#b00}.meta{color:#666;font-size:90%}</style>
+</head><body>
+<p><a href="?">&larr; all traces</a></p>
+<h1>Trace {{.TraceID}}</h1>
+{{if .Dropped}}<p class="err">{{.Dropped}} spans dropped (per-trace limit)</p>{{end}}
+{{range .Spans}}<div class="span" style="margin-left:{{indent .Depth}}px">
+<b{{if .Error}} class="err"{{end}}>{{.Name}}</b> +{{.Offset}} {{.Duration}} [{{.Status}}] <span class="meta">{{.SpanID}}</span>
+{{range .Attrs}}<div class="meta">{{.}}</div>{{end}}
+{{range .Events}}<div class="meta">&bull; {{.}}</div>{{end}}
+</div>
+{{end}}
+</body></html>
+`))
@@ -10,14 +10,15 @@ import (
 	"go-chi-observability/internal/testutil"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
 )
 
 func TestRecordErrorWritesStandardizedErrorResponse(t *testing.T) {
-	ctx := ContextWithRequestID(context.Background(), "req-1")
-	span := trace.SpanFromContext(ctx)
-	logger := zap.NewNop()
+	tel := testutil.NewTelemetry(t)
+	ctx, span := otel.Tracer("test").Start(ContextWithRequestID(context.Background(), "req-1"), "test.op")
+	logger := tel.Logger
 
 	counter, err := otel.Meter("test").Int64Counter("test.errors.total")
 	if err != nil {
@@ -38,6 +39,8 @@ func TestRecordErrorWritesStandardizedErrorResponse(t *testing.T) {
 		w,
 	)
 
+	span.End()
+
 	resp := w.Result()
 	testutil.CheckResponseCode(t, http.StatusBadRequest, resp.StatusCode)
 
@@ -55,4 +58,12 @@ func TestRecordErrorWritesStandardizedErrorResponse(t *testing.T) {
 	if _, ok := body["request_id"]; ok {
 		t.Fatal("did not expect request_id field in JSON body")
 	}
+
+	tel.AssertSpan("test.op", nil, codes.Error)
+	tel.AssertCounter("test.errors.total", []attribute.KeyValue{attribute.String("operation", "add")}, 1)
+	tel.AssertLog("invalid request body",
+		zap.String("operation", "add"),
+		zap.String("request_id", "req-1"),
+		zap.Error(errors.New("bad json")),
+	)
 }
@@ -0,0 +1,119 @@
+package observability
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// instrumentedSpanExporter records export outcomes for the traces signal.
+type instrumentedSpanExporter struct {
+	sdktrace.SpanExporter
+}
+
+func (e instrumentedSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	spansHanded.Add(int64(len(spans)))
+
+	start := time.Now()
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	recordExport(ctx, signalTraces, len(spans), start, err)
+	return err
+}
+
+// instrumentedLogExporter records export outcomes for the logs signal.
+type instrumentedLogExporter struct {
+	sdklog.Exporter
+}
+
+func (e instrumentedLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	logsHanded.Add(int64(len(records)))
+
+	start := time.Now()
+	err := e.Exporter.Export(ctx, records)
+	recordExport(ctx, signalLogs, len(records), start, err)
+	return err
+}
+
+// countingLogProcessor counts records entering the batch processor so the
+// queue depth can be derived; the SDK does not expose it for logs.
+type countingLogProcessor struct {
+	*sdklog.BatchProcessor
+}
+
+func (p countingLogProcessor) OnEmit(ctx context.Context, r *sdklog.Record) error {
+	logsAccepted.Add(1)
+	return p.BatchProcessor.OnEmit(ctx, r)
+}
+
+// countingSpanProcessor counts spans entering the batch processor and drops
+// them itself once limit are waiting, so that queue depth and overflow drops
+// are known without the SDK's experimental self-metrics. limit is the batch
+// processor's queue size, which therefore never overflows on its own.
+type countingSpanProcessor struct {
+	sdktrace.SpanProcessor
+	limit int64
+}
+
+func (p countingSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	// The batch processor ignores unsampled spans.
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	if spansAccepted.Add(1)-spansHanded.Load() > p.limit {
+		spansAccepted.Add(-1)
+		droppedItems.Add(context.Background(), 1, metric.WithAttributes(
+			attribute.String("signal", signalTraces),
+			attribute.String("reason", "queue_full"),
+		))
+		return
+	}
+	p.SpanProcessor.OnEnd(s)
+}
+
+// instrumentedMetricExporter records export outcomes for the metrics signal,
+// counting data points as items.
+type instrumentedMetricExporter struct {
+	sdkmetric.Exporter
+}
+
+func (e instrumentedMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	start := time.Now()
+	err := e.Exporter.Export(ctx, rm)
+	recordExport(ctx, signalMetrics, countDataPoints(rm), start, err)
+	return err
+}
+
+func countDataPoints(rm *metricdata.ResourceMetrics) int {
+	n := 0
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				n += len(d.DataPoints)
+			case metricdata.Gauge[float64]:
+				n += len(d.DataPoints)
+			case metricdata.Sum[int64]:
+				n += len(d.DataPoints)
+			case metricdata.Sum[float64]:
+				n += len(d.DataPoints)
+			case metricdata.Histogram[int64]:
+				n += len(d.DataPoints)
+			case metricdata.Histogram[float64]:
+				n += len(d.DataPoints)
+			case metricdata.ExponentialHistogram[int64]:
+				n += len(d.DataPoints)
+			case metricdata.ExponentialHistogram[float64]:
+				n += len(d.DataPoints)
+			case metricdata.Summary:
+				n += len(d.DataPoints)
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,300 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"golang.org/x/sync/singleflight"
+)
+
+// healthCheckDuration records how long each check took. It is a no-op until
+// InitMetrics registers the real instrument.
+var healthCheckDuration metric.Float64Histogram = noop.Float64Histogram{}
+
+// HealthChecks is the process-wide registry behind /livez and /readyz.
+var HealthChecks = NewHealthChecker(5 * time.Second)
+
+// HealthCheck is a named probe of a dependency.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+
+	// Timeout bounds a single run of Check. Zero means 2s.
+	Timeout time.Duration
+
+	// Critical checks make the service unready when they fail; failing
+	// non-critical checks only mark it degraded.
+	Critical bool
+
+	// Liveness includes the check in /livez. Only checks whose failure means
+	// the process must be restarted belong there.
+	Liveness bool
+}
+
+// CheckResult is the outcome of one check, as reported by /livez and /readyz.
+type CheckResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Duration  float64   `json:"duration_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// HealthReport is the JSON body of /livez and /readyz.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Health report and check statuses.
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusDegraded    = "degraded"
+	HealthStatusUnavailable = "unavailable"
+
+	checkStatusPass = "pass"
+	checkStatusFail = "fail"
+)
+
+// errShuttingDown is reported by /readyz once shutdown has begun.
+var errShuttingDown = errors.New("shutting down")
+
+// HealthChecker runs registered checks concurrently, caching each result for
+// ttl so that frequent probes do not hammer dependencies. Probes arriving
+// while a stale check is running wait for it rather than running their own.
+type HealthChecker struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	checks   []HealthCheck
+	cache    map[string]CheckResult
+	inflight singleflight.Group
+
+	shuttingDown atomic.Bool
+}
+
+func NewHealthChecker(ttl time.Duration) *HealthChecker {
+	return &HealthChecker{
+		ttl:   ttl,
+		cache: make(map[string]CheckResult),
+	}
+}
+
+func initHealthMetrics(meter metric.Meter) error {
+	var err error
+
+	healthCheckDuration, err = meter.Float64Histogram("observability.health.check.duration",
+		metric.WithDescription("Duration of health checks in milliseconds"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(1, 5, 10, 50, 100, 500, 1000, 5000),
+	)
+	if err != nil {
+		return fmt.Errorf("creating health check duration histogram: %w", err)
+	}
+	return nil
+}
+
+// Register adds a check, replacing any existing check with the same name.
+func (h *HealthChecker) Register(check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.cache, check.Name)
+	for i, c := range h.checks {
+		if c.Name == check.Name {
+			h.checks[i] = check
+			return
+		}
+	}
+	h.checks = append(h.checks, check)
+}
+
+// SetShuttingDown makes /readyz report unavailable from now on, so load
+// balancers stop routing new requests while in-flight ones drain.
+func (h *HealthChecker) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness runs the checks marked Liveness.
+func (h *HealthChecker) Liveness(ctx context.Context) HealthReport {
+	return h.run(ctx, true)
+}
+
+// Readiness runs every check. The report is unavailable once shutdown has
+// begun or while any critical check fails.
+func (h *HealthChecker) Readiness(ctx context.Context) HealthReport {
+	report := h.run(ctx, false)
+	if h.shuttingDown.Load() {
+		report.Status = HealthStatusUnavailable
+		report.Checks = append([]CheckResult{{
+			Name:      "shutdown",
+			Status:    checkStatusFail,
+			Critical:  true,
+			Error:     errShuttingDown.Error(),
+			CheckedAt: time.Now(),
+		}}, report.Checks...)
+	}
+	return report
+}
+
+func (h *HealthChecker) run(ctx context.Context, livenessOnly bool) HealthReport {
+	h.mu.Lock()
+	var checks []HealthCheck
+	for _, c := range h.checks {
+		if !livenessOnly || c.Liveness {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.Unlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.result(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	report := HealthReport{Status: HealthStatusOK, Checks: results}
+	for _, r := range results {
+		if r.Status == checkStatusPass {
+			continue
+		}
+		if r.Critical {
+			report.Status = HealthStatusUnavailable
+			break
+		}
+		report.Status = HealthStatusDegraded
+	}
+	return report
+}
+
+// result returns the cached result for c, running it when the cache is stale.
+func (h *HealthChecker) result(ctx context.Context, c HealthCheck) CheckResult {
+	h.mu.Lock()
+	cached, ok := h.cache[c.Name]
+	h.mu.Unlock()
+	if ok && time.Since(cached.CheckedAt) < h.ttl {
+		return cached
+	}
+
+	res, _, _ := h.inflight.Do(c.Name, func() (any, error) {
+		return h.check(ctx, c), nil
+	})
+	return res.(CheckResult)
+}
+
+// check runs c and caches its result. The result is shared by every probe
+// waiting on it, so the check is not canceled with ctx, only by its timeout.
+func (h *HealthChecker) check(ctx context.Context, c HealthCheck) CheckResult {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runCheck(ctx, c.Check)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0
+
+	res := CheckResult{
+		Name:      c.Name,
+		Status:    checkStatusPass,
+		Critical:  c.Critical,
+		Duration:  elapsed,
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = checkStatusFail
+		res.Error = err.Error()
+	}
+
+	healthCheckDuration.Record(context.WithoutCancel(ctx), elapsed, metric.WithAttributes(
+		attribute.String("check", c.Name),
+		attribute.String("status", res.Status),
+	))
+
+	h.mu.Lock()
+	h.cache[c.Name] = res
+	h.mu.Unlock()
+	return res
+}
+
+// runCheck returns check's error, or the context's once the timeout expires
+// even if check ignores ctx.
+func runCheck(ctx context.Context, check func(context.Context) error) error {
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+}
+
+// LivenessHandler serves /livez: 503 while any liveness check fails.
+func (h *HealthChecker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, h.Liveness(r.Context()))
+	})
+}
+
+// ReadinessHandler serves /readyz: 503 during shutdown or while any critical
+// check fails, 200 (possibly "degraded") otherwise.
+func (h *HealthChecker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, h.Readiness(r.Context()))
+	})
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	status := http.StatusOK
+	if report.Status == HealthStatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// TelemetryHealthCheck reports the export health of every telemetry signal
+// (see TelemetryHealth). It is meant to be registered as non-critical: a
+// collector outage should not take the service out of rotation.
+func TelemetryHealthCheck() HealthCheck {
+	return HealthCheck{
+		Name: "telemetry",
+		Check: func(context.Context) error {
+			signals, healthy := TelemetryHealth()
+			if healthy {
+				return nil
+			}
+			for _, s := range signals {
+				if !s.Healthy {
+					return fmt.Errorf("%s export failing: %s", s.Signal, s.LastError)
+				}
+			}
+			return nil
+		},
+	}
+}
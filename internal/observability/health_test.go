@@ -0,0 +1,111 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+)
+
+func TestReadinessReflectsCriticality(t *testing.T) {
+	h := NewHealthChecker(0)
+	h.Register(HealthCheck{Name: "db", Critical: true, Check: func(context.Context) error { return nil }})
+	h.Register(HealthCheck{Name: "cache", Check: func(context.Context) error { return errors.New("connection refused") }})
+
+	report := h.Readiness(context.Background())
+	if report.Status != HealthStatusDegraded {
+		t.Fatalf("expected degraded with a failing non-critical check, got %q", report.Status)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Name != "cache" || report.Checks[0].Error != "connection refused" {
+		t.Fatalf("unexpected checks %+v", report.Checks)
+	}
+
+	h.Register(HealthCheck{Name: "db", Critical: true, Check: func(context.Context) error { return errors.New("down") }})
+	if report := h.Readiness(context.Background()); report.Status != HealthStatusUnavailable {
+		t.Fatalf("expected unavailable with a failing critical check, got %q", report.Status)
+	}
+}
+
+func TestHealthCheckTimesOutAndCaches(t *testing.T) {
+	var calls atomic.Int32
+	h := NewHealthChecker(time.Minute)
+	h.Register(HealthCheck{
+		Name:     "slow",
+		Critical: true,
+		Timeout:  10 * time.Millisecond,
+		Check: func(ctx context.Context) error {
+			calls.Add(1)
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+
+	start := time.Now()
+	report := h.Readiness(context.Background())
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("expected check to be abandoned at its timeout")
+	}
+	if report.Status != HealthStatusUnavailable {
+		t.Fatalf("expected timed-out critical check to fail readiness, got %+v", report)
+	}
+
+	h.Readiness(context.Background())
+	if calls.Load() != 1 {
+		t.Fatalf("expected cached result on second probe, got %d calls", calls.Load())
+	}
+}
+
+func TestHealthCheckRunsOncePerStaleCache(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := NewHealthChecker(time.Minute)
+	h.Register(HealthCheck{
+		Name: "db",
+		Check: func(ctx context.Context) error {
+			calls.Add(1)
+			<-release
+			return nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() { h.Readiness(context.Background()) })
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected concurrent probes to share one check, got %d calls", calls.Load())
+	}
+}
+
+func TestReadinessHandlerReturns503AfterShutdown(t *testing.T) {
+	h := NewHealthChecker(0)
+	h.Register(HealthCheck{Name: "ok", Check: func(context.Context) error { return nil }})
+
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := testutil.ExecuteRequest(r, h.ReadinessHandler())
+	testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+	h.SetShuttingDown()
+
+	w = testutil.ExecuteRequest(r, h.ReadinessHandler())
+	testutil.CheckResponseCode(t, http.StatusServiceUnavailable, w.Code)
+
+	var report HealthReport
+	testutil.DecodeJSONBody(t, w.Body, &report)
+	if report.Status != HealthStatusUnavailable || report.Checks[0].Name != "shutdown" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	w = testutil.ExecuteRequest(httptest.NewRequest(http.MethodGet, "/livez", nil), h.LivenessHandler())
+	testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,249 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// HTTPClientOptions configures NewHTTPClient. Zero values select the default
+// noted on each field.
+type HTTPClientOptions struct {
+	// Base performs the actual requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Timeout bounds each attempt, including reading the response body.
+	// Defaults to 10s.
+	Timeout time.Duration
+
+	// Retries is the number of additional attempts after a retryable
+	// failure. Zero disables retries.
+	Retries int
+
+	// InitialBackoff is the wait before the first retry; it doubles per
+	// retry up to MaxBackoff, with jitter. Defaults to 100ms and 2s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// RetryOn decides whether an attempt's outcome is retryable. Defaults to
+	// network errors and 429, 502, 503 and 504 responses.
+	RetryOn func(*http.Response, error) bool
+}
+
+// NewHTTPClient returns a client for calling other services with the same
+// observability guarantees as incoming requests:
+//
+//   - a client span per attempt with W3C trace context and baggage injected
+//     (via otelhttp), and the http.client.request.duration metric
+//   - the caller's request ID forwarded as X-Request-ID
+//   - a per-attempt timeout
+//   - retries with exponential backoff for idempotent requests, each attempt
+//     recorded as an "http.client.attempt" event on the caller's span
+//   - failures logged through LoggerWithTrace
+//
+// Requests must carry the caller's context (http.NewRequestWithContext) for
+// spans, request IDs and logs to be correlated.
+func NewHTTPClient(opts HTTPClientOptions) *http.Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 2 * time.Second
+	}
+	if opts.RetryOn == nil {
+		opts.RetryOn = defaultRetryOn
+	}
+
+	return &http.Client{
+		Transport: &retryTransport{
+			opts: opts,
+			next: requestIDTransport{next: otelhttp.NewTransport(opts.Base)},
+		},
+	}
+}
+
+func defaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		// The caller gave up; retrying cannot help.
+		return !errors.Is(err, context.Canceled)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// requestIDTransport forwards the request ID from the request context unless
+// the caller already set the header.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := RequestIDFromContext(req.Context())
+	if id == "" || req.Header.Get("X-Request-ID") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Request-ID", id)
+	return t.next.RoundTrip(req)
+}
+
+type retryTransport struct {
+	opts HTTPClientOptions
+	next http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	span := trace.SpanFromContext(ctx)
+	logger := LoggerWithTrace(ctx)
+
+	retries := 0
+	if isReplayable(req) {
+		retries = t.opts.Retries
+	}
+	backoff := t.opts.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, t.opts.Timeout)
+		attemptReq := req.Clone(attemptCtx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+		retry := attempt <= retries && ctx.Err() == nil && t.opts.RetryOn(resp, err)
+
+		attrs := []attribute.KeyValue{
+			attribute.Int("http.request.resend_count", attempt-1),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.Bool("retry", retry),
+		}
+		if err != nil {
+			attrs = append(attrs, attribute.String("error.message", err.Error()))
+		} else {
+			attrs = append(attrs, attribute.Int("http.response.status_code", resp.StatusCode))
+		}
+		span.AddEvent("http.client.attempt", trace.WithAttributes(attrs...))
+
+		if !retry {
+			if err != nil {
+				cancel()
+				logger.Error("outbound request failed",
+					zap.String("method", req.Method),
+					zap.String("host", req.URL.Host),
+					zap.String("path", req.URL.Path),
+					zap.Int("attempts", attempt),
+					zap.Error(err),
+				)
+				return nil, err
+			}
+			if resp.StatusCode >= http.StatusInternalServerError {
+				logger.Warn("outbound request returned server error",
+					zap.String("method", req.Method),
+					zap.String("host", req.URL.Host),
+					zap.String("path", req.URL.Path),
+					zap.Int("attempts", attempt),
+					zap.Int("status", resp.StatusCode),
+				)
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		wait := jitter(backoff)
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				wait = min(after, t.opts.MaxBackoff)
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		cancel()
+
+		logger.Warn("outbound request attempt failed, retrying",
+			zap.String("method", req.Method),
+			zap.String("host", req.URL.Host),
+			zap.String("path", req.URL.Path),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", wait),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, t.opts.MaxBackoff)
+	}
+}
+
+// isReplayable reports whether req may safely be sent again: its method must
+// be idempotent (or it must carry an Idempotency-Key) and its body, if any,
+// must be re-readable.
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// jitter returns a duration in [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// cancelOnClose releases the attempt's timeout context once the caller has
+// finished with the response body.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,171 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func newClientTestContext(t *testing.T) (context.Context, *tracetest.SpanRecorder, func()) {
+	t.Helper()
+
+	oldLogger, oldPropagator := Logger, otel.GetTextMapPropagator()
+	Logger = zap.NewNop()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		Logger = oldLogger
+		otel.SetTextMapPropagator(oldPropagator)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "caller")
+	ctx = ContextWithRequestID(ctx, "req-42")
+
+	return ctx, recorder, func() { span.End() }
+}
+
+func TestHTTPClientPropagatesTraceAndRequestID(t *testing.T) {
+	ctx, recorder, end := newClientTestContext(t)
+
+	var traceparent, requestID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		requestID = r.Header.Get("X-Request-ID")
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := NewHTTPClient(HTTPClientOptions{}).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	end()
+
+	testutil.CheckResponseCode(t, http.StatusOK, resp.StatusCode)
+
+	if requestID != "req-42" {
+		t.Fatalf("expected X-Request-ID %q, got %q", "req-42", requestID)
+	}
+
+	var caller, client sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		switch s.Name() {
+		case "caller":
+			caller = s
+		case "HTTP GET":
+			client = s
+		}
+	}
+	if caller == nil || client == nil {
+		t.Fatalf("expected caller and client spans, got %d spans", len(recorder.Ended()))
+	}
+	if client.Parent().SpanID() != caller.SpanContext().SpanID() {
+		t.Fatal("expected client span to be a child of the caller span")
+	}
+	if !strings.Contains(traceparent, client.SpanContext().SpanID().String()) {
+		t.Fatalf("expected traceparent to carry client span ID, got %q", traceparent)
+	}
+}
+
+func TestHTTPClientRetriesWithAttemptEvents(t *testing.T) {
+	ctx, recorder, end := newClientTestContext(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(HTTPClientOptions{Retries: 3, InitialBackoff: time.Millisecond})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	end()
+
+	if string(body) != "ok" || calls.Load() != 3 {
+		t.Fatalf("expected success on third attempt, got %q after %d calls", body, calls.Load())
+	}
+
+	var events int
+	for _, s := range recorder.Ended() {
+		if s.Name() != "caller" {
+			continue
+		}
+		for _, ev := range s.Events() {
+			if ev.Name == "http.client.attempt" {
+				events++
+			}
+		}
+	}
+	if events != 3 {
+		t.Fatalf("expected 3 attempt events, got %d", events)
+	}
+}
+
+func TestHTTPClientDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	ctx, _, end := newClientTestContext(t)
+	defer end()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(HTTPClientOptions{Retries: 3, InitialBackoff: time.Millisecond})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, strings.NewReader(`{}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	testutil.CheckResponseCode(t, http.StatusServiceUnavailable, resp.StatusCode)
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call for POST without Idempotency-Key, got %d", calls.Load())
+	}
+}
+
+func TestHTTPClientPerAttemptTimeout(t *testing.T) {
+	ctx, _, end := newClientTestContext(t)
+	defer end()
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewHTTPClient(HTTPClientOptions{Timeout: 20 * time.Millisecond})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	_, err := client.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package observability
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// logBuffer is the process-wide buffer fed by InitLogger. It is nil unless
+// DEBUG_LOGS_ENABLED is true.
+var logBuffer *LogBuffer
+
+// BufferedLog is a log entry held by a LogBuffer.
+type BufferedLog struct {
+	Time      time.Time      `json:"time"`
+	Level     string         `json:"level"`
+	Message   string         `json:"message"`
+	TraceID   string         `json:"trace_id,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+
+	level zapcore.Level
+}
+
+// LogQuery filters LogBuffer.Query. Zero values match everything.
+type LogQuery struct {
+	TraceID   string
+	RequestID string
+	MinLevel  zapcore.Level
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// LogBuffer keeps the last N entries of every level in memory, so a noisy
+// info stream cannot push out the errors worth looking at.
+type LogBuffer struct {
+	perLevel int
+
+	mu    sync.Mutex
+	rings map[zapcore.Level]*logRing
+}
+
+type logRing struct {
+	entries []BufferedLog
+	next    int
+}
+
+func NewLogBuffer(perLevel int) *LogBuffer {
+	return &LogBuffer{perLevel: perLevel, rings: make(map[zapcore.Level]*logRing)}
+}
+
+func (b *LogBuffer) add(entry BufferedLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.rings[entry.level]
+	if !ok {
+		r = &logRing{entries: make([]BufferedLog, 0, b.perLevel)}
+		b.rings[entry.level] = r
+	}
+
+	if len(r.entries) < b.perLevel {
+		r.entries = append(r.entries, entry)
+		return
+	}
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % b.perLevel
+}
+
+// Query returns matching entries in time order. Filtering by RequestID also
+// returns every entry on the traces those entries belong to, since most log
+// lines inside a request carry only the trace ID.
+func (b *LogBuffer) Query(q LogQuery) []BufferedLog {
+	b.mu.Lock()
+	var all []BufferedLog
+	for _, r := range b.rings {
+		all = append(all, r.entries...)
+	}
+	b.mu.Unlock()
+
+	traceIDs := make(map[string]bool)
+	if q.TraceID != "" {
+		traceIDs[q.TraceID] = true
+	}
+	if q.RequestID != "" {
+		for _, e := range all {
+			if e.RequestID == q.RequestID && e.TraceID != "" {
+				traceIDs[e.TraceID] = true
+			}
+		}
+	}
+
+	out := make([]BufferedLog, 0)
+	for _, e := range all {
+		if e.level < q.MinLevel {
+			continue
+		}
+		if !q.Since.IsZero() && e.Time.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && e.Time.After(q.Until) {
+			continue
+		}
+		if q.TraceID != "" || q.RequestID != "" {
+			if !traceIDs[e.TraceID] && (q.RequestID == "" || e.RequestID != q.RequestID) {
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[len(out)-q.Limit:]
+	}
+	return out
+}
+
+// logBufferCore writes every entry it receives into a LogBuffer. It sits
+// below the level and sampling filters, so it holds exactly what stdout does.
+type logBufferCore struct {
+	buf    *LogBuffer
+	fields []zapcore.Field
+}
+
+func newLogBufferCore(buf *LogBuffer) zapcore.Core {
+	return &logBufferCore{buf: buf}
+}
+
+func (c *logBufferCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *logBufferCore) With(fields []zapcore.Field) zapcore.Core {
+	return &logBufferCore{
+		buf:    c.buf,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *logBufferCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *logBufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	entry := BufferedLog{
+		Time:    ent.Time,
+		Level:   ent.Level.String(),
+		Message: ent.Message,
+		level:   ent.Level,
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		if ctx, ok := f.Interface.(context.Context); ok {
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				entry.TraceID = sc.TraceID().String()
+			}
+			continue
+		}
+		f.AddTo(enc)
+	}
+
+	if id, ok := enc.Fields["trace_id"].(string); ok {
+		entry.TraceID = id
+		delete(enc.Fields, "trace_id")
+	}
+	if id, ok := enc.Fields["request_id"].(string); ok {
+		entry.RequestID = id
+		delete(enc.Fields, "request_id")
+	}
+	if len(enc.Fields) > 0 {
+		entry.Fields = enc.Fields
+	}
+
+	c.buf.add(entry)
+	return nil
+}
+
+func (c *logBufferCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,132 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func newBufferedTestLogger(t *testing.T, perLevel int) *LogBuffer {
+	t.Helper()
+
+	buf := NewLogBuffer(perLevel)
+	oldLogger, oldBuffer := Logger, logBuffer
+	Logger = zap.New(newLogBufferCore(buf))
+	logBuffer = buf
+	t.Cleanup(func() { Logger, logBuffer = oldLogger, oldBuffer })
+
+	return buf
+}
+
+func TestLogBufferKeepsLastEntriesPerLevel(t *testing.T) {
+	buf := newBufferedTestLogger(t, 2)
+
+	Logger.Error("first failure")
+	for i := range 5 {
+		Logger.Info("step", zap.Int("i", i))
+	}
+
+	entries := buf.Query(LogQuery{})
+	if len(entries) != 3 {
+		t.Fatalf("expected 2 info + 1 error entries, got %d", len(entries))
+	}
+	if entries[0].Message != "first failure" {
+		t.Fatalf("expected error entry to survive info churn, got %q", entries[0].Message)
+	}
+	if got := entries[2].Fields["i"]; got != int64(4) {
+		t.Fatalf("expected newest info entry i=4, got %#v", got)
+	}
+}
+
+func TestLogBufferQueryByRequestIDFollowsTrace(t *testing.T) {
+	buf := newBufferedTestLogger(t, 10)
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0xab},
+		SpanID:  trace.SpanID{0x01},
+	}))
+	logger := LoggerWithTrace(ctx)
+
+	logger.Info("starting chained calculation", zap.String("request_id", "req-1"))
+	logger.Info("chain step completed")
+	logger.Error("chain step failed", zap.String("request_id", "req-1"))
+	Logger.Info("unrelated", zap.String("request_id", "req-2"))
+
+	entries := buf.Query(LogQuery{RequestID: "req-1"})
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries for the request's trace, got %d", len(entries))
+	}
+	wantTraceID := trace.TraceID{0xab}.String()
+	for _, e := range entries {
+		if e.TraceID != wantTraceID {
+			t.Fatalf("expected trace_id on every entry, got %q for %q", e.TraceID, e.Message)
+		}
+	}
+
+	errors := buf.Query(LogQuery{RequestID: "req-1", MinLevel: zap.ErrorLevel})
+	if len(errors) != 1 || errors[0].Message != "chain step failed" {
+		t.Fatalf("expected only the error entry, got %+v", errors)
+	}
+}
+
+func TestLogsHandlerFilters(t *testing.T) {
+	newBufferedTestLogger(t, 10)
+
+	Logger.Warn("old", zap.String("request_id", "req-1"))
+	Logger.Warn("new", zap.String("request_id", "req-2"))
+
+	r := httptest.NewRequest(http.MethodGet, "/debug/logs?request_id=req-2&level=warn&since=1m", nil)
+	w := testutil.ExecuteRequest(r, LogsHandler())
+	testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Count   int           `json:"count"`
+		Entries []BufferedLog `json:"entries"`
+	}
+	testutil.DecodeJSONBody(t, w.Body, &body)
+
+	if body.Count != 1 || body.Entries[0].Message != "new" {
+		t.Fatalf("expected only the req-2 entry, got %+v", body)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/debug/logs?level=loud", nil)
+	w = testutil.ExecuteRequest(r, LogsHandler())
+	testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+}
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseLogTime("5m", now)
+	if err != nil || !got.Equal(now.Add(-5*time.Minute)) {
+		t.Fatalf("expected 5m ago, got %v (%v)", got, err)
+	}
+
+	got, err = parseLogTime("2026-01-01T11:00:00Z", now)
+	if err != nil || !got.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("expected RFC 3339 timestamp, got %v (%v)", got, err)
+	}
+}
+
+func TestLogBufferFromEnvRejectsNonPositiveSizes(t *testing.T) {
+	t.Setenv("DEBUG_LOGS_ENABLED", "true")
+
+	for _, perLevel := range []string{"0", "-1"} {
+		t.Setenv("DEBUG_LOGS_PER_LEVEL", perLevel)
+		if buf, err := logBufferFromEnv(); err == nil {
+			t.Errorf("expected DEBUG_LOGS_PER_LEVEL=%s to be rejected, got %+v", perLevel, buf)
+		}
+	}
+
+	t.Setenv("DEBUG_LOGS_PER_LEVEL", "1")
+	if buf, err := logBufferFromEnv(); err != nil || buf == nil {
+		t.Fatalf("expected a buffer, got %v, %v", buf, err)
+	}
+}
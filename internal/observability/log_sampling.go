@@ -0,0 +1,198 @@
+package observability
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxSampledMessages bounds the sampler's per-message counters. When it is
+// exceeded the counters are reset, which only ever lets more entries through.
+const maxSampledMessages = 4096
+
+// maxSuppressedLoggers bounds the "logger" attribute of
+// observability.logs.suppressed.total; further logger names are reported as
+// "other".
+const maxSuppressedLoggers = 32
+
+// suppressedLogs counts entries dropped by the log sampler. It is a no-op
+// until InitMetrics registers the real instrument.
+var suppressedLogs metric.Int64Counter = noop.Int64Counter{}
+
+// logSampler is shared by every samplingCore derived from the process logger.
+// It is nil when sampling is disabled.
+var logSampler *sampler
+
+// LogSamplingConfig controls per-message log sampling. Within each Tick the
+// first First entries with a given level and message are logged, then every
+// Thereafter-th entry.
+type LogSamplingConfig struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+
+	// KeepSampledTraces keeps every entry whose logger carries the context of
+	// a sampled span, so exported traces always have their full log narrative.
+	// It is on by default; with an always-on trace sampler that leaves only
+	// logs outside a sampled span to the sampler.
+	KeepSampledTraces bool
+}
+
+func logSamplingConfigFromEnv() (LogSamplingConfig, bool, error) {
+	enabled, err := envBool("LOG_SAMPLING_ENABLED", true)
+	if err != nil {
+		return LogSamplingConfig{}, false, err
+	}
+
+	cfg := LogSamplingConfig{}
+	if cfg.Tick, err = envDuration("LOG_SAMPLING_TICK", time.Second); err != nil {
+		return cfg, false, err
+	}
+	if cfg.First, err = envInt("LOG_SAMPLING_FIRST", 100); err != nil {
+		return cfg, false, err
+	}
+	if cfg.Thereafter, err = envInt("LOG_SAMPLING_THEREAFTER", 100); err != nil {
+		return cfg, false, err
+	}
+	if cfg.KeepSampledTraces, err = envBool("LOG_SAMPLING_KEEP_SAMPLED_TRACES", true); err != nil {
+		return cfg, false, err
+	}
+
+	return cfg, enabled, nil
+}
+
+func initLogSamplingMetrics(meter metric.Meter) error {
+	var err error
+
+	suppressedLogs, err = meter.Int64Counter("observability.logs.suppressed.total",
+		metric.WithDescription("Log entries dropped by per-message sampling"),
+		metric.WithUnit("{entry}"),
+	)
+	return err
+}
+
+type sampleKey struct {
+	level zapcore.Level
+	msg   string
+}
+
+type sampleCount struct {
+	resetAt time.Time
+	n       int
+}
+
+type sampler struct {
+	cfg LogSamplingConfig
+
+	mu      sync.Mutex
+	counts  map[sampleKey]*sampleCount
+	loggers map[string]bool // logger names reported on suppressedLogs
+}
+
+func newSampler(cfg LogSamplingConfig) *sampler {
+	return &sampler{cfg: cfg, counts: make(map[sampleKey]*sampleCount), loggers: make(map[string]bool)}
+}
+
+// loggerLabel returns the "logger" attribute for entries of the named
+// logger: its name, "root" for the unnamed logger, or "other" once
+// maxSuppressedLoggers names have been seen.
+func (s *sampler) loggerLabel(name string) string {
+	if name == "" {
+		return "root"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loggers[name] {
+		if len(s.loggers) >= maxSuppressedLoggers {
+			return "other"
+		}
+		s.loggers[name] = true
+	}
+	return name
+}
+
+func (s *sampler) allow(ent zapcore.Entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sampleKey{level: ent.Level, msg: ent.Message}
+	c, ok := s.counts[key]
+	if !ok {
+		if len(s.counts) >= maxSampledMessages {
+			clear(s.counts)
+		}
+		c = &sampleCount{}
+		s.counts[key] = c
+	}
+
+	if !ent.Time.Before(c.resetAt) {
+		c.n = 0
+		c.resetAt = ent.Time.Add(s.cfg.Tick)
+	}
+	c.n++
+
+	if c.n <= s.cfg.First {
+		return true
+	}
+	if s.cfg.Thereafter <= 0 {
+		return false
+	}
+	return (c.n-s.cfg.First)%s.cfg.Thereafter == 0
+}
+
+// samplingCore drops repetitive entries according to its sampler. Error-level
+// entries, and entries from loggers bound to a debug-elevated or (optionally)
+// sampled trace context, bypass sampling.
+type samplingCore struct {
+	zapcore.Core
+	sampler *sampler
+	keep    bool
+}
+
+func withSampling(core zapcore.Core) zapcore.Core {
+	if logSampler == nil {
+		return core
+	}
+	return &samplingCore{Core: withoutSampling(core), sampler: logSampler}
+}
+
+func withoutSampling(core zapcore.Core) zapcore.Core {
+	if sc, ok := core.(*samplingCore); ok {
+		return sc.Core
+	}
+	return core
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	keep := c.keep
+	if ctx, ok := contextFromFields(fields); ok {
+		keep = keep || DebugLoggingFromContext(ctx) ||
+			(c.sampler.cfg.KeepSampledTraces && trace.SpanContextFromContext(ctx).IsSampled())
+	}
+
+	return &samplingCore{Core: c.Core.With(fields), sampler: c.sampler, keep: keep}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	if c.keep || ent.Level >= zapcore.ErrorLevel || c.sampler.allow(ent) {
+		return c.Core.Check(ent, ce)
+	}
+
+	suppressedLogs.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("level", ent.Level.String()),
+		attribute.String("logger", c.sampler.loggerLabel(ent.LoggerName)),
+	))
+	return ce
+}
@@ -0,0 +1,194 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newSampledTestLogger(t *testing.T, cfg LogSamplingConfig) *observer.ObservedLogs {
+	t.Helper()
+
+	core, logs := observer.New(zap.DebugLevel)
+
+	oldLogger, oldSampler := Logger, logSampler
+	logSampler = newSampler(cfg)
+	Logger = zap.New(logPipeline(core))
+	t.Cleanup(func() { Logger, logSampler = oldLogger, oldSampler })
+
+	return logs
+}
+
+func TestSamplingCoreFirstThenEveryMth(t *testing.T) {
+	logs := newSampledTestLogger(t, LogSamplingConfig{Tick: time.Hour, First: 3, Thereafter: 5})
+
+	for range 20 {
+		Logger.Info("chain step completed")
+	}
+
+	// 3 initial entries, then the 5th, 10th and 15th of the remaining 17.
+	if got := logs.FilterMessage("chain step completed").Len(); got != 6 {
+		t.Fatalf("expected 6 sampled entries, got %d", got)
+	}
+}
+
+func TestSamplingCoreCountsPerMessage(t *testing.T) {
+	logs := newSampledTestLogger(t, LogSamplingConfig{Tick: time.Hour, First: 1})
+
+	Logger.Info("a")
+	Logger.Info("a")
+	Logger.Info("b")
+
+	if got := logs.Len(); got != 2 {
+		t.Fatalf("expected 2 entries, got %d", got)
+	}
+}
+
+func TestSamplingCoreAlwaysKeepsErrors(t *testing.T) {
+	logs := newSampledTestLogger(t, LogSamplingConfig{Tick: time.Hour, First: 1})
+
+	for range 5 {
+		Logger.Error("chain step failed")
+	}
+
+	if got := logs.Len(); got != 5 {
+		t.Fatalf("expected all 5 error entries, got %d", got)
+	}
+}
+
+func TestSamplingCoreKeepsSampledAndElevatedContexts(t *testing.T) {
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{2},
+		SpanID:  trace.SpanID{2},
+	}))
+
+	tests := []struct {
+		name string
+		cfg  LogSamplingConfig
+		ctx  context.Context
+		want int
+	}{
+		{name: "sampled trace", cfg: LogSamplingConfig{Tick: time.Hour, First: 1, KeepSampledTraces: true}, ctx: sampled, want: 5},
+		{name: "sampled trace without keep", cfg: LogSamplingConfig{Tick: time.Hour, First: 1}, ctx: sampled, want: 1},
+		{name: "unsampled trace", cfg: LogSamplingConfig{Tick: time.Hour, First: 1, KeepSampledTraces: true}, ctx: unsampled, want: 1},
+		{name: "debug elevated", cfg: LogSamplingConfig{Tick: time.Hour, First: 1}, ctx: ContextWithDebugLogging(unsampled), want: 5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := newSampledTestLogger(t, tc.cfg)
+			logger := LoggerWithTrace(tc.ctx)
+
+			for range 5 {
+				logger.Info("calculator operation completed")
+			}
+
+			if got := logs.Len(); got != tc.want {
+				t.Fatalf("expected %d entries, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSamplerResetsEachTick(t *testing.T) {
+	s := newSampler(LogSamplingConfig{Tick: time.Second, First: 1})
+	now := time.Unix(0, 0)
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Message: "m", Time: now}
+
+	if !s.allow(ent) {
+		t.Fatal("expected first entry to be allowed")
+	}
+	if s.allow(ent) {
+		t.Fatal("expected second entry in the same tick to be dropped")
+	}
+
+	ent.Time = now.Add(time.Second)
+	if !s.allow(ent) {
+		t.Fatal("expected first entry of the next tick to be allowed")
+	}
+}
+
+func TestSamplingCoreKeepsSampledTracesByDefault(t *testing.T) {
+	for _, key := range []string{"LOG_SAMPLING_ENABLED", "LOG_SAMPLING_TICK", "LOG_SAMPLING_FIRST", "LOG_SAMPLING_THEREAFTER", "LOG_SAMPLING_KEEP_SAMPLED_TRACES"} {
+		t.Setenv(key, "")
+	}
+	cfg, enabled, err := logSamplingConfigFromEnv()
+	if err != nil || !enabled {
+		t.Fatalf("expected sampling enabled by default, got %t, %v", enabled, err)
+	}
+	cfg.Tick = time.Hour
+
+	reader := sdkmetric.NewManualReader()
+	oldSuppressed := suppressedLogs
+	if err := initLogSamplingMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { suppressedLogs = oldSuppressed })
+
+	logs := newSampledTestLogger(t, cfg)
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{2},
+		SpanID:  trace.SpanID{2},
+	}))
+
+	for range cfg.First + 10 {
+		LoggerWithTrace(sampled).Named("calculator").Info("chain step completed")
+	}
+	if got := logs.Len(); got != cfg.First+10 {
+		t.Fatalf("expected every entry of a sampled trace, got %d", got)
+	}
+
+	logs.TakeAll()
+	for range cfg.First + 10 {
+		LoggerWithTrace(unsampled).Named("calculator").Info("chain step completed")
+	}
+	if got := logs.Len(); got != cfg.First {
+		t.Fatalf("expected the first %d entries outside a sampled trace, got %d", cfg.First, got)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	suppressed := sumInt64(t, rm, "observability.logs.suppressed.total")
+	key := attribute.NewSet(attribute.String("level", "info"), attribute.String("logger", "calculator"))
+	if suppressed[key] != 10 {
+		t.Fatalf("expected 10 suppressed calculator entries, got %v", suppressed)
+	}
+}
+
+func TestSamplerBoundsLoggerLabels(t *testing.T) {
+	s := newSampler(LogSamplingConfig{})
+
+	if got := s.loggerLabel(""); got != "root" {
+		t.Errorf("expected the unnamed logger as root, got %q", got)
+	}
+	for i := range maxSuppressedLoggers {
+		s.loggerLabel(fmt.Sprint("logger", i))
+	}
+	if got := s.loggerLabel("one.too.many"); got != "other" {
+		t.Errorf("expected other past the limit, got %q", got)
+	}
+	if got := s.loggerLabel("logger0"); got != "logger0" {
+		t.Errorf("expected a known name to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// spanEventCore mirrors warn and error entries onto the span carried by the
+// "context" field that LoggerWithTrace embeds, so the log narrative is visible
+// inline in the trace view even when the logs backend is unavailable.
+//
+// Loggers without a recording span in their context are disabled, so the
+// core costs nothing on startup or background logging.
+type spanEventCore struct {
+	span   trace.Span
+	fields []zapcore.Field
+}
+
+func newSpanEventCore() zapcore.Core {
+	return &spanEventCore{}
+}
+
+func (c *spanEventCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= zapcore.WarnLevel && c.span != nil && c.span.IsRecording()
+}
+
+func (c *spanEventCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &spanEventCore{
+		span:   c.span,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+	if ctx, ok := contextFromFields(fields); ok {
+		clone.span = trace.SpanFromContext(ctx)
+	}
+	return clone
+}
+
+func (c *spanEventCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *spanEventCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		if _, ok := f.Interface.(context.Context); ok {
+			continue
+		}
+		f.AddTo(enc)
+	}
+	// Already present on the span itself.
+	delete(enc.Fields, "trace_id")
+	delete(enc.Fields, "span_id")
+
+	attrs := make([]attribute.KeyValue, 0, len(enc.Fields)+1)
+	attrs = append(attrs, attribute.String("log.severity", ent.Level.String()))
+	for k, v := range enc.Fields {
+		attrs = append(attrs, logFieldAttribute(k, v))
+	}
+
+	c.span.AddEvent(ent.Message, trace.WithTimestamp(ent.Time), trace.WithAttributes(attrs...))
+	return nil
+}
+
+func (c *spanEventCore) Sync() error {
+	return nil
+}
+
+func logFieldAttribute(key string, v any) attribute.KeyValue {
+	switch v := v.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
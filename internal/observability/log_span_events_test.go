@@ -0,0 +1,74 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestSpanEventCoreMirrorsWarnAndErrorEntries(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	oldLogger := Logger
+	Logger = zap.New(newSpanEventCore())
+	t.Cleanup(func() { Logger = oldLogger })
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "calculator.divide")
+	logger := LoggerWithTrace(ctx).With(zap.String("operation", "divide"))
+
+	logger.Info("calculator operation completed")
+	logger.Warn("slow operation", zap.Float64("duration_ms", 12.5))
+	logger.Error("division failed", zap.Error(errors.New("division by zero")))
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	events := spans[0].Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	if events[0].Name != "slow operation" || events[1].Name != "division failed" {
+		t.Fatalf("unexpected event names %q, %q", events[0].Name, events[1].Name)
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		"log.severity": attribute.StringValue("error"),
+		"operation":    attribute.StringValue("divide"),
+		"error":        attribute.StringValue("division by zero"),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range events[1].Attributes {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("attribute %q: expected %v, got %v", k, v.Emit(), got[k].Emit())
+		}
+	}
+	for _, k := range []attribute.Key{"context", "trace_id", "span_id"} {
+		if _, ok := got[k]; ok {
+			t.Fatalf("did not expect attribute %q on span event", k)
+		}
+	}
+}
+
+func TestSpanEventCoreDisabledWithoutSpan(t *testing.T) {
+	core := newSpanEventCore()
+	if core.Enabled(zap.ErrorLevel) {
+		t.Fatal("expected core without span context to be disabled")
+	}
+
+	if core.With([]zap.Field{zap.Any("context", context.Background())}).Enabled(zap.ErrorLevel) {
+		t.Fatal("expected core with non-recording span to be disabled")
+	}
+}
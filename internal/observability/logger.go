@@ -2,24 +2,116 @@ package observability
 
 import (
 	"context"
+	"fmt"
+	"os"
 
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 var Logger *zap.Logger
 
+// LogLevel is the minimum level for every logger that has not been elevated
+// with DebugLogMiddleware. It is read from LOG_LEVEL by InitLogger and can be
+// changed at runtime.
+var LogLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+
 func InitLogger() error {
-	var err error
+	if lvl := os.Getenv("LOG_LEVEL"); lvl != "" {
+		if err := LogLevel.UnmarshalText([]byte(lvl)); err != nil {
+			return fmt.Errorf("parse LOG_LEVEL: %w", err)
+		}
+	}
+
+	sampling, enabled, err := logSamplingConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	if enabled {
+		logSampler = newSampler(sampling)
+	}
 
-	Logger, err = zap.NewProduction()
+	spanEvents, err := envBool("LOG_SPAN_EVENTS_ENABLED", false)
 	if err != nil {
 		return err
 	}
 
+	logBuffer, err = logBufferFromEnv()
+	if err != nil {
+		return err
+	}
+
+	// The underlying cores accept every level; LogLevel is applied by the
+	// outermost levelCore so a single request can bypass it. zap's own
+	// sampler is replaced by samplingCore, which is trace-aware.
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+	cfg.Sampling = nil
+
+	base, err := cfg.Build()
+	if err != nil {
+		return err
+	}
+
+	Logger = base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if spanEvents {
+			core = zapcore.NewTee(core, newSpanEventCore())
+		}
+		if logBuffer != nil {
+			core = zapcore.NewTee(core, newLogBufferCore(logBuffer))
+		}
+		return logPipeline(core)
+	}))
+
+	// Captured before InitLogging tees in the OTLP core.
+	selfLogger = Logger
+	installOTelDiagnostics()
+
 	return nil
 }
 
+// logBufferFromEnv builds the buffer behind /debug/logs, or returns nil
+// unless DEBUG_LOGS_ENABLED is true.
+func logBufferFromEnv() (*LogBuffer, error) {
+	enabled, err := envBool("DEBUG_LOGS_ENABLED", false)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	perLevel, err := envInt("DEBUG_LOGS_PER_LEVEL", 1000)
+	if err != nil {
+		return nil, err
+	}
+	if perLevel < 1 {
+		return nil, fmt.Errorf("DEBUG_LOGS_PER_LEVEL must be at least 1, got %d", perLevel)
+	}
+
+	return NewLogBuffer(perLevel), nil
+}
+
+// logPipeline wraps the destination cores (stdout, OTLP, ...) with the
+// process-wide filters, outermost first: LogLevel, then sampling.
+func logPipeline(dest zapcore.Core) zapcore.Core {
+	return withLogLevel(withSampling(dest))
+}
+
+// logDestinations strips the filters added by logPipeline.
+func logDestinations(core zapcore.Core) zapcore.Core {
+	return withoutSampling(withoutLogLevel(core))
+}
+
+// contextFromFields returns the context embedded by LoggerWithTrace, if any.
+// Wrapping cores use it in With to learn which span a logger belongs to.
+func contextFromFields(fields []zapcore.Field) (context.Context, bool) {
+	for _, f := range fields {
+		if ctx, ok := f.Interface.(context.Context); ok {
+			return ctx, true
+		}
+	}
+	return nil, false
+}
+
 func SyncLogger() {
 	_ = Logger.Sync()
 }
@@ -42,14 +134,22 @@ func SyncLogger() {
 //
 // The human-readable trace_id / span_id string fields are kept so that stdout
 // JSON logs remain greppable without an OTel-aware tool.
+//
+// When ctx was elevated by DebugLogMiddleware the returned logger ignores
+// LogLevel and emits debug entries for that request only.
 func LoggerWithTrace(ctx context.Context) *zap.Logger {
+	logger := Logger
+	if DebugLoggingFromContext(ctx) {
+		logger = Logger.WithOptions(zap.WrapCore(withoutLogLevel))
+	}
+
 	span := trace.SpanContextFromContext(ctx)
 
 	if !span.IsValid() {
-		return Logger
+		return logger
 	}
 
-	return Logger.With(
+	return logger.With(
 		// Picked up by otelzap.Core.Write → convertField, which sets the
 		// context used in log.Logger.Emit, populating the native OTel
 		// TraceID/SpanID on the exported OTLP log record.
@@ -59,3 +159,36 @@ func LoggerWithTrace(ctx context.Context) *zap.Logger {
 		zap.String("span_id", span.SpanID().String()),
 	)
 }
+
+// levelCore gates an underlying core at LogLevel. It is kept as the outermost
+// core so that withoutLogLevel can strip it for debug-elevated requests.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func withLogLevel(core zapcore.Core) zapcore.Core {
+	return &levelCore{Core: withoutLogLevel(core), level: LogLevel}
+}
+
+func withoutLogLevel(core zapcore.Core) zapcore.Core {
+	if lc, ok := core.(*levelCore); ok {
+		return lc.Core
+	}
+	return core
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -14,7 +14,16 @@ import (
 
 func InitLogging(ctx context.Context) (func(context.Context) error, error) {
 
-	exporter, err := otlploghttp.New(ctx)
+	var exporterOpts []otlploghttp.Option
+	client, err := walHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if client != nil {
+		exporterOpts = append(exporterOpts, otlploghttp.WithHTTPClient(client))
+	}
+
+	exporter, err := otlploghttp.New(ctx, exporterOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -32,16 +41,19 @@ func InitLogging(ctx context.Context) (func(context.Context) error, error) {
 
 	provider := sdklog.NewLoggerProvider(
 		sdklog.WithResource(res),
-		sdklog.WithProcessor(
-			sdklog.NewBatchProcessor(exporter),
-		),
+		sdklog.WithProcessor(countingLogProcessor{
+			sdklog.NewBatchProcessor(instrumentedLogExporter{exporter}),
+		}),
 	)
 
 	otelCore := otelzap.NewCore(ServiceName(), otelzap.WithLoggerProvider(provider))
 
 	// Tee the existing stdout logger core with the OTel core so logs
-	// go to both stdout and the OTLP endpoint.
-	Logger = zap.New(zapcore.NewTee(Logger.Core(), otelCore))
+	// go to both stdout and the OTLP endpoint. Level and sampling are
+	// re-applied above the tee so both destinations honour them.
+	Logger = Logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return logPipeline(zapcore.NewTee(logDestinations(core), otelCore))
+	}))
 
 	return provider.Shutdown, nil
 }
@@ -2,6 +2,7 @@ package observability
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -12,19 +13,46 @@ import (
 
 func InitMetrics(ctx context.Context) (func(context.Context) error, error) {
 
-	exporter, err := otlpmetrichttp.New(ctx)
+	var exporterOpts []otlpmetrichttp.Option
+	client, err := walHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if client != nil {
+		exporterOpts = append(exporterOpts, otlpmetrichttp.WithHTTPClient(client))
+	}
+
+	exporter, err := otlpmetrichttp.New(ctx, exporterOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	provider := sdkmetric.NewMeterProvider(
 		sdkmetric.WithReader(
-			sdkmetric.NewPeriodicReader(exporter),
+			sdkmetric.NewPeriodicReader(instrumentedMetricExporter{exporter}),
 		),
 	)
 
 	otel.SetMeterProvider(provider)
 
+	meter := otel.Meter("observability")
+
+	if err := initLogSamplingMetrics(meter); err != nil {
+		return nil, fmt.Errorf("creating suppressed logs counter: %w", err)
+	}
+
+	if err := initSelfMetrics(meter); err != nil {
+		return nil, err
+	}
+
+	if err := initTailSamplingMetrics(meter); err != nil {
+		return nil, err
+	}
+
+	if err := initHealthMetrics(meter); err != nil {
+		return nil, err
+	}
+
 	return provider.Shutdown, nil
 }
 
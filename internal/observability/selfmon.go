@@ -0,0 +1,390 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// Telemetry signals, used as the "signal" attribute on self-monitoring
+// metrics and as keys in the export health report.
+const (
+	signalTraces  = "traces"
+	signalMetrics = "metrics"
+	signalLogs    = "logs"
+)
+
+// Self-monitoring instruments. They are no-ops until InitMetrics registers
+// the real instruments.
+var (
+	exportDuration   metric.Float64Histogram = noop.Float64Histogram{}
+	exportedItems    metric.Int64Counter     = noop.Int64Counter{}
+	droppedItems     metric.Int64Counter     = noop.Int64Counter{}
+	otelErrorCounter metric.Int64Counter     = noop.Int64Counter{}
+)
+
+// selfLogger writes the pipeline's own diagnostics. InitLogger points it at
+// the stdout-only logger so that failures of the OTLP log exporter cannot
+// feed back into the exporter they are reporting on.
+var selfLogger = zap.NewNop()
+
+// Log records accepted by the batch processor, handed to the exporter, and
+// dropped because the queue was full, and the same for spans, which
+// countingSpanProcessor drops before the batch processor would. The
+// differences are the queue depths.
+var (
+	logsAccepted    atomic.Int64
+	logsHanded      atomic.Int64
+	logsQueueDrops  atomic.Int64
+	spansAccepted   atomic.Int64
+	spansHanded     atomic.Int64
+	otelErrorsLimit = newErrorLimiter(30 * time.Second)
+)
+
+func initSelfMetrics(meter metric.Meter) error {
+	var err error
+
+	exportDuration, err = meter.Float64Histogram("observability.telemetry.export.duration",
+		metric.WithDescription("Duration of telemetry export calls in milliseconds"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(1, 5, 10, 50, 100, 500, 1000, 5000, 30000),
+	)
+	if err != nil {
+		return fmt.Errorf("creating export duration histogram: %w", err)
+	}
+
+	exportedItems, err = meter.Int64Counter("observability.telemetry.exported.total",
+		metric.WithDescription("Spans, metric data points and log records passed to exporters, by outcome"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating exported items counter: %w", err)
+	}
+
+	droppedItems, err = meter.Int64Counter("observability.telemetry.dropped.total",
+		metric.WithDescription("Telemetry items discarded before reaching the collector"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating dropped items counter: %w", err)
+	}
+
+	otelErrorCounter, err = meter.Int64Counter("observability.telemetry.errors.total",
+		metric.WithDescription("Errors reported by the OpenTelemetry SDK and exporters"),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating otel error counter: %w", err)
+	}
+
+	queueSize, err := meter.Int64ObservableGauge("observability.telemetry.queue.size",
+		metric.WithDescription("Spans and log records waiting in the batch processor queues"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating queue size gauge: %w", err)
+	}
+
+	logsAttrs := metric.WithAttributes(attribute.String("signal", signalLogs))
+	tracesAttrs := metric.WithAttributes(attribute.String("signal", signalTraces))
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(queueSize, max(0, logsAccepted.Load()-logsHanded.Load()-logsQueueDrops.Load()), logsAttrs)
+		o.ObserveInt64(queueSize, max(0, spansAccepted.Load()-spansHanded.Load()), tracesAttrs)
+		return nil
+	}, queueSize)
+	if err != nil {
+		return fmt.Errorf("registering queue size callback: %w", err)
+	}
+
+	return initWALMetrics(meter)
+}
+
+// recordExport updates the self-monitoring metrics and health state after an
+// exporter call.
+func recordExport(ctx context.Context, signal string, items int, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	// The caller's context may already be cancelled (e.g. a timed-out
+	// export), which must not prevent the measurement.
+	ctx = context.WithoutCancel(ctx)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0
+	attrs := metric.WithAttributes(
+		attribute.String("signal", signal),
+		attribute.String("outcome", outcome),
+	)
+	exportDuration.Record(ctx, elapsed, attrs)
+	exportedItems.Add(ctx, int64(items), attrs)
+
+	if err != nil {
+		droppedItems.Add(ctx, int64(items), metric.WithAttributes(
+			attribute.String("signal", signal),
+			attribute.String("reason", "export_failed"),
+		))
+	}
+
+	exportHealth.record(signal, time.Now(), err)
+}
+
+// installOTelDiagnostics routes SDK errors and internal warnings to
+// selfLogger, rate limited, and counts them.
+func installOTelDiagnostics() {
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		otelErrorCounter.Add(context.Background(), 1)
+		if n, ok := otelErrorsLimit.allow(err.Error(), time.Now()); ok {
+			selfLogger.Warn("opentelemetry error", zap.Error(err), zap.Int("suppressed", n))
+		}
+	}))
+	otel.SetLogger(logr.New(otelLogSink{}))
+}
+
+// errorLimiter lets one message per key through each interval and counts the
+// rest, so a dead collector produces one log line per interval rather than
+// one per export.
+type errorLimiter struct {
+	interval time.Duration
+
+	mu         sync.Mutex
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+func newErrorLimiter(interval time.Duration) *errorLimiter {
+	return &errorLimiter{
+		interval:   interval,
+		last:       make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// allow reports whether key may be logged now, and how many occurrences were
+// suppressed since it was last logged.
+func (l *errorLimiter) allow(key string, now time.Time) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if last, ok := l.last[key]; ok && now.Sub(last) < l.interval {
+		l.suppressed[key]++
+		return 0, false
+	}
+
+	if len(l.last) >= maxSampledMessages {
+		clear(l.last)
+		clear(l.suppressed)
+	}
+
+	n := l.suppressed[key]
+	l.last[key] = now
+	delete(l.suppressed, key)
+	return n, true
+}
+
+// otelLogSink receives the SDK's internal Error and Warn messages. The log
+// batch processor reports queue overflow only this way, as a "dropped log
+// records" warning.
+type otelLogSink struct {
+	values []any
+}
+
+func (otelLogSink) Init(logr.RuntimeInfo) {}
+
+// Enabled admits Warn (V(1)) and Error; Info and Debug are too chatty.
+func (otelLogSink) Enabled(level int) bool {
+	return level <= 1
+}
+
+func (s otelLogSink) Info(_ int, msg string, kv ...any) {
+	kv = append(s.values[:len(s.values):len(s.values)], kv...)
+
+	if msg == "dropped log records" {
+		if n, ok := logrValue(kv, "dropped").(uint64); ok {
+			logsQueueDrops.Add(int64(n))
+			droppedItems.Add(context.Background(), int64(n), metric.WithAttributes(
+				attribute.String("signal", signalLogs),
+				attribute.String("reason", "queue_full"),
+			))
+		}
+	}
+
+	if n, ok := otelErrorsLimit.allow(msg, time.Now()); ok {
+		selfLogger.Warn(msg, zap.Any("otel", logrFields(kv)), zap.Int("suppressed", n))
+	}
+}
+
+func (s otelLogSink) Error(err error, msg string, kv ...any) {
+	otelErrorCounter.Add(context.Background(), 1)
+	if n, ok := otelErrorsLimit.allow(msg, time.Now()); ok {
+		kv = append(s.values[:len(s.values):len(s.values)], kv...)
+		selfLogger.Error(msg, zap.Error(err), zap.Any("otel", logrFields(kv)), zap.Int("suppressed", n))
+	}
+}
+
+func (s otelLogSink) WithValues(kv ...any) logr.LogSink {
+	return otelLogSink{values: append(s.values[:len(s.values):len(s.values)], kv...)}
+}
+
+func (s otelLogSink) WithName(string) logr.LogSink {
+	return s
+}
+
+func logrValue(kv []any, key string) any {
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i] == key {
+			return kv[i+1]
+		}
+	}
+	return nil
+}
+
+func logrFields(kv []any) map[string]any {
+	out := make(map[string]any, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		out[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	return out
+}
+
+// exportHealth tracks per-signal export outcomes for /health/telemetry.
+var exportHealth = newExportTracker()
+
+// SignalHealth is the export status of one telemetry signal.
+type SignalHealth struct {
+	Signal       string     `json:"signal"`
+	Healthy      bool       `json:"healthy"`
+	LastSuccess  *time.Time `json:"last_success,omitempty"`
+	LastFailure  *time.Time `json:"last_failure,omitempty"`
+	FailingSince *time.Time `json:"failing_since,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	Buffering    bool       `json:"buffering,omitempty"`
+}
+
+type exportTracker struct {
+	mu      sync.Mutex
+	signals map[string]*SignalHealth
+}
+
+func newExportTracker() *exportTracker {
+	t := &exportTracker{signals: make(map[string]*SignalHealth)}
+	for _, s := range []string{signalTraces, signalMetrics, signalLogs} {
+		t.signals[s] = &SignalHealth{Signal: s}
+	}
+	return t
+}
+
+func (t *exportTracker) record(signal string, now time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.signals[signal]
+	if err == nil && h.Buffering {
+		// The WAL answered for the collector; the signal is healthy again
+		// only once the backlog has been delivered (markDrained).
+		return
+	}
+	if err == nil {
+		h.LastSuccess = &now
+		h.FailingSince = nil
+		h.LastError = ""
+		return
+	}
+
+	h.LastFailure = &now
+	h.LastError = err.Error()
+	if h.FailingSince == nil {
+		h.FailingSince = &now
+	}
+}
+
+// markBuffering records that an export for signal was written to the WAL
+// instead of reaching the collector.
+func (t *exportTracker) markBuffering(signal string, now time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.signals[signal]
+	if !ok {
+		return
+	}
+	h.Buffering = true
+	h.LastFailure = &now
+	h.LastError = err.Error()
+	if h.FailingSince == nil {
+		h.FailingSince = &now
+	}
+}
+
+// markDrained records that the WAL backlog has been delivered.
+func (t *exportTracker) markDrained(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, h := range t.signals {
+		if !h.Buffering {
+			continue
+		}
+		h.Buffering = false
+		h.LastSuccess = &now
+		h.FailingSince = nil
+		h.LastError = ""
+	}
+}
+
+// report returns the status of every signal. A signal is unhealthy once its
+// exports have failed continuously for longer than threshold.
+func (t *exportTracker) report(threshold time.Duration, now time.Time) ([]SignalHealth, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	healthy := true
+	out := make([]SignalHealth, 0, len(t.signals))
+	for _, s := range []string{signalTraces, signalMetrics, signalLogs} {
+		h := *t.signals[s]
+		h.Healthy = h.FailingSince == nil || now.Sub(*h.FailingSince) <= threshold
+		healthy = healthy && h.Healthy
+		out = append(out, h)
+	}
+	return out, healthy
+}
+
+// TelemetryHealth reports whether every signal has exported successfully
+// within OTEL_EXPORT_UNHEALTHY_AFTER (default 1m) of its first failure.
+func TelemetryHealth() ([]SignalHealth, bool) {
+	threshold, err := envDuration("OTEL_EXPORT_UNHEALTHY_AFTER", time.Minute)
+	if err != nil {
+		threshold = time.Minute
+	}
+	return exportHealth.report(threshold, time.Now())
+}
+
+// TelemetryHealthHandler serves the export status of each signal at
+// /health/telemetry, with 503 while any signal is unhealthy.
+func TelemetryHealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signals, healthy := TelemetryHealth()
+
+		status := http.StatusOK
+		if !healthy {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{
+			"healthy": healthy,
+			"signals": signals,
+		})
+	})
+}
@@ -0,0 +1,233 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type failingSpanExporter struct {
+	err error
+}
+
+func (e failingSpanExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return e.err
+}
+func (e failingSpanExporter) Shutdown(context.Context) error { return nil }
+
+// stalledSpanProcessor accepts spans and never exports them.
+type stalledSpanProcessor struct {
+	sdktrace.SpanProcessor
+	ended int
+}
+
+func (p *stalledSpanProcessor) OnEnd(sdktrace.ReadOnlySpan) { p.ended++ }
+
+func TestErrorLimiterAllowsOnePerInterval(t *testing.T) {
+	l := newErrorLimiter(time.Minute)
+	now := time.Unix(0, 0)
+
+	if _, ok := l.allow("connection refused", now); !ok {
+		t.Fatal("expected first occurrence to be allowed")
+	}
+	for range 3 {
+		if _, ok := l.allow("connection refused", now.Add(time.Second)); ok {
+			t.Fatal("expected repeat within interval to be suppressed")
+		}
+	}
+
+	n, ok := l.allow("connection refused", now.Add(time.Minute))
+	if !ok || n != 3 {
+		t.Fatalf("expected allowed with 3 suppressed, got ok=%t n=%d", ok, n)
+	}
+}
+
+func TestExportTrackerReportsFailuresPastThreshold(t *testing.T) {
+	tracker := newExportTracker()
+	start := time.Unix(0, 0)
+
+	tracker.record(signalTraces, start, errors.New("connection refused"))
+	tracker.record(signalTraces, start.Add(30*time.Second), errors.New("connection refused"))
+
+	if _, healthy := tracker.report(time.Minute, start.Add(45*time.Second)); !healthy {
+		t.Fatal("expected healthy while failing for less than the threshold")
+	}
+
+	signals, healthy := tracker.report(time.Minute, start.Add(2*time.Minute))
+	if healthy {
+		t.Fatal("expected unhealthy after failing for longer than the threshold")
+	}
+	if signals[0].Signal != signalTraces || signals[0].Healthy || signals[0].LastError != "connection refused" {
+		t.Fatalf("unexpected traces status %+v", signals[0])
+	}
+
+	tracker.record(signalTraces, start.Add(3*time.Minute), nil)
+	if _, healthy := tracker.report(time.Minute, start.Add(3*time.Minute)); !healthy {
+		t.Fatal("expected healthy again after a successful export")
+	}
+}
+
+func TestTelemetryHealthHandlerReturns503WhenFailing(t *testing.T) {
+	t.Setenv("OTEL_EXPORT_UNHEALTHY_AFTER", "1ns")
+
+	oldHealth := exportHealth
+	exportHealth = newExportTracker()
+	t.Cleanup(func() { exportHealth = oldHealth })
+
+	exportHealth.record(signalLogs, time.Now().Add(-time.Second), errors.New("503 Service Unavailable"))
+
+	r := httptest.NewRequest(http.MethodGet, "/health/telemetry", nil)
+	w := testutil.ExecuteRequest(r, TelemetryHealthHandler())
+
+	testutil.CheckResponseCode(t, http.StatusServiceUnavailable, w.Code)
+
+	var body struct {
+		Healthy bool           `json:"healthy"`
+		Signals []SignalHealth `json:"signals"`
+	}
+	testutil.DecodeJSONBody(t, w.Body, &body)
+	if body.Healthy || len(body.Signals) != 3 {
+		t.Fatalf("unexpected body %+v", body)
+	}
+}
+
+func TestInstrumentedSpanExporterRecordsOutcomes(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	if err := initSelfMetrics(meter); err != nil {
+		t.Fatalf("initializing self metrics: %v", err)
+	}
+	oldHealth := exportHealth
+	exportHealth = newExportTracker()
+	t.Cleanup(func() { exportHealth = oldHealth })
+
+	spans := tracetest.SpanStubs{{Name: "a"}, {Name: "b"}}.Snapshots()
+	exporter := instrumentedSpanExporter{failingSpanExporter{err: errors.New("connection refused")}}
+	if err := exporter.ExportSpans(context.Background(), spans); err == nil {
+		t.Fatal("expected export error to be returned")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+
+	dropped := sumInt64(t, rm, "observability.telemetry.dropped.total")
+	want := attribute.NewSet(attribute.String("signal", signalTraces), attribute.String("reason", "export_failed"))
+	if dropped[want] != 2 {
+		t.Fatalf("expected 2 dropped spans, got %v", dropped)
+	}
+
+	if _, healthy := exportHealth.report(0, time.Now().Add(time.Second)); healthy {
+		t.Fatal("expected failed export to be reflected in health")
+	}
+}
+
+func TestCountingSpanProcessorDropsPastLimit(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	if err := initSelfMetrics(meter); err != nil {
+		t.Fatalf("initializing self metrics: %v", err)
+	}
+	oldAccepted, oldHanded := spansAccepted.Load(), spansHanded.Load()
+	spansAccepted.Store(0)
+	spansHanded.Store(0)
+	t.Cleanup(func() {
+		spansAccepted.Store(oldAccepted)
+		spansHanded.Store(oldHanded)
+	})
+
+	next := &stalledSpanProcessor{}
+	p := countingSpanProcessor{SpanProcessor: next, limit: 2}
+	sampled := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+	for _, s := range (tracetest.SpanStubs{{SpanContext: sampled}, {SpanContext: sampled}, {SpanContext: sampled}, {}}).Snapshots() {
+		p.OnEnd(s)
+	}
+
+	if next.ended != 2 {
+		t.Fatalf("expected 2 spans passed on, got %d", next.ended)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+	dropped := sumInt64(t, rm, "observability.telemetry.dropped.total")
+	want := attribute.NewSet(attribute.String("signal", signalTraces), attribute.String("reason", "queue_full"))
+	if dropped[want] != 1 {
+		t.Fatalf("expected 1 span dropped from a full queue, got %v", dropped)
+	}
+
+	queued := gaugeInt64(t, rm, "observability.telemetry.queue.size")
+	if got := queued[attribute.NewSet(attribute.String("signal", signalTraces))]; got != 2 {
+		t.Fatalf("expected 2 queued spans, got %v", queued)
+	}
+}
+
+func TestOTelLogSinkCountsDroppedLogRecords(t *testing.T) {
+	before := logsQueueDrops.Load()
+
+	otelLogSink{}.Info(1, "dropped log records", "dropped", uint64(7))
+
+	if got := logsQueueDrops.Load() - before; got != 7 {
+		t.Fatalf("expected 7 dropped records, got %d", got)
+	}
+}
+
+func sumInt64(t *testing.T, rm metricdata.ResourceMetrics, name string) map[attribute.Set]int64 {
+	t.Helper()
+
+	out := make(map[attribute.Set]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("metric %s: expected Sum[int64], got %T", name, m.Data)
+			}
+			for _, dp := range sum.DataPoints {
+				out[dp.Attributes] = dp.Value
+			}
+		}
+	}
+	return out
+}
+
+func gaugeInt64(t *testing.T, rm metricdata.ResourceMetrics, name string) map[attribute.Set]int64 {
+	t.Helper()
+
+	out := make(map[attribute.Set]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			gauge, ok := m.Data.(metricdata.Gauge[int64])
+			if !ok {
+				t.Fatalf("metric %s: expected Gauge[int64], got %T", name, m.Data)
+			}
+			for _, dp := range gauge.DataPoints {
+				out[dp.Attributes] = dp.Value
+			}
+		}
+	}
+	return out
+}
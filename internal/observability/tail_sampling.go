@@ -0,0 +1,389 @@
+package observability
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tail sampling instruments. They are no-ops until InitMetrics registers the
+// real instruments.
+var (
+	tailDecisions metric.Int64Counter = noop.Int64Counter{}
+	tailEvicted   metric.Int64Counter = noop.Int64Counter{}
+)
+
+// tailSampler is the process-wide tail sampler installed by InitTracing. It is
+// nil when OTEL_TAIL_SAMPLING_ENABLED is false.
+var tailSampler *TailSampler
+
+// Reasons a tail sampling decision was made, used as the "reason" attribute on
+// observability.tail_sampling.decisions.total. The span_limit and evicted
+// reasons also label observability.tail_sampling.evicted.total.
+const (
+	tailReasonError     = "error"
+	tailReasonLatency   = "latency"
+	tailReasonDebug     = "debug"
+	tailReasonRatio     = "ratio"
+	tailReasonTimeout   = "timeout"
+	tailReasonSpanLimit = "span_limit"
+	tailReasonEvicted   = "evicted"
+)
+
+// TailSamplingConfig controls which buffered traces a TailSampler keeps.
+type TailSamplingConfig struct {
+	// Window is how long a trace is buffered waiting for its local root span
+	// to end. A trace still open after Window is kept as slow, so Window
+	// should be at least LatencyThreshold.
+	Window time.Duration
+
+	// LatencyThreshold keeps traces whose root span lasted at least this long.
+	LatencyThreshold time.Duration
+
+	// Ratio is the fraction of the remaining traces that are kept, chosen by
+	// trace ID so every service sampling the same trace agrees.
+	Ratio float64
+
+	// MaxTraces bounds the number of traces buffered at once. When exceeded,
+	// the oldest trace is decided early on what has been seen of it.
+	MaxTraces int
+}
+
+func tailSamplingConfigFromEnv() (TailSamplingConfig, bool, error) {
+	enabled, err := envBool("OTEL_TAIL_SAMPLING_ENABLED", false)
+	if err != nil || !enabled {
+		return TailSamplingConfig{}, false, err
+	}
+
+	cfg := TailSamplingConfig{}
+	if cfg.Window, err = envDuration("OTEL_TAIL_SAMPLING_WINDOW", 30*time.Second); err != nil {
+		return cfg, false, err
+	}
+	if cfg.LatencyThreshold, err = envDuration("OTEL_TAIL_SAMPLING_LATENCY_THRESHOLD", time.Second); err != nil {
+		return cfg, false, err
+	}
+	if cfg.Ratio, err = envFloat("OTEL_TAIL_SAMPLING_RATIO", 0.1); err != nil {
+		return cfg, false, err
+	}
+	if cfg.MaxTraces, err = envInt("OTEL_TAIL_SAMPLING_MAX_TRACES", 10000); err != nil {
+		return cfg, false, err
+	}
+
+	return cfg, true, nil
+}
+
+func initTailSamplingMetrics(meter metric.Meter) error {
+	var err error
+
+	tailDecisions, err = meter.Int64Counter("observability.tail_sampling.decisions.total",
+		metric.WithDescription("Tail sampling decisions, by decision and reason"),
+		metric.WithUnit("{trace}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating tail sampling decisions counter: %w", err)
+	}
+
+	tailEvicted, err = meter.Int64Counter("observability.tail_sampling.evicted.total",
+		metric.WithDescription("Traces decided before their root span ended, by reason: the buffer was full or the trace reached the span limit"),
+		metric.WithUnit("{trace}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating tail sampling evicted counter: %w", err)
+	}
+
+	buffered, err := meter.Int64ObservableGauge("observability.tail_sampling.buffered",
+		metric.WithDescription("Traces buffered awaiting a tail sampling decision"),
+		metric.WithUnit("{trace}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating tail sampling buffered gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if tailSampler != nil {
+			o.ObserveInt64(buffered, int64(tailSampler.Buffered()))
+		}
+		return nil
+	}, buffered)
+	if err != nil {
+		return fmt.Errorf("registering tail sampling buffered callback: %w", err)
+	}
+
+	return nil
+}
+
+// TailSampler is a span processor that buffers ended spans per trace and
+// forwards a trace to next only once it has decided to keep it. The decision
+// is made when the trace's local root span ends: traces with an error status,
+// a root lasting at least LatencyThreshold, or a span forced by debug
+// elevation are always kept, the rest by Ratio. Spans that end after the
+// decision follow it.
+type TailSampler struct {
+	next sdktrace.SpanProcessor
+	cfg  TailSamplingConfig
+
+	mu      sync.Mutex
+	pending map[trace.TraceID]*pendingTrace
+	// order lists buffered traces oldest first. Decided traces are not
+	// removed from it; oldestLocked skips them.
+	order []pendingEntry
+
+	// decided remembers recent decisions for spans that end after their
+	// trace's root, in a ring of MaxTraces entries.
+	decided      map[trace.TraceID]bool
+	decidedOrder []trace.TraceID
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type pendingEntry struct {
+	id    trace.TraceID
+	trace *pendingTrace
+}
+
+type pendingTrace struct {
+	firstSeen time.Time
+	spans     []sdktrace.ReadOnlySpan
+	hasError  bool
+	debug     bool
+}
+
+func NewTailSampler(next sdktrace.SpanProcessor, cfg TailSamplingConfig) *TailSampler {
+	s := &TailSampler{
+		next:    next,
+		cfg:     cfg,
+		pending: make(map[trace.TraceID]*pendingTrace),
+		decided: make(map[trace.TraceID]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.expireLoop()
+	return s
+}
+
+func (s *TailSampler) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	s.next.OnStart(ctx, span)
+}
+
+func (s *TailSampler) OnEnd(span sdktrace.ReadOnlySpan) {
+	id := span.SpanContext().TraceID()
+
+	s.mu.Lock()
+	if keep, ok := s.decided[id]; ok {
+		s.mu.Unlock()
+		if keep {
+			s.next.OnEnd(span)
+		}
+		return
+	}
+
+	t, ok := s.pending[id]
+	if !ok {
+		t = &pendingTrace{firstSeen: time.Now()}
+		s.pending[id] = t
+		s.order = append(s.order, pendingEntry{id: id, trace: t})
+	}
+	t.spans = append(t.spans, span)
+	t.hasError = t.hasError || span.Status().Code == codes.Error
+	t.debug = t.debug || isDebugForced(span)
+
+	var forward []sdktrace.ReadOnlySpan
+	switch {
+	case !span.Parent().IsValid() || span.Parent().IsRemote():
+		forward = s.decideLocked(id, s.reasonAtRoot(id, t, span))
+	case len(t.spans) >= maxSpansPerTrace:
+		// Deciding on the ratio here would drop an error or a slow root
+		// that ends later, so a trace this long is kept.
+		tailEvicted.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", tailReasonSpanLimit)))
+		forward = s.decideLocked(id, s.reasonEarly(id, t, tailReasonSpanLimit))
+	}
+
+	for len(s.pending) > s.cfg.MaxTraces {
+		oldest, ot, _ := s.oldestLocked()
+		tailEvicted.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", tailReasonEvicted)))
+		forward = append(forward, s.decideLocked(oldest, s.reasonEarly(oldest, ot, tailReasonEvicted))...)
+	}
+	s.mu.Unlock()
+
+	for _, sp := range forward {
+		s.next.OnEnd(sp)
+	}
+}
+
+// reasonAtRoot decides a trace whose local root has ended. An empty reason
+// means the trace was not selected by the ratio and is dropped.
+func (s *TailSampler) reasonAtRoot(id trace.TraceID, t *pendingTrace, root sdktrace.ReadOnlySpan) string {
+	switch {
+	case t.hasError:
+		return tailReasonError
+	case root.EndTime().Sub(root.StartTime()) >= s.cfg.LatencyThreshold:
+		return tailReasonLatency
+	case t.debug:
+		return tailReasonDebug
+	case sampledByRatio(id, s.cfg.Ratio):
+		return tailReasonRatio
+	}
+	return ""
+}
+
+// reasonEarly decides a trace whose root has not ended, on the spans seen so
+// far; early is the reason reported when it would otherwise be dropped.
+func (s *TailSampler) reasonEarly(id trace.TraceID, t *pendingTrace, early string) string {
+	switch {
+	case t.hasError:
+		return tailReasonError
+	case t.debug:
+		return tailReasonDebug
+	case early == tailReasonTimeout || early == tailReasonSpanLimit:
+		// The root has been running for longer than Window, or the trace
+		// is too long to buffer until it ends.
+		return early
+	case sampledByRatio(id, s.cfg.Ratio):
+		return tailReasonRatio
+	}
+	return ""
+}
+
+// oldestLocked returns the oldest buffered trace, first dropping entries of
+// traces already decided from the front of s.order.
+func (s *TailSampler) oldestLocked() (trace.TraceID, *pendingTrace, bool) {
+	for len(s.order) > 0 {
+		e := s.order[0]
+		if s.pending[e.id] == e.trace {
+			return e.id, e.trace, true
+		}
+		s.order = s.order[1:]
+	}
+	return trace.TraceID{}, nil, false
+}
+
+// decideLocked records the decision for id, removes it from the buffer and
+// returns the spans to forward. reason is empty for a drop.
+func (s *TailSampler) decideLocked(id trace.TraceID, reason string) []sdktrace.ReadOnlySpan {
+	t := s.pending[id]
+	delete(s.pending, id)
+
+	keep := reason != ""
+	s.decided[id] = keep
+	s.decidedOrder = append(s.decidedOrder, id)
+	if len(s.decidedOrder) > s.cfg.MaxTraces {
+		delete(s.decided, s.decidedOrder[0])
+		s.decidedOrder = s.decidedOrder[1:]
+	}
+
+	decision := "drop"
+	if keep {
+		decision = "keep"
+	} else {
+		reason = "not_selected"
+	}
+	tailDecisions.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("decision", decision),
+		attribute.String("reason", reason),
+	))
+
+	if !keep || t == nil {
+		return nil
+	}
+	return t.spans
+}
+
+// expireLoop decides traces that have been buffered for longer than Window.
+func (s *TailSampler) expireLoop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(max(s.cfg.Window/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.expire(now)
+		}
+	}
+}
+
+func (s *TailSampler) expire(now time.Time) {
+	var forward []sdktrace.ReadOnlySpan
+
+	s.mu.Lock()
+	for {
+		id, t, ok := s.oldestLocked()
+		if !ok || now.Sub(t.firstSeen) < s.cfg.Window {
+			break
+		}
+		forward = append(forward, s.decideLocked(id, s.reasonEarly(id, t, tailReasonTimeout))...)
+	}
+	s.mu.Unlock()
+
+	for _, sp := range forward {
+		s.next.OnEnd(sp)
+	}
+}
+
+// Buffered returns the number of traces awaiting a decision.
+func (s *TailSampler) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// Shutdown decides every buffered trace on what has been seen of it, then
+// shuts down next.
+func (s *TailSampler) Shutdown(ctx context.Context) error {
+	close(s.stop)
+	<-s.done
+
+	var forward []sdktrace.ReadOnlySpan
+	s.mu.Lock()
+	for {
+		id, t, ok := s.oldestLocked()
+		if !ok {
+			break
+		}
+		forward = append(forward, s.decideLocked(id, s.reasonEarly(id, t, ""))...)
+	}
+	s.mu.Unlock()
+
+	for _, sp := range forward {
+		s.next.OnEnd(sp)
+	}
+	return s.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes spans already forwarded to next. Buffered traces are not
+// decided early.
+func (s *TailSampler) ForceFlush(ctx context.Context) error {
+	return s.next.ForceFlush(ctx)
+}
+
+func isDebugForced(span sdktrace.ReadOnlySpan) bool {
+	for _, kv := range span.Attributes() {
+		if kv.Key == "debug.forced" {
+			return kv.Value.AsBool()
+		}
+	}
+	return false
+}
+
+// sampledByRatio applies the same trace ID test as the SDK's
+// TraceIDRatioBased sampler.
+func sampledByRatio(id trace.TraceID, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	bound := uint64(ratio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:16])>>1 < bound
+}
@@ -0,0 +1,234 @@
+package observability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTailSamplingTest(t *testing.T, cfg TailSamplingConfig) (*TailSampler, trace.Tracer, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	if cfg.Window == 0 {
+		cfg.Window = time.Hour
+	}
+	if cfg.LatencyThreshold == 0 {
+		cfg.LatencyThreshold = time.Second
+	}
+	if cfg.MaxTraces == 0 {
+		cfg.MaxTraces = 100
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	sampler := NewTailSampler(recorder, cfg)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sampler))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	return sampler, provider.Tracer("test"), recorder
+}
+
+func TestTailSamplerKeepsErroredAndSlowTraces(t *testing.T) {
+	_, tracer, recorder := newTailSamplingTest(t, TailSamplingConfig{Ratio: 0})
+
+	ctx, root := tracer.Start(context.Background(), "errored")
+	_, child := tracer.Start(ctx, "divide")
+	child.SetStatus(codes.Error, "division by zero")
+	child.End()
+	root.End()
+
+	start := time.Now()
+	_, slow := tracer.Start(context.Background(), "slow", trace.WithTimestamp(start))
+	slow.End(trace.WithTimestamp(start.Add(2 * time.Second)))
+
+	_, fast := tracer.Start(context.Background(), "fast")
+	fast.End()
+
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	if len(names) != 3 || names[0] != "divide" || names[1] != "errored" || names[2] != "slow" {
+		t.Fatalf("expected errored and slow traces only, got %v", names)
+	}
+}
+
+func TestTailSamplerLateSpansFollowDecision(t *testing.T) {
+	_, tracer, recorder := newTailSamplingTest(t, TailSamplingConfig{Ratio: 1})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, late := tracer.Start(ctx, "late")
+	root.End()
+
+	if len(recorder.Ended()) != 1 {
+		t.Fatalf("expected root to be forwarded at decision, got %d spans", len(recorder.Ended()))
+	}
+
+	late.End()
+	if len(recorder.Ended()) != 2 {
+		t.Fatalf("expected late span to follow the keep decision, got %d spans", len(recorder.Ended()))
+	}
+}
+
+func TestTailSamplerDecidesOnTimeoutAndEviction(t *testing.T) {
+	sampler, tracer, recorder := newTailSamplingTest(t, TailSamplingConfig{Ratio: 0, MaxTraces: 1})
+
+	// Root still running: only the child has ended.
+	ctx, open := tracer.Start(context.Background(), "open")
+	defer open.End()
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	if sampler.Buffered() != 1 {
+		t.Fatalf("expected 1 buffered trace, got %d", sampler.Buffered())
+	}
+
+	sampler.expire(time.Now().Add(2 * time.Hour))
+	if sampler.Buffered() != 0 || len(recorder.Ended()) != 1 {
+		t.Fatalf("expected timed-out trace to be kept as slow, got %d buffered, %d forwarded",
+			sampler.Buffered(), len(recorder.Ended()))
+	}
+
+	// A second open trace evicts the first, which is dropped at ratio 0.
+	ctx1, open1 := tracer.Start(context.Background(), "open1")
+	defer open1.End()
+	_, c1 := tracer.Start(ctx1, "c1")
+	c1.End()
+	ctx2, open2 := tracer.Start(context.Background(), "open2")
+	defer open2.End()
+	_, c2 := tracer.Start(ctx2, "c2")
+	c2.End()
+
+	if sampler.Buffered() != 1 || len(recorder.Ended()) != 1 {
+		t.Fatalf("expected oldest trace evicted and dropped, got %d buffered, %d forwarded",
+			sampler.Buffered(), len(recorder.Ended()))
+	}
+}
+
+func TestTailSamplerRecordsDecisions(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	if err := initTailSamplingMetrics(meter); err != nil {
+		t.Fatalf("initializing tail sampling metrics: %v", err)
+	}
+
+	_, tracer, _ := newTailSamplingTest(t, TailSamplingConfig{Ratio: 0})
+
+	_, kept := tracer.Start(context.Background(), "kept")
+	kept.SetStatus(codes.Error, "boom")
+	kept.End()
+	_, dropped := tracer.Start(context.Background(), "dropped")
+	dropped.End()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+
+	decisions := sumInt64(t, rm, "observability.tail_sampling.decisions.total")
+	keep := attribute.NewSet(attribute.String("decision", "keep"), attribute.String("reason", tailReasonError))
+	drop := attribute.NewSet(attribute.String("decision", "drop"), attribute.String("reason", "not_selected"))
+	if decisions[keep] != 1 || decisions[drop] != 1 {
+		t.Fatalf("unexpected decisions %v", decisions)
+	}
+}
+
+func TestTailSamplerRecordsEarlyDecisionsByReason(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	if err := initTailSamplingMetrics(meter); err != nil {
+		t.Fatalf("initializing tail sampling metrics: %v", err)
+	}
+
+	_, tracer, _ := newTailSamplingTest(t, TailSamplingConfig{Ratio: 0, MaxTraces: 1})
+
+	ctx, long := tracer.Start(context.Background(), "long")
+	defer long.End()
+	for range maxSpansPerTrace {
+		_, child := tracer.Start(ctx, "step")
+		child.End()
+	}
+
+	// Two open traces with MaxTraces 1: the first is evicted.
+	for _, name := range []string{"open1", "open2"} {
+		ctx, open := tracer.Start(context.Background(), name)
+		defer open.End()
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+
+	evicted := sumInt64(t, rm, "observability.tail_sampling.evicted.total")
+	spanLimit := attribute.NewSet(attribute.String("reason", tailReasonSpanLimit))
+	full := attribute.NewSet(attribute.String("reason", tailReasonEvicted))
+	if evicted[spanLimit] != 1 || evicted[full] != 1 {
+		t.Fatalf("unexpected early decisions %v", evicted)
+	}
+}
+
+func TestSampledByRatio(t *testing.T) {
+	id := trace.TraceID{8: 0x80}
+	if sampledByRatio(id, 0.25) {
+		t.Fatal("expected trace ID in the upper half to be rejected at 0.25")
+	}
+	if !sampledByRatio(id, 0.75) || !sampledByRatio(id, 1) {
+		t.Fatal("expected trace ID in the upper half to be kept at 0.75 and 1")
+	}
+}
+
+func TestTailSamplerKeepsTracesAtSpanLimit(t *testing.T) {
+	sampler, tracer, recorder := newTailSamplingTest(t, TailSamplingConfig{Ratio: 0})
+
+	ctx, root := tracer.Start(context.Background(), "long")
+	for range maxSpansPerTrace {
+		_, child := tracer.Start(ctx, "step")
+		child.End()
+	}
+	if sampler.Buffered() != 0 || len(recorder.Ended()) != maxSpansPerTrace {
+		t.Fatalf("expected the trace to be kept at the span limit, got %d buffered, %d forwarded",
+			sampler.Buffered(), len(recorder.Ended()))
+	}
+
+	// The root fails after the early decision; it is still exported.
+	root.SetStatus(codes.Error, "boom")
+	root.End()
+	if got := recorder.Ended(); len(got) != maxSpansPerTrace+1 || got[len(got)-1].Name() != "long" {
+		t.Fatalf("expected the errored root to follow the keep decision, got %d spans", len(got))
+	}
+}
+
+func TestTailSamplerSkipsDecidedTracesInOrder(t *testing.T) {
+	sampler, tracer, _ := newTailSamplingTest(t, TailSamplingConfig{Ratio: 1, MaxTraces: 2})
+
+	// Three traces buffered and decided out of order leave stale entries
+	// that eviction must skip.
+	var roots []trace.Span
+	for range 3 {
+		ctx, root := tracer.Start(context.Background(), "root")
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+		roots = append(roots, root)
+	}
+	roots[2].End()
+	roots[1].End()
+
+	if sampler.Buffered() != 0 {
+		t.Fatalf("expected every trace decided, got %d buffered", sampler.Buffered())
+	}
+	sampler.expire(time.Now().Add(2 * time.Hour))
+	if len(sampler.order) != 0 {
+		t.Fatalf("expected stale order entries to be dropped, got %d", len(sampler.order))
+	}
+	roots[0].End()
+}
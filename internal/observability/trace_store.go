@@ -0,0 +1,228 @@
+package observability
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxSpansPerTrace bounds the memory a single trace (e.g. a very long chain)
+// can hold in the store. Further spans are counted but not kept.
+const maxSpansPerTrace = 1024
+
+// latencyBuckets are the zpages-style boundaries used to group traces by the
+// duration of their root span.
+var latencyBuckets = []time.Duration{
+	0,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+var latencyBucketLabels = []string{
+	">0", ">10µs", ">100µs", ">1ms", ">10ms", ">100ms", ">1s", ">10s",
+}
+
+// traceStore is the process-wide store fed by InitTracing. It is nil unless
+// DEBUG_TRACES_ENABLED is true.
+var traceStore *TraceStore
+
+// TraceStore is a span processor that keeps the most recent traces, and
+// separately the most recent errored traces, in bounded memory so they can be
+// inspected on /debug/traces without a tracing backend.
+type TraceStore struct {
+	maxRecent  int
+	maxErrored int
+
+	mu      sync.Mutex
+	traces  map[trace.TraceID]*StoredTrace
+	recent  []trace.TraceID
+	errored []trace.TraceID
+}
+
+// StoredTrace is a trace held by a TraceStore.
+type StoredTrace struct {
+	ID      trace.TraceID
+	Spans   []sdktrace.ReadOnlySpan
+	Dropped int
+
+	// Root is the local root span, nil until it has ended.
+	Root     sdktrace.ReadOnlySpan
+	HasError bool
+
+	inRecent  bool
+	inErrored bool
+}
+
+// TraceSummary describes a stored trace for listing.
+type TraceSummary struct {
+	TraceID  string
+	Route    string
+	Start    time.Time
+	Duration time.Duration
+	Bucket   int
+	Error    bool
+	Spans    int
+}
+
+func NewTraceStore(maxRecent, maxErrored int) *TraceStore {
+	return &TraceStore{
+		maxRecent:  maxRecent,
+		maxErrored: maxErrored,
+		traces:     make(map[trace.TraceID]*StoredTrace),
+	}
+}
+
+func (s *TraceStore) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (s *TraceStore) OnEnd(span sdktrace.ReadOnlySpan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := span.SpanContext().TraceID()
+	t, ok := s.traces[id]
+	if !ok {
+		t = &StoredTrace{ID: id, inRecent: true}
+		s.traces[id] = t
+		s.recent = append(s.recent, id)
+		s.evict()
+	}
+
+	if len(t.Spans) < maxSpansPerTrace {
+		t.Spans = append(t.Spans, span)
+	} else {
+		t.Dropped++
+	}
+
+	if !span.Parent().IsValid() || span.Parent().IsRemote() {
+		t.Root = span
+	}
+
+	if span.Status().Code == codes.Error && !t.HasError {
+		t.HasError = true
+		t.inErrored = true
+		s.errored = append(s.errored, id)
+		s.evict()
+	}
+}
+
+// evict trims both rings to their capacity, deleting traces that are no
+// longer referenced by either.
+func (s *TraceStore) evict() {
+	for len(s.recent) > s.maxRecent {
+		id := s.recent[0]
+		s.recent = s.recent[1:]
+		if t := s.traces[id]; t != nil {
+			t.inRecent = false
+			if !t.inErrored {
+				delete(s.traces, id)
+			}
+		}
+	}
+
+	for len(s.errored) > s.maxErrored {
+		id := s.errored[0]
+		s.errored = s.errored[1:]
+		if t := s.traces[id]; t != nil {
+			t.inErrored = false
+			if !t.inRecent {
+				delete(s.traces, id)
+			}
+		}
+	}
+}
+
+func (s *TraceStore) Shutdown(context.Context) error   { return nil }
+func (s *TraceStore) ForceFlush(context.Context) error { return nil }
+
+// Summaries lists stored traces, newest first.
+func (s *TraceStore) Summaries() []TraceSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]TraceSummary, 0, len(s.traces))
+	for _, t := range s.traces {
+		out = append(out, summarize(t))
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.After(out[j].Start) })
+	return out
+}
+
+// Trace returns a copy of the stored trace with the given ID.
+func (s *TraceStore) Trace(id trace.TraceID) (StoredTrace, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.traces[id]
+	if !ok {
+		return StoredTrace{}, false
+	}
+
+	cp := *t
+	cp.Spans = slices.Clone(t.Spans)
+	return cp, true
+}
+
+func summarize(t *StoredTrace) TraceSummary {
+	sum := TraceSummary{
+		TraceID: t.ID.String(),
+		Error:   t.HasError,
+		Spans:   len(t.Spans) + t.Dropped,
+	}
+
+	root := t.Root
+	if root == nil {
+		// Root still running: describe the trace by its earliest ended span.
+		root = t.Spans[0]
+		for _, sp := range t.Spans[1:] {
+			if sp.StartTime().Before(root.StartTime()) {
+				root = sp
+			}
+		}
+	}
+
+	sum.Route = spanRoute(root)
+	sum.Start = root.StartTime()
+	sum.Duration = root.EndTime().Sub(root.StartTime())
+	sum.Bucket = latencyBucket(sum.Duration)
+	return sum
+}
+
+// spanRoute prefers the matched route, then the request path, then the span
+// name, so non-HTTP roots are still grouped sensibly.
+func spanRoute(span sdktrace.ReadOnlySpan) string {
+	var path string
+	for _, kv := range span.Attributes() {
+		switch kv.Key {
+		case semconv.HTTPRouteKey:
+			return kv.Value.Emit()
+		case semconv.URLPathKey:
+			path = kv.Value.Emit()
+		}
+	}
+	if path != "" {
+		return path
+	}
+	return span.Name()
+}
+
+func latencyBucket(d time.Duration) int {
+	for i := len(latencyBuckets) - 1; i > 0; i-- {
+		if d >= latencyBuckets[i] {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newStoreTracer(store *TraceStore) trace.Tracer {
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(store)).Tracer("test")
+}
+
+func TestTraceStoreKeepsErroredTracesBeyondRecentCapacity(t *testing.T) {
+	store := NewTraceStore(2, 1)
+	tracer := newStoreTracer(store)
+
+	_, failed := tracer.Start(context.Background(), "failed")
+	failed.SetStatus(codes.Error, "boom")
+	failed.End()
+	failedID := failed.SpanContext().TraceID()
+
+	var lastID trace.TraceID
+	for range 3 {
+		_, span := tracer.Start(context.Background(), "ok")
+		span.End()
+		lastID = span.SpanContext().TraceID()
+	}
+
+	if got := len(store.Summaries()); got != 3 {
+		t.Fatalf("expected 2 recent + 1 errored trace, got %d", got)
+	}
+	if _, ok := store.Trace(failedID); !ok {
+		t.Fatal("expected errored trace to be retained")
+	}
+	if _, ok := store.Trace(lastID); !ok {
+		t.Fatal("expected most recent trace to be retained")
+	}
+}
+
+func TestTraceStoreSummaryUsesRootRouteAndLatency(t *testing.T) {
+	store := NewTraceStore(10, 10)
+	tracer := newStoreTracer(store)
+
+	start := time.Now()
+	ctx, root := tracer.Start(context.Background(), "http_request",
+		trace.WithTimestamp(start),
+	)
+	root.SetAttributes(semconv.URLPath("/calculator/chain"))
+	_, child := tracer.Start(ctx, "calculator.chain")
+	child.End()
+	root.End(trace.WithTimestamp(start.Add(20 * time.Millisecond)))
+
+	sums := store.Summaries()
+	if len(sums) != 1 {
+		t.Fatalf("expected 1 trace, got %d", len(sums))
+	}
+
+	sum := sums[0]
+	if sum.Route != "/calculator/chain" {
+		t.Fatalf("expected route %q, got %q", "/calculator/chain", sum.Route)
+	}
+	if latencyBucketLabels[sum.Bucket] != ">10ms" {
+		t.Fatalf("expected bucket >10ms, got %s", latencyBucketLabels[sum.Bucket])
+	}
+	if sum.Spans != 2 {
+		t.Fatalf("expected 2 spans, got %d", sum.Spans)
+	}
+}
+
+func TestTracesHandlerRendersSpanTree(t *testing.T) {
+	store := NewTraceStore(10, 10)
+	oldStore := traceStore
+	traceStore = store
+	t.Cleanup(func() { traceStore = oldStore })
+
+	tracer := newStoreTracer(store)
+	ctx, chain := tracer.Start(context.Background(), "calculator.chain")
+	_, step := tracer.Start(ctx, "calculator.chain.step.0.divide")
+	step.RecordError(errors.New("division by zero at step 0"))
+	step.SetStatus(codes.Error, "division by zero at step 0")
+	step.End()
+	chain.End()
+	id := chain.SpanContext().TraceID().String()
+
+	t.Run("list", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/debug/traces?status=error", nil)
+		w := testutil.ExecuteRequest(r, TracesHandler())
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+		if body := w.Body.String(); !strings.Contains(body, id) {
+			t.Fatalf("expected errored trace %s in listing", id)
+		}
+	})
+
+	t.Run("detail", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/debug/traces?trace_id="+id, nil)
+		w := testutil.ExecuteRequest(r, TracesHandler())
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		body := w.Body.String()
+		parent := strings.Index(body, "calculator.chain<")
+		child := strings.Index(body, "calculator.chain.step.0.divide")
+		if parent < 0 || child < 0 || child < parent {
+			t.Fatalf("expected chain span followed by its step span, got:\n%s", body)
+		}
+		if !strings.Contains(body, "margin-left:24px") {
+			t.Fatal("expected step span to be indented under the chain span")
+		}
+	})
+
+	t.Run("unknown trace", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/debug/traces?trace_id=0102030405060708090a0b0c0d0e0f10", nil)
+		w := testutil.ExecuteRequest(r, TracesHandler())
+
+		testutil.CheckResponseCode(t, http.StatusNotFound, w.Code)
+	})
+}
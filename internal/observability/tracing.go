@@ -3,17 +3,30 @@ package observability
 import (
 	"context"
 	"os"
+	"strconv"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func InitTracing(ctx context.Context) (func(context.Context) error, error) {
 
-	exporter, err := otlptracehttp.New(ctx)
+	var exporterOpts []otlptracehttp.Option
+	client, err := walHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if client != nil {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithHTTPClient(client))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -29,12 +42,48 @@ func InitTracing(ctx context.Context) (func(context.Context) error, error) {
 		return nil, err
 	}
 
-	provider := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
+	// countingSpanProcessor reports the queue depth and overflow drops; the
+	// SDK's own processor metrics need OTEL_GO_X_OBSERVABILITY=true.
+	queueSize, err := envInt("OTEL_BSP_MAX_QUEUE_SIZE", sdktrace.DefaultMaxQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	var export sdktrace.SpanProcessor = countingSpanProcessor{
+		SpanProcessor: sdktrace.NewBatchSpanProcessor(instrumentedSpanExporter{exporter}, sdktrace.WithMaxQueueSize(queueSize)),
+		limit:         int64(queueSize),
+	}
+
+	tailCfg, tailEnabled, err := tailSamplingConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if tailEnabled {
+		tailSampler = NewTailSampler(export, tailCfg)
+		export = tailSampler
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSpanProcessor(export),
 		sdktrace.WithResource(res),
-	)
+		sdktrace.WithSampler(debugSampler{base: samplerFromEnv()}),
+	}
+
+	store, err := traceStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		traceStore = store
+		opts = append(opts, sdktrace.WithSpanProcessor(store))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
 
 	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 
 	return provider.Shutdown, nil
 }
@@ -46,3 +95,69 @@ func ServiceName() string {
 	}
 	return name
 }
+
+// traceStoreFromEnv builds the in-memory store behind /debug/traces, or
+// returns nil unless DEBUG_TRACES_ENABLED is true.
+func traceStoreFromEnv() (*TraceStore, error) {
+	enabled, err := envBool("DEBUG_TRACES_ENABLED", false)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	recent, err := envInt("DEBUG_TRACES_RECENT", 256)
+	if err != nil {
+		return nil, err
+	}
+	errored, err := envInt("DEBUG_TRACES_ERRORED", 128)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTraceStore(recent, errored), nil
+}
+
+// samplerFromEnv mirrors the SDK's handling of OTEL_TRACES_SAMPLER and
+// OTEL_TRACES_SAMPLER_ARG, which is bypassed once WithSampler is used.
+func samplerFromEnv() sdktrace.Sampler {
+	ratio := 1.0
+	if arg, err := strconv.ParseFloat(os.Getenv("OTEL_TRACES_SAMPLER_ARG"), 64); err == nil {
+		ratio = arg
+	}
+
+	switch os.Getenv("OTEL_TRACES_SAMPLER") {
+	case "always_on":
+		return sdktrace.AlwaysSample()
+	case "always_off":
+		return sdktrace.NeverSample()
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio)
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	default:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+}
+
+// debugSampler always samples spans started from a context elevated by
+// DebugLogMiddleware and defers to base for everything else.
+type debugSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s debugSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !DebugLoggingFromContext(p.ParentContext) {
+		return s.base.ShouldSample(p)
+	}
+
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: []attribute.KeyValue{attribute.Bool("debug.forced", true)},
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s debugSampler) Description() string {
+	return "DebugSampler{" + s.base.Description() + "}"
+}
@@ -0,0 +1,507 @@
+package observability
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// telemetryWAL is shared by the trace, metric and log exporters. It is nil
+// unless OTEL_WAL_DIR is set.
+var (
+	telemetryWAL     *WAL
+	telemetryWALOnce sync.Once
+	telemetryWALErr  error
+)
+
+// walEvicted counts requests discarded because the WAL hit its size limit.
+var walEvicted metric.Int64Counter = noop.Int64Counter{}
+
+// WAL is a bounded on-disk queue of OTLP/HTTP export requests. It is used as
+// the transport of the OTLP exporters: requests that fail with a network
+// error or a retryable status are written to disk and reported to the
+// exporter as accepted, and a background loop replays them in order once the
+// collector is reachable again. While a backlog exists, new requests are
+// appended behind it so ordering is preserved.
+//
+// Only the method, signal and body reach the disk. Exporter headers carry
+// credentials, so replay sends each request to the URL and with the headers
+// of the latest request the exporter made for that signal; a signal has no
+// target until the exporter's first request after OpenWAL.
+type WAL struct {
+	dir      string
+	maxBytes int64
+	interval time.Duration
+	timeout  time.Duration
+	next     http.RoundTripper
+
+	mu      sync.Mutex
+	seq     uint64
+	size    int64
+	files   []walFile
+	targets map[string]walTarget
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+type walFile struct {
+	name   string
+	size   int64
+	signal string
+}
+
+// walHeader precedes the raw request body in each WAL file.
+type walHeader struct {
+	Method string `json:"method"`
+	Signal string `json:"signal"`
+}
+
+// walTarget is where, and with which headers, the exporter currently sends a
+// signal.
+type walTarget struct {
+	url    string
+	header http.Header
+}
+
+func initWALMetrics(meter metric.Meter) error {
+	var err error
+
+	walEvicted, err = meter.Int64Counter("observability.telemetry.wal.evicted.total",
+		metric.WithDescription("Buffered export requests discarded because the WAL was full"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating WAL evicted counter: %w", err)
+	}
+
+	backlogRequests, err := meter.Int64ObservableGauge("observability.telemetry.wal.backlog.requests",
+		metric.WithDescription("Export requests buffered on disk awaiting replay"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating WAL backlog gauge: %w", err)
+	}
+
+	backlogBytes, err := meter.Int64ObservableGauge("observability.telemetry.wal.backlog.size",
+		metric.WithDescription("Bytes buffered on disk awaiting replay"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return fmt.Errorf("creating WAL backlog size gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if telemetryWAL == nil {
+			return nil
+		}
+		n, size := telemetryWAL.Backlog()
+		o.ObserveInt64(backlogRequests, int64(n))
+		o.ObserveInt64(backlogBytes, size)
+		return nil
+	}, backlogRequests, backlogBytes)
+	if err != nil {
+		return fmt.Errorf("registering WAL backlog callback: %w", err)
+	}
+
+	return nil
+}
+
+// OpenWAL opens (creating if needed) a WAL in dir, loads any requests left
+// from a previous run and starts replaying them through next.
+func OpenWAL(dir string, maxBytes int64, interval time.Duration, next http.RoundTripper) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create WAL dir: %w", err)
+	}
+
+	w := &WAL{
+		dir:      dir,
+		maxBytes: maxBytes,
+		interval: interval,
+		timeout:  10 * time.Second,
+		next:     next,
+		targets:  make(map[string]walTarget),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+
+	go w.replayLoop()
+	return w, nil
+}
+
+// walFromEnv returns the process-wide WAL, opening it on first use, or nil
+// when OTEL_WAL_DIR is unset.
+func walFromEnv() (*WAL, error) {
+	telemetryWALOnce.Do(func() {
+		dir := os.Getenv("OTEL_WAL_DIR")
+		if dir == "" {
+			return
+		}
+
+		maxBytes, err := envInt("OTEL_WAL_MAX_BYTES", 64<<20)
+		if err != nil {
+			telemetryWALErr = err
+			return
+		}
+		interval, err := envDuration("OTEL_WAL_RETRY_INTERVAL", 5*time.Second)
+		if err != nil {
+			telemetryWALErr = err
+			return
+		}
+
+		// The exporters apply OTEL_EXPORTER_OTLP_TIMEOUT (milliseconds) only
+		// to their default client, which the WAL replaces.
+		timeoutMS, err := envInt("OTEL_EXPORTER_OTLP_TIMEOUT", 10_000)
+		if err != nil {
+			telemetryWALErr = err
+			return
+		}
+
+		telemetryWAL, telemetryWALErr = OpenWAL(dir, int64(maxBytes), interval, http.DefaultTransport)
+		if telemetryWAL != nil {
+			telemetryWAL.timeout = time.Duration(timeoutMS) * time.Millisecond
+		}
+	})
+	return telemetryWAL, telemetryWALErr
+}
+
+// CloseWAL stops the replay loop of the process-wide WAL, if one was opened.
+// Call it after the exporters have shut down so their final flushes can
+// still be buffered.
+func CloseWAL() {
+	if telemetryWAL != nil {
+		_ = telemetryWAL.Close()
+	}
+}
+
+// walHTTPClient returns the HTTP client the OTLP exporters should use, or nil
+// to keep their default. The client has no timeout of its own: the WAL bounds
+// each attempt so that a hung collector is buffered rather than reported to
+// the exporter as a failure it would retry.
+func walHTTPClient() (*http.Client, error) {
+	wal, err := walFromEnv()
+	if err != nil || wal == nil {
+		return nil, err
+	}
+	return &http.Client{Transport: wal}, nil
+}
+
+func (w *WAL) load() error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return fmt.Errorf("read WAL dir: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".req") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		var seq uint64
+		var signal string
+		if _, err := fmt.Sscanf(strings.TrimSuffix(e.Name(), ".req"), "%d-%s", &seq, &signal); err != nil {
+			continue
+		}
+		w.seq = max(w.seq, seq)
+		w.size += info.Size()
+		w.files = append(w.files, walFile{name: e.Name(), size: info.Size(), signal: signal})
+	}
+
+	sort.Slice(w.files, func(i, j int) bool { return w.files[i].name < w.files[j].name })
+	return nil
+}
+
+// Backlog returns the number of buffered requests and their size on disk.
+func (w *WAL) Backlog() (requests int, bytes int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.files), w.size
+}
+
+// Close stops the replay loop. Buffered requests stay on disk and are
+// replayed by the next OpenWAL on the same directory.
+func (w *WAL) Close() error {
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+func (w *WAL) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	w.setTarget(req)
+
+	if n, _ := w.Backlog(); n > 0 {
+		return w.spill(req, body, errors.New("backlog pending"))
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), w.timeout)
+	attempt := req.Clone(ctx)
+	attempt.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := w.next.RoundTrip(attempt)
+	if err == nil && !isRetryableExportStatus(resp.StatusCode) {
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+	cancel()
+
+	if resp != nil {
+		err = fmt.Errorf("collector returned %s", resp.Status)
+		resp.Body.Close()
+	}
+	return w.spill(req, body, err)
+}
+
+// spill persists req and answers the exporter with an empty 200, which it
+// treats as full success.
+func (w *WAL) spill(req *http.Request, body []byte, cause error) (*http.Response, error) {
+	signal := signalFromPath(req.URL.Path)
+	if err := w.append(req, body, signal); err != nil {
+		return nil, fmt.Errorf("buffer export request: %w (after %v)", err, cause)
+	}
+
+	exportHealth.markBuffering(signal, time.Now(), cause)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+// setTarget records the URL and headers of a live exporter request, which
+// replay uses for the requests buffered for its signal.
+func (w *WAL) setTarget(req *http.Request) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.targets[signalFromPath(req.URL.Path)] = walTarget{url: req.URL.String(), header: req.Header.Clone()}
+}
+
+func (w *WAL) append(req *http.Request, body []byte, signal string) error {
+	hdr, err := json.Marshal(walHeader{Method: req.Method, Signal: signal})
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	size := int64(len(hdr) + 1 + len(body))
+	for len(w.files) > 0 && w.size+size > w.maxBytes {
+		oldest := w.files[0]
+		w.files = w.files[1:]
+		w.size -= oldest.size
+		_ = os.Remove(filepath.Join(w.dir, oldest.name))
+		walEvicted.Add(context.Background(), 1, metric.WithAttributes(attribute.String("signal", oldest.signal)))
+	}
+	if size > w.maxBytes {
+		walEvicted.Add(context.Background(), 1, metric.WithAttributes(attribute.String("signal", signal)))
+		return errors.New("request larger than WAL capacity")
+	}
+
+	w.seq++
+	name := fmt.Sprintf("%020d-%s.req", w.seq, signal)
+	data := append(append(hdr, '\n'), body...)
+	if err := os.WriteFile(filepath.Join(w.dir, name), data, 0o600); err != nil {
+		return err
+	}
+
+	w.files = append(w.files, walFile{name: name, size: size, signal: signal})
+	w.size += size
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *WAL) replayLoop() {
+	defer close(w.done)
+
+	timer := time.NewTimer(w.interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+
+		// Keep draining until the backlog is empty or the collector fails
+		// again, then wait for the next interval.
+		for w.replayOldest() {
+		}
+		timer.Reset(w.interval)
+	}
+}
+
+// replayOldest sends the oldest buffered request whose signal has a target.
+// It reports whether the request left the queue (delivered, or rejected as
+// non-retryable) so the caller can continue with the next one.
+func (w *WAL) replayOldest() bool {
+	w.mu.Lock()
+	f, target, ok := w.nextReplayLocked()
+	w.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	req, err := w.readRequest(f.name, target)
+	if err != nil {
+		selfLogger.Warn("discarding unreadable WAL entry", zap.String("file", f.name), zap.Error(err))
+		w.remove(f)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	resp, err := w.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		exportHealth.markBuffering(f.signal, time.Now(), err)
+		return false
+	}
+	resp.Body.Close()
+
+	if isRetryableExportStatus(resp.StatusCode) {
+		exportHealth.markBuffering(f.signal, time.Now(), fmt.Errorf("collector returned %s", resp.Status))
+		return false
+	}
+	if resp.StatusCode >= 300 {
+		selfLogger.Warn("collector rejected buffered export request",
+			zap.String("file", f.name),
+			zap.Int("status", resp.StatusCode),
+		)
+	}
+
+	w.remove(f)
+	if n, _ := w.Backlog(); n == 0 {
+		exportHealth.markDrained(time.Now())
+	}
+	return true
+}
+
+// nextReplayLocked returns the oldest buffered request the exporter has
+// shown a target for. The caller holds w.mu.
+func (w *WAL) nextReplayLocked() (walFile, walTarget, bool) {
+	for _, f := range w.files {
+		if target, ok := w.targets[f.signal]; ok {
+			return f, target, true
+		}
+	}
+	return walFile{}, walTarget{}, false
+}
+
+// readRequest rebuilds a buffered request for target.
+func (w *WAL) readRequest(name string, target walTarget) (*http.Request, error) {
+	data, err := os.ReadFile(filepath.Join(w.dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(bytes.NewReader(data))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var hdr walHeader
+	if err := json.Unmarshal(line, &hdr); err != nil {
+		return nil, err
+	}
+
+	body := data[len(line):]
+	req, err := http.NewRequest(hdr.Method, target.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = target.header.Clone()
+	return req, nil
+}
+
+func (w *WAL) remove(f walFile) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range w.files {
+		if w.files[i].name == f.name {
+			w.files = append(w.files[:i], w.files[i+1:]...)
+			w.size -= f.size
+			break
+		}
+	}
+	_ = os.Remove(filepath.Join(w.dir, f.name))
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
+
+// isRetryableExportStatus matches the statuses the OTLP exporters retry.
+func isRetryableExportStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func signalFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/v1/traces"):
+		return signalTraces
+	case strings.HasSuffix(path, "/v1/metrics"):
+		return signalMetrics
+	case strings.HasSuffix(path, "/v1/logs"):
+		return signalLogs
+	}
+	return "unknown"
+}
@@ -0,0 +1,232 @@
+package observability
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+)
+
+// fakeCollector records request bodies and authorization headers and
+// answers 503 while down is set.
+type fakeCollector struct {
+	down atomic.Bool
+
+	mu     sync.Mutex
+	bodies []string
+	auth   []string
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	c.bodies = append(c.bodies, string(body))
+	c.auth = append(c.auth, r.Header.Get("Authorization"))
+	c.mu.Unlock()
+}
+
+func (c *fakeCollector) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.bodies...)
+}
+
+func newWALTest(t *testing.T, maxBytes int64) (*WAL, *fakeCollector, *httptest.Server, string) {
+	t.Helper()
+
+	oldHealth := exportHealth
+	exportHealth = newExportTracker()
+	t.Cleanup(func() { exportHealth = oldHealth })
+
+	collector := &fakeCollector{}
+	srv := httptest.NewServer(collector)
+	t.Cleanup(srv.Close)
+
+	dir := filepath.Join(t.TempDir(), "wal")
+	wal, err := OpenWAL(dir, maxBytes, time.Hour, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("opening WAL: %v", err)
+	}
+	return wal, collector, srv, dir
+}
+
+func postExport(t *testing.T, wal *WAL, url, body string) {
+	t.Helper()
+	postExportWithAuth(t, wal, url, body, "")
+}
+
+func postExportWithAuth(t *testing.T, wal *WAL, url, body, auth string) {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/traces", strings.NewReader(body))
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := (&http.Client{Transport: wal}).Do(req)
+	if err != nil {
+		t.Fatalf("export request failed: %v", err)
+	}
+	resp.Body.Close()
+	testutil.CheckResponseCode(t, http.StatusOK, resp.StatusCode)
+}
+
+func waitForBacklog(t *testing.T, wal *WAL, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n, _ := wal.Backlog()
+		if n == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected backlog of %d, got %d", want, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWALBuffersWhileCollectorDownAndReplaysInOrder(t *testing.T) {
+	wal, collector, srv, _ := newWALTest(t, 1<<20)
+	defer wal.Close()
+
+	collector.down.Store(true)
+	postExport(t, wal, srv.URL, "one")
+	postExport(t, wal, srv.URL, "two")
+
+	if n, _ := wal.Backlog(); n != 2 {
+		t.Fatalf("expected 2 buffered requests, got %d", n)
+	}
+	if signals, _ := exportHealth.report(0, time.Now().Add(time.Second)); !signals[0].Buffering {
+		t.Fatalf("expected traces to report buffering, got %+v", signals[0])
+	}
+
+	collector.down.Store(false)
+	// New requests queue behind the backlog rather than overtaking it.
+	postExport(t, wal, srv.URL, "three")
+	waitForBacklog(t, wal, 0)
+
+	got := strings.Join(collector.received(), ",")
+	if got != "one,two,three" {
+		t.Fatalf("expected replay in order, got %q", got)
+	}
+	if _, healthy := exportHealth.report(0, time.Now()); !healthy {
+		t.Fatal("expected healthy once the backlog is drained")
+	}
+}
+
+func TestWALEvictsOldestWhenFull(t *testing.T) {
+	wal, collector, srv, _ := newWALTest(t, 300)
+	defer wal.Close()
+
+	collector.down.Store(true)
+	for _, body := range []string{"first", "second", "third", "fourth"} {
+		postExport(t, wal, srv.URL, strings.Repeat(body, 10))
+	}
+
+	n, size := wal.Backlog()
+	if n == 0 || n == 4 || size > 300 {
+		t.Fatalf("expected some requests evicted within 300 bytes, got %d requests, %d bytes", n, size)
+	}
+
+	collector.down.Store(false)
+	wal.wake <- struct{}{}
+	waitForBacklog(t, wal, 0)
+
+	got := collector.received()
+	if got[len(got)-1] != strings.Repeat("fourth", 10) || strings.HasPrefix(got[0], "first") {
+		t.Fatalf("expected oldest requests to be evicted, got %q", got)
+	}
+}
+
+func TestWALReplaysBacklogFromPreviousRun(t *testing.T) {
+	wal, collector, srv, dir := newWALTest(t, 1<<20)
+
+	collector.down.Store(true)
+	postExport(t, wal, srv.URL, "left over")
+	wal.Close()
+
+	collector.down.Store(false)
+	reopened, err := OpenWAL(dir, 1<<20, 10*time.Millisecond, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("reopening WAL: %v", err)
+	}
+	defer reopened.Close()
+
+	// Nothing is replayed until the exporter shows where traces go now.
+	time.Sleep(50 * time.Millisecond)
+	if n, _ := reopened.Backlog(); n != 1 {
+		t.Fatalf("expected the backlog to wait for a target, got %d requests", n)
+	}
+
+	postExport(t, reopened, srv.URL, "new")
+	waitForBacklog(t, reopened, 0)
+	if got := strings.Join(collector.received(), ","); got != "left over,new" {
+		t.Fatalf("expected buffered request to be replayed first, got %q", got)
+	}
+}
+
+func TestWALKeepsCredentialsOffDisk(t *testing.T) {
+	wal, collector, srv, dir := newWALTest(t, 1<<20)
+	defer wal.Close()
+
+	collector.down.Store(true)
+	postExportWithAuth(t, wal, srv.URL, "payload", "Bearer old-secret")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one WAL entry, got %v, %v", entries, err)
+	}
+	path := filepath.Join(dir, entries[0].Name())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "old-secret") || strings.Contains(string(data), "Authorization") {
+		t.Fatalf("expected no header values on disk, got %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected WAL entry mode 0600, got %v", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0o700 {
+		t.Errorf("expected WAL dir mode 0700, got %v", info.Mode().Perm())
+	}
+
+	// Replay uses the credentials of the exporter's latest request.
+	collector.down.Store(false)
+	postExportWithAuth(t, wal, srv.URL, "next", "Bearer new-secret")
+	waitForBacklog(t, wal, 0)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if got := strings.Join(collector.auth, ","); got != "Bearer new-secret,Bearer new-secret" {
+		t.Fatalf("expected replay with the current credentials, got %q", got)
+	}
+}
+
+func TestCloseWALStopsProcessWAL(t *testing.T) {
+	wal, _, _, _ := newWALTest(t, 1<<20)
+
+	prev := telemetryWAL
+	telemetryWAL = wal
+	defer func() { telemetryWAL = prev }()
+
+	CloseWAL()
+	select {
+	case <-wal.done:
+	default:
+		t.Fatal("expected CloseWAL to stop the replay loop")
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-chi/chi/v5"
+
+	"go-chi-observability/internal/observability"
+)
+
+// Module is an API domain the server can mount and manage. Domain packages
+// implement it without importing this package; cmd/api lists them.
+type Module interface {
+	// Name is the domain name. Routes are mounted under "/" + Name, and
+	// health checks are registered as Name + "." + check name.
+	Name() string
+
+	// InitMetrics registers the domain's metric instruments. It runs once,
+	// after observability.InitMetrics.
+	InitMetrics() error
+
+	// RegisterRoutes mounts the domain's routes relative to its prefix.
+	RegisterRoutes(r chi.Router)
+
+	// HealthChecks returns the domain's dependency checks, if any.
+	HealthChecks() []observability.HealthCheck
+
+	// Shutdown releases the domain's resources once the server has stopped
+	// accepting requests.
+	Shutdown(ctx context.Context) error
+}
+
+// InitModuleMetrics calls InitMetrics on each module in order.
+func InitModuleMetrics(modules []Module) error {
+	for _, m := range modules {
+		if err := m.InitMetrics(); err != nil {
+			return fmt.Errorf("init %s metrics: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// RegisterModuleHealthChecks adds every module's checks to registry, prefixed
+// with the module name.
+func RegisterModuleHealthChecks(registry *observability.HealthChecker, modules []Module) {
+	for _, m := range modules {
+		for _, c := range m.HealthChecks() {
+			c.Name = m.Name() + "." + c.Name
+			registry.Register(c)
+		}
+	}
+}
+
+// ShutdownModules shuts modules down in reverse order, so a module can rely
+// on those listed before it until its own Shutdown returns. Every module is
+// shut down even if an earlier one fails.
+func ShutdownModules(ctx context.Context, modules []Module) error {
+	var errs []error
+	for i := len(modules) - 1; i >= 0; i-- {
+		if err := modules[i].Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown %s: %w", modules[i].Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"go-chi-observability/internal/observability"
+	"go-chi-observability/internal/testutil"
+)
+
+type fakeModule struct {
+	name        string
+	shutdownErr error
+	shutdowns   *[]string
+}
+
+func (m fakeModule) Name() string       { return m.name }
+func (m fakeModule) InitMetrics() error { return nil }
+
+func (m fakeModule) RegisterRoutes(r chi.Router) {
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(chi.RouteContext(r.Context()).RoutePattern()))
+	})
+}
+
+func (m fakeModule) HealthChecks() []observability.HealthCheck {
+	return []observability.HealthCheck{{Name: "upstream", Check: func(context.Context) error { return nil }}}
+}
+
+func (m fakeModule) Shutdown(context.Context) error {
+	*m.shutdowns = append(*m.shutdowns, m.name)
+	return m.shutdownErr
+}
+
+func TestNewRouterMountsModulesUnderTheirName(t *testing.T) {
+	setupRouterTests(t)
+
+	router := NewRouter(fakeModule{name: "widgets"})
+
+	req := httptest.NewRequest(http.MethodGet, "/widgets/ping", nil)
+	w := testutil.ExecuteRequest(req, router)
+
+	testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+	if got := w.Body.String(); got != "/widgets/ping" {
+		t.Fatalf("expected route pattern %q, got %q", "/widgets/ping", got)
+	}
+}
+
+func TestRegisterModuleHealthChecksPrefixesNames(t *testing.T) {
+	registry := observability.NewHealthChecker(0)
+	RegisterModuleHealthChecks(registry, []Module{fakeModule{name: "widgets"}})
+
+	report := registry.Readiness(context.Background())
+	if len(report.Checks) != 1 || report.Checks[0].Name != "widgets.upstream" {
+		t.Fatalf("unexpected checks %+v", report.Checks)
+	}
+}
+
+func TestShutdownModulesRunsInReverseAndJoinsErrors(t *testing.T) {
+	var order []string
+	modules := []Module{
+		fakeModule{name: "a", shutdowns: &order, shutdownErr: errors.New("boom")},
+		fakeModule{name: "b", shutdowns: &order},
+	}
+
+	err := ShutdownModules(context.Background(), modules)
+	if err == nil || err.Error() != "shutdown a: boom" {
+		t.Fatalf("expected joined shutdown error, got %v", err)
+	}
+	if len(order) != 2 || order[0] != "b" || order[1] != "a" {
+		t.Fatalf("expected reverse shutdown order, got %v", order)
+	}
+}
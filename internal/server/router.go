@@ -5,25 +5,37 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
-	"go-chi-observability/internal/calculator"
 	"go-chi-observability/internal/handlers"
 	"go-chi-observability/internal/observability"
 )
 
-func NewRouter() http.Handler {
+// NewRouter builds the API router, mounting each module under "/" + Name
+// behind the request middleware stack.
+func NewRouter(modules ...Module) http.Handler {
 
 	r := chi.NewRouter()
 
 	r.Handle("/metrics", observability.PrometheusHandler())
 	r.Get("/health", handlers.Health)
+	r.Handle("/livez", observability.HealthChecks.LivenessHandler())
+	r.Handle("/readyz", observability.HealthChecks.ReadinessHandler())
+	r.Handle("/health/telemetry", observability.TelemetryHealthHandler())
+
+	r.Group(func(r chi.Router) {
+		r.Use(observability.DebugAuthMiddleware)
+		r.Handle("/debug/traces", observability.TracesHandler())
+		r.Handle("/debug/logs", observability.LogsHandler())
+	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(observability.RequestIDMiddleware)
+		r.Use(observability.DebugLogMiddleware)
 		r.Use(observability.TracingMiddleware)
 		r.Use(observability.LoggingMiddleware)
 
-		// Domain route groups — add new RegisterRoutes calls here as the project grows
-		calculator.RegisterRoutes(r)
+		for _, m := range modules {
+			r.Route("/"+m.Name(), m.RegisterRoutes)
+		}
 	})
 
 	return r
@@ -3,17 +3,22 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"go-chi-observability/internal/calculator"
 	"go-chi-observability/internal/observability"
 	"go-chi-observability/internal/testutil"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -31,7 +36,7 @@ func setupRouterTests(t *testing.T) {
 }
 
 func TestNewRouterHealthEndpoint(t *testing.T) {
-	router := NewRouter()
+	router := NewRouter(calculator.Module{})
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	w := testutil.ExecuteRequest(req, router)
@@ -43,10 +48,29 @@ func TestNewRouterHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestNewRouterDebugEndpointsNeedToken(t *testing.T) {
+	t.Setenv("DEBUG_LOG_SECRET", "s3cret")
+	router := NewRouter(calculator.Module{})
+
+	for _, path := range []string{"/debug/traces", "/debug/logs"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusUnauthorized, w.Code)
+
+		token := observability.SignDebugToken([]byte("s3cret"), time.Now().Add(time.Minute))
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = testutil.ExecuteRequest(req, router)
+		if w.Code == http.StatusUnauthorized {
+			t.Fatalf("expected %s to accept a signed token", path)
+		}
+	}
+}
+
 func TestNewRouterCalculatorAddSetsHeaderAndOmitsRequestIDInBody(t *testing.T) {
 	setupRouterTests(t)
 
-	router := NewRouter()
+	router := NewRouter(calculator.Module{})
 	body := []byte(`{"a":2,"b":3}`)
 	req := httptest.NewRequest(http.MethodPost, "/calculator/add", bytes.NewReader(body))
 	w := testutil.ExecuteRequest(req, router)
@@ -77,7 +101,7 @@ func TestNewRouterCalculatorAddSetsHeaderAndOmitsRequestIDInBody(t *testing.T) {
 
 func TestNewRouterCalculatorBinaryOperations(t *testing.T) {
 	setupRouterTests(t)
-	router := NewRouter()
+	router := NewRouter(calculator.Module{})
 
 	tests := []struct {
 		name      string
@@ -90,6 +114,8 @@ func TestNewRouterCalculatorBinaryOperations(t *testing.T) {
 		{name: "subtract", path: "/calculator/subtract", body: `{"a":9,"b":3}`, operation: "subtract", result: 6},
 		{name: "multiply", path: "/calculator/multiply", body: `{"a":9,"b":3}`, operation: "multiply", result: 27},
 		{name: "divide", path: "/calculator/divide", body: `{"a":9,"b":3}`, operation: "divide", result: 3},
+		{name: "power", path: "/calculator/power", body: `{"a":2,"b":10}`, operation: "power", result: 1024},
+		{name: "modulo", path: "/calculator/modulo", body: `{"a":-7,"b":3}`, operation: "modulo", result: -1},
 	}
 
 	for _, tc := range tests {
@@ -122,7 +148,7 @@ func TestNewRouterCalculatorBinaryOperations(t *testing.T) {
 
 func TestNewRouterCalculatorDivideByZero(t *testing.T) {
 	setupRouterTests(t)
-	router := NewRouter()
+	router := NewRouter(calculator.Module{})
 
 	req := httptest.NewRequest(http.MethodPost, "/calculator/divide", strings.NewReader(`{"a":10,"b":0}`))
 	w := testutil.ExecuteRequest(req, router)
@@ -143,9 +169,39 @@ func TestNewRouterCalculatorDivideByZero(t *testing.T) {
 	}
 }
 
+func TestNewRouterCalculatorTelemetryContract(t *testing.T) {
+	setupRouterTests(t)
+	tel := testutil.NewTelemetry(t)
+	tel.UseLogger(&observability.Logger)
+	router := NewRouter(calculator.Module{})
+
+	req := httptest.NewRequest(http.MethodPost, "/calculator/multiply", strings.NewReader(`{"a":4,"b":5}`))
+	testutil.CheckResponseCode(t, http.StatusOK, testutil.ExecuteRequest(req, router).Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/calculator/divide", strings.NewReader(`{"a":1,"b":0}`))
+	testutil.CheckResponseCode(t, http.StatusBadRequest, testutil.ExecuteRequest(req, router).Code)
+
+	span := tel.AssertSpan("calculator.multiply", []attribute.KeyValue{
+		attribute.String("calculator.operation", "multiply"),
+		attribute.Float64("calculator.result", 20),
+	}, codes.Ok)
+	tel.AssertSpan("calculator.divide", nil, codes.Error)
+
+	tel.AssertCounter("calculator.operations.total", []attribute.KeyValue{attribute.String("operation", "multiply")}, 1)
+	tel.AssertCounter("calculator.errors.total", []attribute.KeyValue{attribute.String("operation", "divide")}, 1)
+
+	tel.AssertLog("calculator operation completed",
+		zap.String("operation", "multiply"),
+		zap.Float64("result", 20),
+		zap.String("trace_id", span.SpanContext().TraceID().String()),
+		zap.String("span_id", span.Parent().SpanID().String()),
+	)
+	tel.AssertLog("division by zero: 1 / 0", zap.String("operation", "divide"))
+}
+
 func TestNewRouterCalculatorChain(t *testing.T) {
 	setupRouterTests(t)
-	router := NewRouter()
+	router := NewRouter(calculator.Module{})
 
 	t.Run("success", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/calculator/chain", strings.NewReader(`{"initial":10,"steps":[{"op":"add","value":5},{"op":"multiply","value":2},{"op":"subtract","value":4}]}`))
@@ -183,3 +239,965 @@ func TestNewRouterCalculatorChain(t *testing.T) {
 		}
 	})
 }
+
+func TestNewRouterCalculatorOperations(t *testing.T) {
+	router := NewRouter(calculator.Module{})
+
+	req := httptest.NewRequest(http.MethodGet, "/calculator/operations", nil)
+	w := testutil.ExecuteRequest(req, router)
+
+	testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+	var resp calculator.OperationsResponse
+	testutil.DecodeJSONBody(t, w.Body, &resp)
+	if len(resp.Operations) != len(calculator.Operations()) {
+		t.Fatalf("expected %d operations, got %+v", len(calculator.Operations()), resp.Operations)
+	}
+	for _, op := range resp.Operations {
+		if op.Endpoint != "/calculator/"+op.Name {
+			t.Errorf("%s: unexpected endpoint %q", op.Name, op.Endpoint)
+		}
+		if op.Name == "power" && op.Operator != "^" {
+			t.Errorf("power: expected operator ^, got %q", op.Operator)
+		}
+	}
+}
+
+func TestNewRouterCalculatorChainUsesRegisteredOperations(t *testing.T) {
+	setupRouterTests(t)
+	tel := testutil.NewTelemetry(t)
+	router := NewRouter(calculator.Module{})
+
+	body := `{"initial":2,"steps":[{"op":"power","value":5},{"op":"modulo","value":10},{"op":"modulo","value":0}]}`
+	req := httptest.NewRequest(http.MethodPost, "/calculator/chain", strings.NewReader(body))
+	w := testutil.ExecuteRequest(req, router)
+
+	testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+	var payload map[string]string
+	testutil.DecodeJSONBody(t, w.Body, &payload)
+	if want := "division by zero: 2 / 0 at step 2"; payload["error"] != want {
+		t.Fatalf("expected error %q, got %q", want, payload["error"])
+	}
+	tel.AssertSpan("calculator.chain.step.0.power", []attribute.KeyValue{attribute.Float64("chain.step.result", 32)}, codes.Ok)
+	tel.AssertSpan("calculator.chain.step.2.modulo", nil, codes.Error)
+	tel.AssertCounter("calculator.operations.total", []attribute.KeyValue{attribute.String("operation", "modulo")}, 1)
+}
+
+func TestNewRouterCalculatorChainControlFlow(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	chain := func(t *testing.T, body string, code int) calculator.ChainResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/calculator/chain", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, code, w.Code)
+
+		var resp calculator.ChainResponse
+		if code == http.StatusOK {
+			testutil.DecodeJSONBody(t, w.Body, &resp)
+		}
+		return resp
+	}
+
+	t.Run("value_from and conditions", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		resp := chain(t, `{"initial":10,"steps":[
+			{"op":"multiply","value":3},
+			{"op":"add","value":5},
+			{"op":"multiply","value_from":0},
+			{"op":"subtract","value":1000,"if":"result > 10000"},
+			{"op":"divide","value":5,"if":"result > 100"}
+		]}`, http.StatusOK)
+
+		if resp.Status != calculator.ChainSucceeded || resp.Skipped != 1 || resp.Result != 210 {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+		if s := resp.Steps[2]; s.Value != 30 || s.Result != 1050 || s.Status != calculator.StepSucceeded {
+			t.Errorf("step 2: expected 35 * 30, got %+v", s)
+		}
+		if s := resp.Steps[3]; s.Status != calculator.StepSkipped || s.Result != 1050 {
+			t.Errorf("step 3: expected a skipped step, got %+v", s)
+		}
+
+		tel.AssertSpan("calculator.chain.step.2.multiply", []attribute.KeyValue{
+			attribute.Int("chain.step.value_from", 0),
+			attribute.Float64("chain.step.value", 30),
+		}, codes.Ok)
+		tel.AssertSpan("calculator.chain.step.3.subtract", []attribute.KeyValue{
+			attribute.String("chain.step.condition", "result > 10000"),
+			attribute.Bool("chain.step.condition_met", false),
+			attribute.Bool("chain.step.skipped", true),
+		}, codes.Unset)
+		tel.AssertSpan("calculator.chain.step.4.divide", []attribute.KeyValue{
+			attribute.Bool("chain.step.condition_met", true),
+		}, codes.Ok)
+		tel.AssertSpan("calculator.chain", []attribute.KeyValue{attribute.Int("chain.skipped_steps", 1)}, codes.Ok)
+	})
+
+	t.Run("continue mode", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		resp := chain(t, `{"initial":8,"mode":"continue","steps":[
+			{"op":"divide","value":0},
+			{"op":"sqrt"},
+			{"op":"add","value_from":0},
+			{"op":"nope"},
+			{"op":"add","value":1}
+		]}`, http.StatusOK)
+
+		if resp.Status != calculator.ChainPartial || resp.Failed != 3 || resp.Result != calculator.Number(math.Sqrt(8)+1) {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+		want := []calculator.StepStatus{"failed", "succeeded", "failed", "failed", "succeeded"}
+		for i, s := range resp.Steps {
+			if s.Status != want[i] {
+				t.Errorf("step %d: expected %s, got %+v", i, want[i], s)
+			}
+		}
+		if s := resp.Steps[0]; s.ErrorKind != "division_by_zero" || s.Result != 8 {
+			t.Errorf("step 0: expected a division by zero leaving 8, got %+v", s)
+		}
+		if s := resp.Steps[2]; !strings.Contains(s.Error, "value_from step 0, which failed") {
+			t.Errorf("step 2: unexpected %+v", s)
+		}
+
+		tel.AssertSpan("calculator.chain.step.0.divide", nil, codes.Error)
+		tel.AssertSpan("calculator.chain.step.1.sqrt", nil, codes.Ok)
+		tel.AssertSpan("calculator.chain.step.4.add", nil, codes.Ok)
+		tel.AssertSpan("calculator.chain", []attribute.KeyValue{attribute.Int("chain.failed_steps", 3)}, codes.Error)
+		tel.AssertCounter("calculator.errors.total", []attribute.KeyValue{attribute.String("operation", "divide")}, 1)
+	})
+
+	t.Run("value_from a skipped step", func(t *testing.T) {
+		resp := chain(t, `{"initial":2,"mode":"continue","steps":[
+			{"op":"multiply","value":3,"if":"result > 100"},
+			{"op":"add","value_from":0}
+		]}`, http.StatusOK)
+
+		if s := resp.Steps[1]; s.Status != calculator.StepFailed || !strings.Contains(s.Error, "value_from step 0, which was skipped") {
+			t.Errorf("step 1: expected a failed reference, got %+v", s)
+		}
+		if resp.Result != 2 {
+			t.Errorf("expected the total to stay 2, got %v", resp.Result)
+		}
+	})
+
+	t.Run("long chain", func(t *testing.T) {
+		resp := chain(t, `{"initial":0,"steps":[`+strings.Repeat(`{"op":"add","value":1},`, 1000)+`{"op":"add","value":1}]}`, http.StatusOK)
+		if resp.Result != 1001 {
+			t.Errorf("expected 1001, got %v", resp.Result)
+		}
+	})
+
+	t.Run("abort mode", func(t *testing.T) {
+		chain(t, `{"initial":8,"steps":[{"op":"divide","value":0},{"op":"add","value":1}]}`, http.StatusBadRequest)
+		chain(t, `{"initial":2,"steps":[{"op":"multiply","value":3,"if":"result > 100"},{"op":"add","value_from":0}]}`, http.StatusBadRequest)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, body := range []string{
+			`{"initial":1,"steps":[{"op":"add","value_from":0}]}`,
+			`{"initial":4,"steps":[{"op":"add","value":1},{"op":"sqrt","value_from":0}]}`,
+			`{"initial":1,"steps":[{"op":"add","value":1,"if":"result >> 1"}]}`,
+			`{"initial":1,"mode":"retry","steps":[{"op":"add","value":1}]}`,
+		} {
+			chain(t, body, http.StatusBadRequest)
+		}
+		for _, body := range []string{
+			`{"initial":"1","mode":"continue","steps":[{"op":"add","value":"1"}]}`,
+			`{"initial":"1","steps":[{"op":"add","value":"1","if":"result > 0"}]}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/calculator/chain?precision=decimal", strings.NewReader(body))
+			testutil.CheckResponseCode(t, http.StatusBadRequest, testutil.ExecuteRequest(req, router).Code)
+		}
+	})
+}
+
+func TestNewRouterCalculatorScientificOperations(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	tests := []struct {
+		path   string
+		body   string
+		result float64
+	}{
+		{"/calculator/sqrt", `{"a":81}`, 9},
+		{"/calculator/root", `{"a":-27,"b":3}`, -3},
+		{"/calculator/factorial", `{"a":5}`, 120},
+		{"/calculator/gcd", `{"a":24,"b":36}`, 12},
+		{"/calculator/log10", `{"a":0.01}`, -2},
+		{"/calculator/cos", `{"a":0}`, 1},
+		{"/calculator/sin", `{"a":90,"angle":"degrees"}`, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			w := testutil.ExecuteRequest(req, router)
+
+			testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+			var resp calculator.CalcResponse
+			testutil.DecodeJSONBody(t, w.Body, &resp)
+			if float64(resp.Result) != tc.result {
+				t.Fatalf("expected result %v, got %v", tc.result, resp.Result)
+			}
+		})
+	}
+
+	t.Run("unknown angle unit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/calculator/tan", strings.NewReader(`{"a":1,"angle":"turns"}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestNewRouterCalculatorDomainErrorTelemetry(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	t.Run("endpoint", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/sqrt", strings.NewReader(`{"a":-4}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+		var payload map[string]string
+		testutil.DecodeJSONBody(t, w.Body, &payload)
+		if want := "square root of negative number -4"; payload["error"] != want {
+			t.Fatalf("expected error %q, got %q", want, payload["error"])
+		}
+		tel.AssertSpan("calculator.sqrt", []attribute.KeyValue{attribute.String("calculator.error.kind", "out_of_domain")}, codes.Error)
+		tel.AssertCounter("calculator.errors.total", []attribute.KeyValue{attribute.String("operation", "sqrt")}, 1)
+	})
+
+	t.Run("chain step", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+		tel.UseLogger(&observability.Logger)
+
+		body := `{"initial":90,"angle":"degrees","steps":[{"op":"sin"},{"op":"subtract","value":2},{"op":"ln"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/chain", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+		tel.AssertSpan("calculator.chain.step.0.sin", []attribute.KeyValue{
+			attribute.String("calculator.angle_unit", "degrees"),
+			attribute.Float64("chain.step.result", 1),
+		}, codes.Ok)
+		tel.AssertSpan("calculator.chain.step.2.ln", []attribute.KeyValue{attribute.String("calculator.error.kind", "out_of_domain")}, codes.Error)
+		tel.AssertCounter("calculator.operations.total", []attribute.KeyValue{attribute.String("operation", "sin")}, 1)
+		tel.AssertCounter("calculator.errors.total", []attribute.KeyValue{attribute.String("operation", "ln")}, 1)
+		tel.AssertLog("chain step failed", zap.String("error_kind", "out_of_domain"))
+	})
+
+	t.Run("expression", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		body := `{"expression":"1 + factorial(x)","variables":{"x":2.5}}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/evaluate", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+		tel.AssertSpan("calculator.evaluate.factorial", []attribute.KeyValue{attribute.String("calculator.error.kind", "not_integer")}, codes.Error)
+		tel.AssertSpan("calculator.evaluate", []attribute.KeyValue{
+			attribute.String("calculator.error.kind", "not_integer"),
+			attribute.Int("calculator.expression.error_position", 5),
+		}, codes.Error)
+	})
+}
+
+func TestNewRouterCalculatorResultPolicy(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	t.Run("error by default", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/multiply", strings.NewReader(`{"a":1e308,"b":10}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+		var payload map[string]string
+		testutil.DecodeJSONBody(t, w.Body, &payload)
+		if want := "multiply result overflows float64 (+Inf)"; payload["error"] != want {
+			t.Fatalf("expected error %q, got %q", want, payload["error"])
+		}
+		span := tel.AssertSpan("calculator.multiply", []attribute.KeyValue{attribute.String("calculator.error.kind", "overflow")}, codes.Error)
+		if len(span.Events()) == 0 || span.Events()[0].Name != "anomaly.detected" {
+			t.Fatalf("expected an anomaly.detected event, got %+v", span.Events())
+		}
+		tel.AssertCounter("calculator.numeric_anomalies.total", []attribute.KeyValue{
+			attribute.String("operation", "multiply"),
+			attribute.String("anomaly", "overflow"),
+			attribute.String("policy", "error"),
+		}, 1)
+	})
+
+	t.Run("clamp", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/calculator/multiply", strings.NewReader(`{"a":-1e308,"b":10,"result_policy":"clamp"}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var resp calculator.CalcResponse
+		testutil.DecodeJSONBody(t, w.Body, &resp)
+		if float64(resp.Result) != -math.MaxFloat64 {
+			t.Fatalf("expected -MaxFloat64, got %v", resp.Result)
+		}
+	})
+
+	t.Run("string in a chain", func(t *testing.T) {
+		body := `{"initial":1e308,"result_policy":"string","steps":[{"op":"multiply","value":10},{"op":"subtract","value":1}]}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/chain", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var payload map[string]any
+		testutil.DecodeJSONBody(t, w.Body, &payload)
+		if payload["result"] != "+Inf" {
+			t.Fatalf(`expected result "+Inf", got %#v`, payload["result"])
+		}
+	})
+
+	t.Run("subnormal", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		body := `{"expression":"x / 1e10","variables":{"x":1e-300}}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/evaluate", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		span := tel.AssertSpan("calculator.evaluate.divide", nil, codes.Ok)
+		if len(span.Events()) != 1 || span.Events()[0].Name != "anomaly.detected" {
+			t.Fatalf("expected an anomaly.detected event, got %+v", span.Events())
+		}
+		tel.AssertCounter("calculator.numeric_anomalies.total", []attribute.KeyValue{
+			attribute.String("operation", "divide"),
+			attribute.String("anomaly", "subnormal"),
+			attribute.String("policy", "error"),
+		}, 1)
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/calculator/add", strings.NewReader(`{"a":1,"b":2,"result_policy":"wrap"}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestNewRouterCalculatorDecimalPrecision(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	t.Run("exact sum", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/add?precision=decimal", strings.NewReader(`{"a":"0.1","b":0.2}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var resp calculator.DecimalCalcResponse
+		testutil.DecodeJSONBody(t, w.Body, &resp)
+		if resp.Result != "0.3" || resp.A != "0.1" || resp.B != "0.2" || resp.Rounding != calculator.RoundHalfEven {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+		tel.AssertSpan("calculator.add", []attribute.KeyValue{
+			attribute.String("calculator.precision", "decimal"),
+			attribute.String("calculator.decimal.result", "0.3"),
+		}, codes.Ok)
+		tel.AssertCounter("calculator.operations.total", []attribute.KeyValue{attribute.String("operation", "add")}, 1)
+	})
+
+	t.Run("scale and rounding", func(t *testing.T) {
+		body := `{"a":"10","b":"3","precision":"decimal","scale":2,"rounding":"ceiling"}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/divide", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var resp calculator.DecimalCalcResponse
+		testutil.DecodeJSONBody(t, w.Body, &resp)
+		if resp.Result != "3.34" {
+			t.Fatalf("expected 3.34, got %q", resp.Result)
+		}
+	})
+
+	t.Run("chain rounds every step", func(t *testing.T) {
+		body := `{"initial":"100","precision":"decimal","scale":2,"rounding":"half-up","steps":[{"op":"divide","value":"3"},{"op":"multiply","value":"3"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/chain", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var resp calculator.DecimalChainResponse
+		testutil.DecodeJSONBody(t, w.Body, &resp)
+		if resp.Result != "99.99" || len(resp.Steps) != 2 || resp.Steps[0].Result != "33.33" {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	})
+
+	t.Run("transcendental operations", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		for _, tc := range []struct{ path, body, want string }{
+			{"/calculator/ln", `{"a":"2"}`, "0.6931471805599453094172321214581766"},
+			{"/calculator/exp", `{"a":"1","scale":5}`, "2.71828"},
+			{"/calculator/sin", `{"a":"30","angle":"degrees"}`, "0.5"},
+			{"/calculator/cos", `{"a":"1","scale":10}`, "0.5403023059"},
+		} {
+			req := httptest.NewRequest(http.MethodPost, tc.path+"?precision=decimal", strings.NewReader(tc.body))
+			w := testutil.ExecuteRequest(req, router)
+
+			testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+			var resp calculator.DecimalCalcResponse
+			testutil.DecodeJSONBody(t, w.Body, &resp)
+			if resp.Result != tc.want {
+				t.Errorf("%s: expected %s, got %s", tc.path, tc.want, resp.Result)
+			}
+		}
+		tel.AssertSpan("calculator.sin", []attribute.KeyValue{
+			attribute.String("calculator.angle_unit", "degrees"),
+			attribute.String("calculator.decimal.result", "0.5"),
+		}, codes.Ok)
+	})
+
+	t.Run("unsupported operands", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/power?precision=decimal", strings.NewReader(`{"a":"2","b":"0.5"}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+		tel.AssertSpan("calculator.power", []attribute.KeyValue{attribute.String("calculator.error.kind", "unsupported")}, codes.Error)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, body := range []string{
+			`{"a":"1","b":"2","precision":"exact"}`,
+			`{"a":"1","b":"2","precision":"decimal","rounding":"up"}`,
+			`{"a":"1","b":"2","precision":"decimal","scale":-1}`,
+			`{"a":"1/3","b":"2","precision":"decimal"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/calculator/add", strings.NewReader(body))
+			w := testutil.ExecuteRequest(req, router)
+
+			testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/calculator/sessions", nil)
+		w := testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusCreated, w.Code)
+		var sess calculator.SessionResponse
+		testutil.DecodeJSONBody(t, w.Body, &sess)
+
+		for _, tc := range []struct{ path, body string }{
+			{"/calculator/add", `{"a":"1","b":"2","session":"` + sess.ID + `","store":"x"}`},
+			{"/calculator/chain", `{"initial":"1","session":"` + sess.ID + `","steps":[{"op":"add","value":"1"}]}`},
+			{"/calculator/chain", `{"initial":"1","session":"` + sess.ID + `","steps":[{"op":"add","value":"1","store":"x"}]}`},
+		} {
+			req := httptest.NewRequest(http.MethodPost, tc.path+"?precision=decimal", strings.NewReader(tc.body))
+			w := testutil.ExecuteRequest(req, router)
+			testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+			if !strings.Contains(w.Body.String(), "sessions are not supported in decimal precision") {
+				t.Errorf("%s: unexpected body %s", tc.path, w.Body)
+			}
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/calculator/sessions/"+sess.ID, nil)
+		w = testutil.ExecuteRequest(req, router)
+		testutil.DecodeJSONBody(t, w.Body, &sess)
+		if len(sess.Registers) != 0 {
+			t.Errorf("expected no registers, got %v", sess.Registers)
+		}
+	})
+
+	t.Run("evaluate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/calculator/evaluate?precision=decimal", strings.NewReader(`{"expression":"1 + 2"}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestNewRouterCalculatorBatch(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	t.Run("mixed items", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		body := `{"items":[
+			{"op":"add","a":1,"b":2},
+			{"chain":{"initial":10,"steps":[{"op":"multiply","value":3},{"op":"sqrt"}]}},
+			{"op":"divide","a":1,"b":0},
+			{"op":"nope"},
+			{"op":"add","chain":{"initial":1,"steps":[{"op":"add","value":1}]}}
+		]}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/batch", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var resp calculator.BatchResponse
+		testutil.DecodeJSONBody(t, w.Body, &resp)
+		if resp.Succeeded != 2 || resp.Failed != 3 || len(resp.Results) != 5 {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+		for i, res := range resp.Results {
+			if res.Index != i {
+				t.Fatalf("result %d has index %d", i, res.Index)
+			}
+		}
+		if r := resp.Results[0]; r.Result == nil || *r.Result != 3 {
+			t.Errorf("item 0: expected 3, got %+v", r)
+		}
+		if r := resp.Results[1]; r.Result == nil || math.Abs(float64(*r.Result)-math.Sqrt(30)) > 1e-12 || len(r.Steps) != 2 {
+			t.Errorf("item 1: expected sqrt(30) after 2 steps, got %+v", r)
+		}
+		if r := resp.Results[2]; r.Error != "division by zero: 1 / 0" || r.ErrorKind != "division_by_zero" {
+			t.Errorf("item 2: unexpected %+v", r)
+		}
+		if r := resp.Results[3]; !strings.Contains(r.Error, `unknown operation "nope"`) {
+			t.Errorf("item 3: unexpected %+v", r)
+		}
+		if r := resp.Results[4]; !strings.Contains(r.Error, "exactly one of op and chain") {
+			t.Errorf("item 4: unexpected %+v", r)
+		}
+
+		batch := tel.AssertSpan("calculator.batch", []attribute.KeyValue{
+			attribute.Int("batch.size", 5),
+			attribute.Int("batch.failed", 3),
+		}, codes.Error)
+		item := tel.AssertSpan("calculator.batch.item", []attribute.KeyValue{attribute.Int("batch.item.index", 0)}, codes.Ok)
+		if item.Parent().SpanID() != batch.SpanContext().SpanID() {
+			t.Error("expected batch items to be children of the batch span")
+		}
+		if links := item.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != batch.SpanContext().SpanID() {
+			t.Errorf("expected a link to the batch span, got %+v", links)
+		}
+		tel.AssertSpan("calculator.chain.step.1.sqrt", nil, codes.Ok)
+
+		tel.AssertCounter("calculator.batch.items.total", []attribute.KeyValue{attribute.String("outcome", "succeeded")}, 2)
+		tel.AssertCounter("calculator.batch.items.total", []attribute.KeyValue{attribute.String("outcome", "failed")}, 3)
+		tel.AssertCounter("calculator.operations.total", []attribute.KeyValue{attribute.String("operation", "add")}, 1)
+		tel.AssertCounter("calculator.errors.total", []attribute.KeyValue{attribute.String("operation", "divide")}, 1)
+	})
+
+	t.Run("invalid batches", func(t *testing.T) {
+		tooMany := `{"items":[` + strings.Repeat(`{"op":"add"},`, 1000) + `{"op":"add"}]}`
+		for _, body := range []string{`{"items":[]}`, tooMany, `{"items":[{"op":"sin"}],"angle":"turns"}`} {
+			req := httptest.NewRequest(http.MethodPost, "/calculator/batch", strings.NewReader(body))
+			w := testutil.ExecuteRequest(req, router)
+
+			testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/batch", strings.NewReader(`{"items":[{"op":"add","a":1,"b":2}],"pad":"`+strings.Repeat("x", 1<<20)+`"}`))
+		w := testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("long chain item", func(t *testing.T) {
+		body := `{"items":[{"chain":{"initial":0,"steps":[` + strings.Repeat(`{"op":"add","value":1},`, 100) + `{"op":"add","value":1}]}}]}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/batch", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+		var resp calculator.BatchResponse
+		testutil.DecodeJSONBody(t, w.Body, &resp)
+		if r := resp.Results[0]; !strings.Contains(r.Error, "chain has 101 steps, the limit is 100") {
+			t.Errorf("expected the chain to be rejected, got %+v", r)
+		}
+	})
+}
+
+func TestNewRouterCalculatorJobs(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	t.Run("submit and poll", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		body := `{"initial":10,"steps":[{"op":"multiply","value":3},{"op":"sqrt"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/jobs", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusAccepted, w.Code)
+
+		var job calculator.JobResponse
+		testutil.DecodeJSONBody(t, w.Body, &job)
+		if job.ID == "" || job.Progress.TotalSteps != 2 {
+			t.Fatalf("unexpected submit response %+v", job)
+		}
+		if got := w.Header().Get("Location"); got != "/calculator/jobs/"+job.ID {
+			t.Errorf("unexpected Location %q", got)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for job.FinishedAt == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("job did not finish, last %+v", job)
+			}
+			time.Sleep(5 * time.Millisecond)
+
+			req := httptest.NewRequest(http.MethodGet, "/calculator/jobs/"+job.ID, nil)
+			w := testutil.ExecuteRequest(req, router)
+			testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+			job = calculator.JobResponse{}
+			testutil.DecodeJSONBody(t, w.Body, &job)
+		}
+		if job.Status != calculator.JobSucceeded || job.Result == nil || math.Abs(float64(job.Result.Result)-math.Sqrt(30)) > 1e-12 {
+			t.Fatalf("unexpected job %+v", job)
+		}
+		if job.Progress.CompletedSteps != 2 {
+			t.Errorf("expected 2 completed steps, got %+v", job.Progress)
+		}
+
+		submit := tel.AssertSpan("calculator.jobs.submit", []attribute.KeyValue{attribute.String("job.id", job.ID)}, codes.Ok)
+		span := tel.AssertSpan("calculator.job", []attribute.KeyValue{attribute.String("job.id", job.ID)}, codes.Ok)
+		if span.Parent().IsValid() || span.SpanContext().TraceID() == submit.SpanContext().TraceID() {
+			t.Error("expected the job to run in a trace of its own")
+		}
+		if links := span.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != submit.SpanContext().SpanID() {
+			t.Errorf("expected a link to the submitting span, got %+v", links)
+		}
+		tel.AssertSpan("calculator.chain.step.1.sqrt", nil, codes.Ok)
+
+		for _, status := range []string{"queued", "running", "succeeded"} {
+			tel.AssertCounter("calculator.jobs.total", []attribute.KeyValue{attribute.String("status", status)}, 1)
+		}
+	})
+
+	t.Run("unknown and finished jobs", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/calculator/jobs/"+uuid.NewString(), nil)
+		w := testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusNotFound, w.Code)
+
+		req = httptest.NewRequest(http.MethodDelete, "/calculator/jobs/"+uuid.NewString(), nil)
+		w = testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusNotFound, w.Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/calculator/jobs", strings.NewReader(`{"initial":1,"steps":[{"op":"add","value":1}]}`))
+		w = testutil.ExecuteRequest(req, router)
+		var job calculator.JobResponse
+		testutil.DecodeJSONBody(t, w.Body, &job)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			req = httptest.NewRequest(http.MethodDelete, "/calculator/jobs/"+job.ID, nil)
+			w = testutil.ExecuteRequest(req, router)
+			if w.Code == http.StatusConflict || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		testutil.CheckResponseCode(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("invalid jobs", func(t *testing.T) {
+		for _, body := range []string{`{"steps":[]}`, `{"initial":1,"steps":[{"op":"add","value":1}],"precision":"decimal"}`, `{`} {
+			req := httptest.NewRequest(http.MethodPost, "/calculator/jobs", strings.NewReader(body))
+			w := testutil.ExecuteRequest(req, router)
+
+			testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestNewRouterCalculatorHistory(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	listHistory := func(t *testing.T, query string) calculator.HistoryPage {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/calculator/history?"+query, nil)
+		w := testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var page calculator.HistoryPage
+		testutil.DecodeJSONBody(t, w.Body, &page)
+		return page
+	}
+
+	t.Run("records and lists calculations", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+		since := time.Now().UTC().Format(time.RFC3339Nano)
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/lcm", strings.NewReader(`{"a":4,"b":6}`))
+		w := testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+		requestID := w.Header().Get("X-Request-ID")
+		for _, body := range []string{`{"a":1,"b":2}`, `{"a":3,"b":4}`} {
+			req := httptest.NewRequest(http.MethodPost, "/calculator/add", strings.NewReader(body))
+			testutil.CheckResponseCode(t, http.StatusOK, testutil.ExecuteRequest(req, router).Code)
+		}
+		req = httptest.NewRequest(http.MethodPost, "/calculator/chain", strings.NewReader(`{"initial":2,"steps":[{"op":"add","value":1}]}`))
+		testutil.CheckResponseCode(t, http.StatusOK, testutil.ExecuteRequest(req, router).Code)
+
+		page := listHistory(t, "from="+since)
+		if len(page.Calculations) != 4 {
+			t.Fatalf("expected 4 calculations, got %+v", page)
+		}
+		if c := page.Calculations[0]; c.Operation != "chain" || c.Result != 3 || len(c.Operands) != 1 || c.Operands[0] != 2 {
+			t.Errorf("expected the chain first, got %+v", c)
+		}
+
+		lcm := page.Calculations[3]
+		if lcm.Operation != "lcm" || lcm.Result != 12 || lcm.RequestID != requestID {
+			t.Fatalf("unexpected calculation %+v", lcm)
+		}
+		span := tel.AssertSpan("calculator.lcm", nil, codes.Ok)
+		if lcm.TraceID != span.SpanContext().TraceID().String() {
+			t.Errorf("expected trace_id %s, got %s", span.SpanContext().TraceID(), lcm.TraceID)
+		}
+
+		insert := tel.AssertSpan("INSERT calculations", []attribute.KeyValue{
+			attribute.String("db.system", "memory"),
+			attribute.String("db.operation.name", "INSERT"),
+			attribute.String("db.collection.name", "calculations"),
+			attribute.String("calculator.history.id", lcm.ID),
+		}, codes.Ok)
+		if insert.SpanKind() != trace.SpanKindClient || insert.Parent().SpanID() != span.SpanContext().SpanID() {
+			t.Errorf("expected a client span below the operation span, got kind %v parent %v", insert.SpanKind(), insert.Parent().SpanID())
+		}
+		tel.AssertSpan("SELECT calculations", []attribute.KeyValue{
+			attribute.String("db.query.text", "SELECT * FROM calculations WHERE created_at >= ? ORDER BY seq DESC LIMIT ?"),
+			attribute.Int("calculator.history.rows", 4),
+		}, codes.Ok)
+
+		adds := listHistory(t, "operation=add&limit=1&from="+since)
+		if len(adds.Calculations) != 1 || adds.Calculations[0].Result != 7 || adds.NextCursor == "" {
+			t.Fatalf("unexpected first page %+v", adds)
+		}
+		adds = listHistory(t, "operation=add&limit=1&from="+since+"&cursor="+adds.NextCursor)
+		if len(adds.Calculations) != 1 || adds.Calculations[0].Result != 3 || adds.NextCursor != "" {
+			t.Fatalf("unexpected last page %+v", adds)
+		}
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=501", "from=yesterday", "from=2026-01-02T00:00:00Z&to=2026-01-01T00:00:00Z", "cursor=!"} {
+			req := httptest.NewRequest(http.MethodGet, "/calculator/history?"+query, nil)
+			w := testutil.ExecuteRequest(req, router)
+
+			testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestNewRouterCalculatorSessions(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	post := func(t *testing.T, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		return testutil.ExecuteRequest(req, router)
+	}
+
+	t.Run("registers across requests", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		w := post(t, "/calculator/sessions", `{"ttl_seconds":600}`)
+		testutil.CheckResponseCode(t, http.StatusCreated, w.Code)
+		var sess calculator.SessionResponse
+		testutil.DecodeJSONBody(t, w.Body, &sess)
+		if sess.ID == "" || sess.TTLSeconds != 600 || w.Header().Get("Location") != "/calculator/sessions/"+sess.ID {
+			t.Fatalf("unexpected session %+v", sess)
+		}
+		tel.AssertSpan("calculator.sessions.create", []attribute.KeyValue{attribute.String("calculator.session.id", sess.ID)}, codes.Ok)
+
+		w = post(t, "/calculator/multiply", `{"a":6,"b":7,"session":"`+sess.ID+`","store":"x"}`)
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+		tel.AssertSpan("calculator.multiply", []attribute.KeyValue{
+			attribute.String("calculator.session.id", sess.ID),
+			attribute.String("calculator.session.stored", "x"),
+		}, codes.Ok)
+
+		w = post(t, "/calculator/chain", `{"initial":"$x","session":"`+sess.ID+`","steps":[{"op":"add","value":"$x","store":"y"},{"op":"subtract","value":4}]}`)
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+		var chain calculator.ChainResponse
+		testutil.DecodeJSONBody(t, w.Body, &chain)
+		if chain.Initial != 42 || chain.Steps[0].Value != 42 || chain.Result != 80 {
+			t.Fatalf("unexpected chain %+v", chain)
+		}
+		tel.AssertSpan("calculator.chain", []attribute.KeyValue{
+			attribute.String("calculator.session.id", sess.ID),
+			attribute.String("chain.initial_register", "x"),
+		}, codes.Ok)
+		tel.AssertSpan("calculator.chain.step.0.add", []attribute.KeyValue{
+			attribute.String("chain.step.value_register", "x"),
+			attribute.Float64("chain.step.value", 42),
+			attribute.String("chain.step.stored", "y"),
+		}, codes.Ok)
+
+		w = post(t, "/calculator/evaluate", `{"expression":"$y / $x + z","variables":{"z":1},"session":"`+sess.ID+`","store":"ratio"}`)
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+		var eval calculator.EvaluateResponse
+		testutil.DecodeJSONBody(t, w.Body, &eval)
+		if eval.Result != 3 {
+			t.Fatalf("expected $y / $x + z = 3, got %+v", eval)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/calculator/sessions/"+sess.ID, nil)
+		w = testutil.ExecuteRequest(req, router)
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+		sess = calculator.SessionResponse{}
+		testutil.DecodeJSONBody(t, w.Body, &sess)
+		if len(sess.Registers) != 3 || sess.Registers["x"] != 42 || sess.Registers["y"] != 84 || sess.Registers["ratio"] != 3 {
+			t.Errorf("unexpected registers %+v", sess.Registers)
+		}
+
+		req = httptest.NewRequest(http.MethodDelete, "/calculator/sessions/"+sess.ID, nil)
+		testutil.CheckResponseCode(t, http.StatusNoContent, testutil.ExecuteRequest(req, router).Code)
+		req = httptest.NewRequest(http.MethodGet, "/calculator/sessions/"+sess.ID, nil)
+		testutil.CheckResponseCode(t, http.StatusNotFound, testutil.ExecuteRequest(req, router).Code)
+	})
+
+	t.Run("invalid references", func(t *testing.T) {
+		w := post(t, "/calculator/sessions", ``)
+		testutil.CheckResponseCode(t, http.StatusCreated, w.Code)
+		var sess calculator.SessionResponse
+		testutil.DecodeJSONBody(t, w.Body, &sess)
+
+		for _, tc := range []struct{ path, body string }{
+			{"/calculator/add", `{"a":"$x","b":1}`},
+			{"/calculator/add", `{"a":1,"b":1,"store":"x"}`},
+			{"/calculator/add", `{"a":"$unset","b":1,"session":"` + sess.ID + `"}`},
+			{"/calculator/add", `{"a":1,"b":1,"session":"` + uuid.NewString() + `"}`},
+			{"/calculator/add", `{"a":1,"b":1,"session":"` + sess.ID + `","store":"1x"}`},
+			{"/calculator/chain", `{"initial":1,"session":"` + sess.ID + `","steps":[{"op":"add","value":"$unset"}]}`},
+			{"/calculator/evaluate", `{"expression":"$x + 1"}`},
+			{"/calculator/sessions", `{"ttl_seconds":-1}`},
+		} {
+			w := post(t, tc.path, tc.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s %s: expected 400, got %d", tc.path, tc.body, w.Code)
+			}
+		}
+	})
+}
+
+func TestNewRouterCalculatorEvaluate(t *testing.T) {
+	setupRouterTests(t)
+	router := NewRouter(calculator.Module{})
+
+	t.Run("success with trace", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		body := `{"expression":"(10 + x) * 3 / 2 - max(1, 2)","variables":{"x":5},"trace":true}`
+		req := httptest.NewRequest(http.MethodPost, "/calculator/evaluate", strings.NewReader(body))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+		var resp calculator.EvaluateResponse
+		testutil.DecodeJSONBody(t, w.Body, &resp)
+		if resp.Result != 20.5 {
+			t.Fatalf("expected result 20.5, got %v", resp.Result)
+		}
+		if resp.Expression != "(((10 + x) * 3) / 2) - max(1, 2)" {
+			t.Fatalf("unexpected normalised expression %q", resp.Expression)
+		}
+		if len(resp.Trace) != 5 || resp.Trace[0].Expression != "10 + x" || resp.Trace[0].Result != 15 {
+			t.Fatalf("unexpected evaluation trace %+v", resp.Trace)
+		}
+
+		root := tel.AssertSpan("calculator.evaluate", []attribute.KeyValue{attribute.Float64("calculator.result", 20.5)}, codes.Ok)
+		sub := tel.AssertSpan("calculator.evaluate.subtract", nil, codes.Ok)
+		add := tel.AssertSpan("calculator.evaluate.add", []attribute.KeyValue{
+			attribute.String("calculator.expression.node", "10 + x"),
+			attribute.Int("calculator.expression.position", 5),
+		}, codes.Ok)
+		if sub.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Fatal("expected the outermost operator span to be a child of calculator.evaluate")
+		}
+		if add.Parent().SpanID() != tel.AssertSpan("calculator.evaluate.multiply", nil, codes.Ok).SpanContext().SpanID() {
+			t.Fatal("expected operator spans to nest like the expression")
+		}
+		tel.AssertCounter("calculator.operations.total", []attribute.KeyValue{attribute.String("operation", "max")}, 1)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/evaluate", strings.NewReader(`{"expression":"(1 + 2"}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+		var payload map[string]string
+		testutil.DecodeJSONBody(t, w.Body, &payload)
+		if want := `syntax error at position 7: expected ")" to close "(" at position 1, found end of expression`; payload["error"] != want {
+			t.Fatalf("expected error %q, got %q", want, payload["error"])
+		}
+		tel.AssertSpan("calculator.evaluate", []attribute.KeyValue{attribute.Int("calculator.expression.error_position", 7)}, codes.Error)
+		tel.AssertCounter("calculator.errors.total", []attribute.KeyValue{attribute.String("operation", "evaluate")}, 1)
+	})
+
+	t.Run("evaluation error", func(t *testing.T) {
+		tel := testutil.NewTelemetry(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/evaluate", strings.NewReader(`{"expression":"1 + 4 / (y - 2)","variables":{"y":2}}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+		var payload map[string]string
+		testutil.DecodeJSONBody(t, w.Body, &payload)
+		if want := "evaluation error at position 7: division by zero: 4 / 0"; payload["error"] != want {
+			t.Fatalf("expected error %q, got %q", want, payload["error"])
+		}
+		failed := tel.AssertSpan("calculator.evaluate.divide", nil, codes.Error)
+		if len(failed.Events()) == 0 {
+			t.Fatal("expected the failing node to record the error")
+		}
+		if parent := tel.AssertSpan("calculator.evaluate.add", nil, codes.Error); len(parent.Events()) != 0 {
+			t.Fatal("expected ancestors to be marked failed without re-recording the error")
+		}
+	})
+
+	t.Run("undefined variable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/calculator/evaluate", strings.NewReader(`{"expression":"2 * rate"}`))
+		w := testutil.ExecuteRequest(req, router)
+
+		testutil.CheckResponseCode(t, http.StatusBadRequest, w.Code)
+
+		var payload map[string]string
+		testutil.DecodeJSONBody(t, w.Body, &payload)
+		if want := `evaluation error at position 5: undefined variable "rate"`; payload["error"] != want {
+			t.Fatalf("expected error %q, got %q", want, payload["error"])
+		}
+	})
+}
+
+func TestNewRouterReadinessEndpoint(t *testing.T) {
+	router := NewRouter(calculator.Module{})
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := testutil.ExecuteRequest(req, router)
+
+	testutil.CheckResponseCode(t, http.StatusOK, w.Code)
+
+	var report observability.HealthReport
+	testutil.DecodeJSONBody(t, w.Body, &report)
+	if report.Status != observability.HealthStatusOK {
+		t.Fatalf("expected status %q, got %q", observability.HealthStatusOK, report.Status)
+	}
+}
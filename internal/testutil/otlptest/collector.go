@@ -0,0 +1,400 @@
+// Package otlptest runs an in-process OTLP/HTTP receiver for end-to-end
+// tests. Point OTEL_EXPORTER_OTLP_ENDPOINT at Collector.URL, exercise the
+// service, then query what arrived on the wire.
+package otlptest
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	collogpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Signal names one of the three OTLP export paths.
+type Signal string
+
+const (
+	Traces  Signal = "traces"
+	Metrics Signal = "metrics"
+	Logs    Signal = "logs"
+)
+
+// Behavior controls how the collector answers export requests for a signal.
+// The zero value accepts everything immediately.
+type Behavior struct {
+	// Delay is waited out before answering, or until the client gives up.
+	Delay time.Duration
+	// Status, if set to a non-2xx code, is returned and the request is
+	// not recorded.
+	Status int
+	// Rejected, if non-zero, is reported back as a partial success with
+	// Message. The request is still recorded.
+	Rejected int64
+	Message  string
+}
+
+// Span is a received span with the resource and scope it was exported under.
+type Span struct {
+	*tracepb.Span
+	Resource *resourcepb.Resource
+	Scope    string
+}
+
+// Metric is a received metric with the resource and scope it was exported
+// under.
+type Metric struct {
+	*metricspb.Metric
+	Resource *resourcepb.Resource
+	Scope    string
+}
+
+// LogRecord is a received log record with the resource and scope it was
+// exported under.
+type LogRecord struct {
+	*logspb.LogRecord
+	Resource *resourcepb.Resource
+	Scope    string
+}
+
+// Collector is a fake OTLP/HTTP collector accepting protobuf and JSON
+// payloads, optionally gzip-compressed.
+type Collector struct {
+	// URL is the base endpoint, e.g. http://127.0.0.1:40123, suitable for
+	// OTEL_EXPORTER_OTLP_ENDPOINT.
+	URL string
+
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	behaviors map[Signal]Behavior
+	requests  map[Signal]int
+	spans     []Span
+	metrics   []Metric
+	logs      []LogRecord
+}
+
+// NewCollector starts a collector on a random local port and stops it when
+// the test ends.
+func NewCollector(t testing.TB) *Collector {
+	t.Helper()
+
+	c := &Collector{
+		behaviors: make(map[Signal]Behavior),
+		requests:  make(map[Signal]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/traces", c.handle(Traces))
+	mux.HandleFunc("POST /v1/metrics", c.handle(Metrics))
+	mux.HandleFunc("POST /v1/logs", c.handle(Logs))
+
+	c.srv = httptest.NewServer(mux)
+	c.URL = c.srv.URL
+	t.Cleanup(c.srv.Close)
+	return c
+}
+
+// SetBehavior changes how subsequent requests for signal are answered.
+func (c *Collector) SetBehavior(signal Signal, b Behavior) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.behaviors[signal] = b
+}
+
+// Requests returns how many export requests for signal were received,
+// including failed ones.
+func (c *Collector) Requests(signal Signal) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[signal]
+}
+
+func (c *Collector) handle(signal Signal) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.requests[signal]++
+		b := c.behaviors[signal]
+		c.mu.Unlock()
+
+		if b.Delay > 0 {
+			select {
+			case <-time.After(b.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if b.Status != 0 && (b.Status < 200 || b.Status > 299) {
+			http.Error(w, http.StatusText(b.Status), b.Status)
+			return
+		}
+
+		body, err := readBody(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		isJSON := mediaType == "application/json"
+
+		var req, resp proto.Message
+		switch signal {
+		case Traces:
+			req = &coltracepb.ExportTraceServiceRequest{}
+			resp = &coltracepb.ExportTraceServiceResponse{}
+			if b.Rejected != 0 {
+				resp = &coltracepb.ExportTraceServiceResponse{PartialSuccess: &coltracepb.ExportTracePartialSuccess{
+					RejectedSpans: b.Rejected, ErrorMessage: b.Message,
+				}}
+			}
+		case Metrics:
+			req = &colmetricpb.ExportMetricsServiceRequest{}
+			resp = &colmetricpb.ExportMetricsServiceResponse{}
+			if b.Rejected != 0 {
+				resp = &colmetricpb.ExportMetricsServiceResponse{PartialSuccess: &colmetricpb.ExportMetricsPartialSuccess{
+					RejectedDataPoints: b.Rejected, ErrorMessage: b.Message,
+				}}
+			}
+		case Logs:
+			req = &collogpb.ExportLogsServiceRequest{}
+			resp = &collogpb.ExportLogsServiceResponse{}
+			if b.Rejected != 0 {
+				resp = &collogpb.ExportLogsServiceResponse{PartialSuccess: &collogpb.ExportLogsPartialSuccess{
+					RejectedLogRecords: b.Rejected, ErrorMessage: b.Message,
+				}}
+			}
+		}
+
+		if isJSON {
+			err = unmarshalJSON(body, req)
+		} else {
+			err = proto.Unmarshal(body, req)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("decoding %s request: %v", signal, err), http.StatusBadRequest)
+			return
+		}
+		c.record(req)
+
+		var out []byte
+		if isJSON {
+			out, err = protojson.Marshal(resp)
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			out, err = proto.Marshal(resp)
+			w.Header().Set("Content-Type", "application/x-protobuf")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(out)
+	}
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+	return io.ReadAll(body)
+}
+
+// record flattens an export request into the per-signal lists.
+func (c *Collector) record(req proto.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch req := req.(type) {
+	case *coltracepb.ExportTraceServiceRequest:
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					c.spans = append(c.spans, Span{Span: s, Resource: rs.Resource, Scope: ss.GetScope().GetName()})
+				}
+			}
+		}
+	case *colmetricpb.ExportMetricsServiceRequest:
+		for _, rm := range req.ResourceMetrics {
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					c.metrics = append(c.metrics, Metric{Metric: m, Resource: rm.Resource, Scope: sm.GetScope().GetName()})
+				}
+			}
+		}
+	case *collogpb.ExportLogsServiceRequest:
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				for _, l := range sl.LogRecords {
+					c.logs = append(c.logs, LogRecord{LogRecord: l, Resource: rl.Resource, Scope: sl.GetScope().GetName()})
+				}
+			}
+		}
+	}
+}
+
+// Spans returns every span received so far.
+func (c *Collector) Spans() []Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Span(nil), c.spans...)
+}
+
+// Metrics returns every metric received so far, one entry per export.
+func (c *Collector) Metrics() []Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Metric(nil), c.metrics...)
+}
+
+// LogRecords returns every log record received so far.
+func (c *Collector) LogRecords() []LogRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]LogRecord(nil), c.logs...)
+}
+
+// SpansNamed returns the received spans with the given name.
+func (c *Collector) SpansNamed(name string) []Span {
+	var out []Span
+	for _, s := range c.Spans() {
+		if s.Name == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// MetricsNamed returns the received exports of the metric with the given
+// name, oldest first.
+func (c *Collector) MetricsNamed(name string) []Metric {
+	var out []Metric
+	for _, m := range c.Metrics() {
+		if m.Name == name {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// LogsWithBody returns the received log records whose string body is body.
+func (c *Collector) LogsWithBody(body string) []LogRecord {
+	var out []LogRecord
+	for _, l := range c.LogRecords() {
+		if l.GetBody().GetStringValue() == body {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Eventually polls cond until it returns true, failing the test after
+// timeout. Exports are asynchronous, so use it before asserting on data
+// from a running service.
+func (c *Collector) Eventually(t testing.TB, timeout time.Duration, cond func(*Collector) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond(c) {
+		if time.Now().After(deadline) {
+			t.Fatalf("collector: condition not met after %s (spans=%d metrics=%d logs=%d)",
+				timeout, len(c.Spans()), len(c.Metrics()), len(c.LogRecords()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Attribute returns the string form of the attribute key in attrs, and
+// whether it was present.
+func Attribute(attrs []*commonpb.KeyValue, key string) (string, bool) {
+	for _, kv := range attrs {
+		if kv.Key != key {
+			continue
+		}
+		switch v := kv.Value.GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			return v.StringValue, true
+		case *commonpb.AnyValue_IntValue:
+			return fmt.Sprint(v.IntValue), true
+		case *commonpb.AnyValue_DoubleValue:
+			return fmt.Sprint(v.DoubleValue), true
+		case *commonpb.AnyValue_BoolValue:
+			return fmt.Sprint(v.BoolValue), true
+		default:
+			return kv.Value.String(), true
+		}
+	}
+	return "", false
+}
+
+// ResourceAttribute is Attribute for a resource, which may be nil.
+func ResourceAttribute(r *resourcepb.Resource, key string) (string, bool) {
+	return Attribute(r.GetAttributes(), key)
+}
+
+// unmarshalJSON decodes OTLP/JSON, which differs from protojson in encoding
+// trace and span IDs as hex rather than base64.
+func unmarshalJSON(body []byte, m proto.Message) error {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	if err := hexIDsToBase64(doc); err != nil {
+		return err
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, m)
+}
+
+var idFields = map[string]bool{"traceId": true, "spanId": true, "parentSpanId": true}
+
+func hexIDsToBase64(v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if s, ok := field.(string); ok && idFields[k] {
+				b, err := hex.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("%s: %w", k, err)
+				}
+				v[k] = base64.StdEncoding.EncodeToString(b)
+				continue
+			}
+			if err := hexIDsToBase64(field); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := hexIDsToBase64(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
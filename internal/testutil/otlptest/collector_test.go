@@ -0,0 +1,142 @@
+package otlptest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go-chi-observability/internal/testutil"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func exportSpan(t *testing.T, c *Collector, name string) error {
+	t.Helper()
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(c.URL),
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		otlptracehttp.WithTimeout(200*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("creating exporter: %v", err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "otlptest"))),
+	)
+	_, span := provider.Tracer("otlptest").Start(context.Background(), name)
+	span.End()
+	return exporter.ExportSpans(context.Background(), recorder.Ended())
+}
+
+func TestCollectorReceivesProtobufSpans(t *testing.T) {
+	c := NewCollector(t)
+
+	if err := exportSpan(t, c, "otlptest.op"); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	spans := c.SpansNamed("otlptest.op")
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if got, _ := ResourceAttribute(spans[0].Resource, "service.name"); got != "otlptest" {
+		t.Fatalf("expected service.name otlptest, got %q", got)
+	}
+	if spans[0].Scope != "otlptest" || len(spans[0].TraceId) != 16 {
+		t.Fatalf("unexpected span %+v", spans[0])
+	}
+}
+
+func TestCollectorDecodesJSONWithHexIDs(t *testing.T) {
+	c := NewCollector(t)
+
+	body := `{"resourceSpans":[{"scopeSpans":[{"spans":[{
+		"traceId":"5b8efff798038103d269b633813fc60c",
+		"spanId":"eee19b7ec3c1b174",
+		"name":"json.op",
+		"attributes":[{"key":"http.status_code","value":{"intValue":"200"}}]
+	}]}]}]}`
+	resp, err := http.Post(c.URL+"/v1/traces", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	testutil.CheckResponseCode(t, http.StatusOK, resp.StatusCode)
+
+	spans := c.SpansNamed("json.op")
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if got := spans[0].TraceId; got[0] != 0x5b || got[15] != 0x0c {
+		t.Fatalf("trace ID not decoded from hex: %x", got)
+	}
+	if got, _ := Attribute(spans[0].Attributes, "http.status_code"); got != "200" {
+		t.Fatalf("expected status attribute 200, got %q", got)
+	}
+}
+
+func TestCollectorSimulatesServerErrors(t *testing.T) {
+	c := NewCollector(t)
+	c.SetBehavior(Traces, Behavior{Status: http.StatusServiceUnavailable})
+
+	if err := exportSpan(t, c, "otlptest.op"); err == nil {
+		t.Fatal("expected export to fail")
+	}
+	if c.Requests(Traces) != 1 || len(c.Spans()) != 0 {
+		t.Fatalf("expected 1 rejected request, got requests=%d spans=%d", c.Requests(Traces), len(c.Spans()))
+	}
+
+	c.SetBehavior(Traces, Behavior{})
+	if err := exportSpan(t, c, "otlptest.op"); err != nil {
+		t.Fatalf("export failed after recovery: %v", err)
+	}
+}
+
+func TestCollectorSimulatesSlowResponses(t *testing.T) {
+	c := NewCollector(t)
+	c.SetBehavior(Traces, Behavior{Delay: time.Second})
+
+	start := time.Now()
+	if err := exportSpan(t, c, "otlptest.op"); err == nil {
+		t.Fatal("expected export to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Fatalf("exporter did not give up on its own timeout (%s)", elapsed)
+	}
+}
+
+func TestCollectorReportsPartialSuccess(t *testing.T) {
+	c := NewCollector(t)
+	c.SetBehavior(Traces, Behavior{Rejected: 3, Message: "quota exceeded"})
+
+	body := []byte(`{"resourceSpans":[]}`)
+	resp, err := http.Post(c.URL+"/v1/traces", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.CheckResponseCode(t, http.StatusOK, resp.StatusCode)
+
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	var out coltracepb.ExportTraceServiceResponse
+	if err := protojson.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if ps := out.GetPartialSuccess(); ps.GetRejectedSpans() != 3 || ps.GetErrorMessage() != "quota exceeded" {
+		t.Fatalf("unexpected partial success %+v", ps)
+	}
+}
@@ -0,0 +1,248 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// The OTel globals hand out delegating tracers and meters that bind to the
+// first provider installed and never rebind, and domains create theirs at
+// package init or in a one-off InitMetrics. So the providers are installed
+// once per test binary; NewTelemetry swaps in a fresh span recorder and takes
+// a metric baseline instead of replacing them.
+var (
+	installOnce   sync.Once
+	spanSink      swappableProcessor
+	metricsReader *sdkmetric.ManualReader
+)
+
+func installProviders() {
+	installOnce.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(
+			sdktrace.WithSampler(sdktrace.AlwaysSample()),
+			sdktrace.WithSpanProcessor(&spanSink),
+		))
+
+		metricsReader = sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metricsReader)))
+	})
+}
+
+// swappableProcessor forwards to the current test's recorder, if any.
+type swappableProcessor struct {
+	current atomic.Pointer[tracetest.SpanRecorder]
+}
+
+func (p *swappableProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	if r := p.current.Load(); r != nil {
+		r.OnStart(ctx, s)
+	}
+}
+
+func (p *swappableProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if r := p.current.Load(); r != nil {
+		r.OnEnd(s)
+	}
+}
+
+func (p *swappableProcessor) Shutdown(context.Context) error   { return nil }
+func (p *swappableProcessor) ForceFlush(context.Context) error { return nil }
+
+// Telemetry captures the spans, metrics and logs a test produces through the
+// global OTel providers and an observed zap logger.
+//
+// Tests using it share process-wide state and must not call t.Parallel.
+type Telemetry struct {
+	t        testing.TB
+	spans    *tracetest.SpanRecorder
+	logs     *observer.ObservedLogs
+	baseline map[string]map[attribute.Distinct]float64
+
+	// Logger records every entry at debug level and above; see AssertLog.
+	Logger *zap.Logger
+}
+
+// NewTelemetry starts capturing telemetry for the rest of the test. Counter
+// values are measured from this point on, so earlier tests do not leak in.
+func NewTelemetry(t testing.TB) *Telemetry {
+	t.Helper()
+	installProviders()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	tel := &Telemetry{
+		t:      t,
+		spans:  tracetest.NewSpanRecorder(),
+		logs:   logs,
+		Logger: zap.New(core),
+	}
+	tel.baseline = tel.collectSums()
+
+	spanSink.current.Store(tel.spans)
+	t.Cleanup(func() { spanSink.current.CompareAndSwap(tel.spans, nil) })
+	return tel
+}
+
+// UseLogger points *logger (typically &observability.Logger) at tel.Logger
+// until the test ends.
+func (tel *Telemetry) UseLogger(logger **zap.Logger) {
+	old := *logger
+	*logger = tel.Logger
+	tel.t.Cleanup(func() { *logger = old })
+}
+
+// Spans returns the spans ended so far.
+func (tel *Telemetry) Spans() []sdktrace.ReadOnlySpan {
+	return tel.spans.Ended()
+}
+
+// Logs returns the observed log entries.
+func (tel *Telemetry) Logs() *observer.ObservedLogs {
+	return tel.logs
+}
+
+// AssertSpan fails the test unless an ended span has the given name and
+// status and carries at least the given attributes. It returns that span.
+func (tel *Telemetry) AssertSpan(name string, attrs []attribute.KeyValue, status codes.Code) sdktrace.ReadOnlySpan {
+	tel.t.Helper()
+
+	var seen []string
+	for _, s := range tel.spans.Ended() {
+		if s.Name() != name {
+			continue
+		}
+		if s.Status().Code == status && hasAttributes(s.Attributes(), attrs) {
+			return s
+		}
+		seen = append(seen, fmt.Sprintf("status=%s {%s}", s.Status().Code, formatAttrs(s.Attributes())))
+	}
+	if len(seen) == 0 {
+		tel.t.Fatalf("no span named %q; ended spans: %s", name, spanNames(tel.spans.Ended()))
+	}
+	tel.t.Fatalf("span %q: want status=%s with {%s}, got:\n  %s", name, status, formatAttrs(attrs), strings.Join(seen, "\n  "))
+	return nil
+}
+
+// AssertCounter fails the test unless the counter (or any other sum) named
+// name has grown by want since NewTelemetry for exactly the given attributes.
+func (tel *Telemetry) AssertCounter(name string, attrs []attribute.KeyValue, want float64) {
+	tel.t.Helper()
+
+	set := attribute.NewSet(attrs...)
+	key := set.Equivalent()
+	got := tel.collectSums()[name][key] - tel.baseline[name][key]
+	if got != want {
+		tel.t.Fatalf("counter %s{%s}: want %g, got %g", name, formatAttrs(attrs), want, got)
+	}
+}
+
+// AssertLog fails the test unless an entry with the given message carries
+// the given fields. It returns that entry.
+func (tel *Telemetry) AssertLog(msg string, fields ...zap.Field) observer.LoggedEntry {
+	tel.t.Helper()
+
+	want := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(want)
+	}
+
+	entries := tel.logs.FilterMessage(msg).All()
+	for _, e := range entries {
+		if hasFields(e.ContextMap(), want.Fields) {
+			return e
+		}
+	}
+	if len(entries) == 0 {
+		tel.t.Fatalf("no log entry %q; logged: %s", msg, logMessages(tel.logs.All()))
+	}
+	tel.t.Fatalf("log entry %q: want fields %v, got %v", msg, want.Fields, entries[0].ContextMap())
+	return observer.LoggedEntry{}
+}
+
+// collectSums reads every int64 and float64 sum, keyed by metric name and
+// attribute set.
+func (tel *Telemetry) collectSums() map[string]map[attribute.Distinct]float64 {
+	tel.t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := metricsReader.Collect(context.Background(), &rm); err != nil {
+		tel.t.Fatalf("collecting metrics: %v", err)
+	}
+
+	out := make(map[string]map[attribute.Distinct]float64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points := out[m.Name]
+			if points == nil {
+				points = make(map[attribute.Distinct]float64)
+				out[m.Name] = points
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					points[dp.Attributes.Equivalent()] += float64(dp.Value)
+				}
+			case metricdata.Sum[float64]:
+				for _, dp := range data.DataPoints {
+					points[dp.Attributes.Equivalent()] += dp.Value
+				}
+			}
+		}
+	}
+	return out
+}
+
+func hasAttributes(got, want []attribute.KeyValue) bool {
+	set := attribute.NewSet(got...)
+	for _, kv := range want {
+		if v, ok := set.Value(kv.Key); !ok || v != kv.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func formatAttrs(attrs []attribute.KeyValue) string {
+	set := attribute.NewSet(attrs...)
+	return set.Encoded(attribute.DefaultEncoder())
+}
+
+func hasFields(got, want map[string]any) bool {
+	for k, v := range want {
+		if !reflect.DeepEqual(got[k], v) {
+			return false
+		}
+	}
+	return true
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name()
+	}
+	return fmt.Sprint(names)
+}
+
+func logMessages(entries []observer.LoggedEntry) string {
+	msgs := make([]string, len(entries))
+	for i, e := range entries {
+		msgs[i] = e.Message
+	}
+	return fmt.Sprint(msgs)
+}